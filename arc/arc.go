@@ -0,0 +1,282 @@
+// Package arc implements a thread safe Adaptive Replacement Cache
+package arc
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// ARC balances between a recency list t1 and a frequency list t2, keeping
+// ghost lists b1 and b2 of keys recently evicted from each. A hit in a ghost
+// list moves the target size p of t1 towards the list that would have hit
+type ARC struct {
+	lock sync.Mutex
+
+	size int
+	p    int
+
+	// the lists have no size limit and no callback, ARC bounds them itself
+	t1 *simplelru.LRU
+	t2 *simplelru.LRU
+	b1 *simplelru.LRU
+	b2 *simplelru.LRU
+
+	onEvicted simplelru.EvictCallback
+}
+
+// ghost is stored as the value of keys in the ghost lists
+var ghost = struct{}{}
+
+func New(size int, onEvict simplelru.EvictCallback) (*ARC, error) {
+	if size <= 0 {
+		return nil, errors.New("arc: size must be positive")
+	}
+	if onEvict == nil {
+		onEvict = func(k, v interface{}) {}
+	}
+
+	c := &ARC{
+		size:      size,
+		onEvicted: onEvict,
+	}
+	c.t1, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.t2, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.b1, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.b2, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+
+	return c, nil
+}
+
+func (c *ARC) Set(k, v interface{}) {
+	if k == nil || v == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// seen once before, it is now frequent
+	if c.t1.Contains(k) {
+		c.t1.Remove(k)
+		c.t2.Set(k, v)
+		return
+	}
+
+	if c.t2.Contains(k) {
+		c.t2.Set(k, v)
+		return
+	}
+
+	// recently evicted from t1, t1 should have been larger
+	if c.b1.Contains(k) {
+		delta := 1
+		if b1, b2 := c.b1.Len(), c.b2.Len(); b2 > b1 {
+			delta = b2 / b1
+		}
+		c.p = min(c.p+delta, c.size)
+
+		if c.t1.Len()+c.t2.Len() >= c.size {
+			c.replace(false)
+		}
+		c.b1.Remove(k)
+		c.t2.Set(k, v)
+		return
+	}
+
+	// recently evicted from t2, t2 should have been larger
+	if c.b2.Contains(k) {
+		delta := 1
+		if b1, b2 := c.b1.Len(), c.b2.Len(); b1 > b2 {
+			delta = b1 / b2
+		}
+		c.p = max(c.p-delta, 0)
+
+		if c.t1.Len()+c.t2.Len() >= c.size {
+			c.replace(true)
+		}
+		c.b2.Remove(k)
+		c.t2.Set(k, v)
+		return
+	}
+
+	if c.t1.Len()+c.t2.Len() >= c.size {
+		c.replace(false)
+	}
+
+	if c.b1.Len() > c.size-c.p {
+		c.b1.RemoveOldest()
+	}
+	if c.b2.Len() > c.p {
+		c.b2.RemoveOldest()
+	}
+
+	c.t1.Set(k, v)
+}
+
+// replace evicts from t1 or t2 depending on p, remembering the key in the
+// matching ghost list
+func (c *ARC) replace(b2ContainsKey bool) {
+	if t1 := c.t1.Len(); t1 > 0 && (t1 > c.p || (t1 == c.p && b2ContainsKey)) {
+		if k, v, ok := c.t1.RemoveOldest(); ok {
+			c.b1.Set(k, ghost)
+			c.onEvicted(k, v)
+		}
+		return
+	}
+
+	if k, v, ok := c.t2.RemoveOldest(); ok {
+		c.b2.Set(k, ghost)
+		c.onEvicted(k, v)
+	}
+}
+
+func (c *ARC) Get(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if v, ok := c.t1.Peek(k); ok {
+		c.t1.Remove(k)
+		c.t2.Set(k, v)
+		return v, true
+	}
+
+	return c.t2.Get(k)
+}
+
+func (c *ARC) Contains(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.t1.Contains(k) || c.t2.Contains(k)
+}
+
+// Peek get a cache without updating its recency or frequency
+func (c *ARC) Peek(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if v, ok := c.t1.Peek(k); ok {
+		return v, true
+	}
+	return c.t2.Peek(k)
+}
+
+func (c *ARC) Remove(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.b1.Remove(k)
+	c.b2.Remove(k)
+
+	for _, l := range []*simplelru.LRU{c.t1, c.t2} {
+		if v, ok := l.Peek(k); ok {
+			l.Remove(k)
+			c.onEvicted(k, v)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOldest removes the oldest entry of t1, or of t2 if t1 is empty
+func (c *ARC) RemoveOldest() (k, v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.removeOldest()
+}
+
+func (c *ARC) removeOldest() (k, v interface{}, ok bool) {
+	l := c.t1
+	if l.Len() == 0 {
+		l = c.t2
+	}
+
+	if k, v, ok = l.RemoveOldest(); ok {
+		c.onEvicted(k, v)
+	}
+	return
+}
+
+// RemoveOldestN removes up to n entries in RemoveOldest order
+func (c *ARC) RemoveOldestN(n int) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	removed := 0
+	for ; removed < n; removed++ {
+		if _, _, ok := c.removeOldest(); !ok {
+			break
+		}
+	}
+	return removed
+}
+
+func (c *ARC) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.t1.Len() + c.t2.Len()
+}
+
+// IsFull reports whether the cache holds as many entries as its size allows
+func (c *ARC) IsFull() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.t1.Len()+c.t2.Len() >= c.size
+}
+
+// Cap returns the size limit of the cache
+func (c *ARC) Cap() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.size
+}
+
+// Keys returns the keys of t1 then of t2, each from oldest to newest
+func (c *ARC) Keys() []interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.t2.AppendKeys(c.t1.Keys())
+}
+
+func (c *ARC) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, l := range []*simplelru.LRU{c.t1, c.t2} {
+		for _, k := range l.Keys() {
+			v, _ := l.Peek(k)
+			c.onEvicted(k, v)
+		}
+		l.Purge()
+	}
+	c.b1.Purge()
+	c.b2.Purge()
+	c.p = 0
+}
+
+// Resize changes the size limit, evicting entries as needed, it returns how
+// many were evicted. ARC needs a positive size, other values are ignored
+func (c *ARC) Resize(size int) int {
+	if size <= 0 {
+		return 0
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.size = size
+	c.p = min(c.p, size)
+
+	evicted := 0
+	for c.t1.Len()+c.t2.Len() > size {
+		c.replace(false)
+		evicted++
+	}
+	c.b1.RemoveOldestN(c.b1.Len() - size)
+	c.b2.RemoveOldestN(c.b2.Len() - size)
+
+	return evicted
+}
+
+var _ simplelru.LRUCache = (*ARC)(nil)
@@ -0,0 +1,248 @@
+package lrucache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// AsyncCache is a cache with lock-free reads: Set and Remove are queued to a
+// single goroutine owning the entries, which applies them in order and then
+// publishes an immutable snapshot that Get reads.
+//
+// A write is thus seen by Get only once the owner applied it and published
+// the next snapshot, and not by the goroutine that made it when Set returns.
+// The owner applies every write queued meanwhile before publishing, so the
+// window is the time to apply them plus the time to copy the live entries,
+// usually well under a millisecond for small caches, and it grows with the
+// queue and the cache size: each batch of writes copies every entry, so a
+// write costs O(n) and AsyncCache suits read heavy caches of moderate size.
+// Sync waits for the window to close. Writes that do not fit in the queue
+// are dropped, and since Get leaves recency alone the entries are evicted in
+// the order they were last written
+type AsyncCache struct {
+	c *Cache
+
+	ops     chan asyncOp
+	snap    atomic.Pointer[asyncSnapshot]
+	dropped atomic.Uint64
+
+	// sending is held for reading while an op is queued and for writing
+	// while stop is closed, so no op is queued once the owner drained ops
+	sending   sync.RWMutex
+	stopped   bool
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+type asyncOp struct {
+	k, v   interface{}
+	ttl    time.Duration
+	remove bool
+
+	// synced is closed once the ops before it are published, see Sync
+	synced chan struct{}
+}
+
+type asyncEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+type asyncSnapshot map[interface{}]asyncEntry
+
+// NewAsync creates an AsyncCache queueing up to queue writes, its entries
+// are kept by a Cache configured by opts
+func NewAsync(queue int, opts ...Option) (*AsyncCache, error) {
+	c, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if queue <= 0 {
+		queue = 1
+	}
+
+	a := &AsyncCache{
+		c:    c,
+		ops:  make(chan asyncOp, queue),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	a.snap.Store(&asyncSnapshot{})
+	go a.run()
+	return a, nil
+}
+
+// Set queues a write of k, it returns false if the queue is full or the
+// cache closed and the write was dropped
+func (a *AsyncCache) Set(k, v interface{}) bool {
+	return a.send(asyncOp{k: k, v: v, ttl: simplelru.NoLimitTTL})
+}
+
+// SetWithTTL works like Set with a ttl for this entry
+func (a *AsyncCache) SetWithTTL(k, v interface{}, ttl time.Duration) bool {
+	return a.send(asyncOp{k: k, v: v, ttl: ttl})
+}
+
+// Remove queues the removal of k, it returns false if it was dropped
+func (a *AsyncCache) Remove(k interface{}) bool {
+	return a.send(asyncOp{k: k, remove: true})
+}
+
+// Get returns the value of k in the latest snapshot, it never blocks
+func (a *AsyncCache) Get(k interface{}) (interface{}, bool) {
+	e, ok := (*a.snap.Load())[k]
+	if !ok || !e.expires.IsZero() && !a.c.clock.Now().Before(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Len returns the number of entries in the latest snapshot, expired ones
+// included
+func (a *AsyncCache) Len() int {
+	return len(*a.snap.Load())
+}
+
+// Dropped returns how many writes did not fit in the queue
+func (a *AsyncCache) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Sync waits until the writes queued before it can be read by Get, or ctx
+// is done. It returns ErrClosed once Close was called
+func (a *AsyncCache) Sync(ctx context.Context) error {
+	synced := make(chan struct{})
+	a.sending.RLock()
+	if a.stopped {
+		a.sending.RUnlock()
+		return ErrClosed
+	}
+	// the owner keeps reading ops until stop is closed, which waits for us
+	select {
+	case a.ops <- asyncOp{synced: synced}:
+	case <-ctx.Done():
+		a.sending.RUnlock()
+		return ctx.Err()
+	}
+	a.sending.RUnlock()
+
+	select {
+	case <-synced:
+		return nil
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close applies the queued writes, stops the owning goroutine and closes the
+// underlying Cache, see Cache.Close. Later writes are dropped, Get keeps
+// reading the last snapshot
+func (a *AsyncCache) Close(ctx context.Context) error {
+	a.closeOnce.Do(func() {
+		a.sending.Lock()
+		a.stopped = true
+		close(a.stop)
+		a.sending.Unlock()
+		select {
+		case <-a.done:
+			a.closeErr = a.c.Close(ctx)
+		case <-ctx.Done():
+			a.closeErr = ctx.Err()
+		}
+	})
+	return a.closeErr
+}
+
+func (a *AsyncCache) send(op asyncOp) bool {
+	a.sending.RLock()
+	defer a.sending.RUnlock()
+
+	if a.stopped {
+		return false
+	}
+	select {
+	case a.ops <- op:
+		return true
+	default:
+		a.dropped.Add(1)
+		return false
+	}
+}
+
+func (a *AsyncCache) run() {
+	defer close(a.done)
+
+	for {
+		select {
+		case op := <-a.ops:
+			a.apply(op)
+		case <-a.stop:
+			a.applyQueued()
+			return
+		}
+	}
+}
+
+// apply applies op and the ops queued after it, then publishes them
+func (a *AsyncCache) apply(op asyncOp) {
+	var synced []chan struct{}
+loop:
+	for {
+		switch {
+		case op.synced != nil:
+			synced = append(synced, op.synced)
+		case op.remove:
+			a.c.Remove(op.k)
+		default:
+			a.c.SetWithTTL(op.k, op.v, op.ttl)
+		}
+
+		select {
+		case op = <-a.ops:
+		default:
+			break loop
+		}
+	}
+
+	a.publish()
+	for _, ch := range synced {
+		close(ch)
+	}
+}
+
+// applyQueued applies the ops left once stop is closed, none can be queued
+// after that
+func (a *AsyncCache) applyQueued() {
+	select {
+	case op := <-a.ops:
+		a.apply(op)
+	default:
+	}
+}
+
+// publish copies the live entries into a new snapshot, it is O(n) in the
+// cache size
+func (a *AsyncCache) publish() {
+	c := a.c
+	c.lock.RLock()
+	now := c.clock.Now()
+	snap := make(asyncSnapshot, c.lru.Len())
+	c.lru.Range(func(k, v interface{}) bool {
+		e := asyncEntry{value: v}
+		if _, ttl, ok := c.lru.PeekWithTTL(k); ok && ttl != simplelru.NoLimitTTL {
+			e.expires = now.Add(ttl)
+		}
+		snap[k] = e
+		return true
+	})
+	c.lock.RUnlock()
+	a.snap.Store(&snap)
+}
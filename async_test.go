@@ -0,0 +1,146 @@
+package lrucache
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestAsyncCacheClose(t *testing.T) {
+	a, err := NewAsync(1024, WithSize(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// writers racing with Close either see their write applied or are told
+	// it was dropped, never both
+	var wg sync.WaitGroup
+	accepted := make([]bool, 512)
+	for i := range accepted {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			accepted[i] = a.Set(i, i)
+		}(i)
+	}
+	if err := a.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	for i, ok := range accepted {
+		if _, got := a.Get(i); got != ok {
+			t.Errorf("Set(%d) = %v, but Get found it %v", i, ok, got)
+		}
+	}
+	if a.Set("late", 1) {
+		t.Error("Set after Close was accepted")
+	}
+	if err := a.Sync(context.Background()); err != ErrClosed {
+		t.Errorf("Sync after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestAsyncCacheSync(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(a *AsyncCache)
+		want map[interface{}]interface{}
+	}{
+		{
+			name: "sets",
+			ops:  func(a *AsyncCache) { a.Set("a", 1); a.Set("b", 2) },
+			want: map[interface{}]interface{}{"a": 1, "b": 2},
+		},
+		{
+			name: "overwrite",
+			ops:  func(a *AsyncCache) { a.Set("a", 1); a.Set("a", 2) },
+			want: map[interface{}]interface{}{"a": 2},
+		},
+		{
+			name: "remove",
+			ops:  func(a *AsyncCache) { a.Set("a", 1); a.Set("b", 2); a.Remove("a") },
+			want: map[interface{}]interface{}{"b": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAsync(16, WithSize(10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.Close(context.Background())
+
+			tt.ops(a)
+			if err := a.Sync(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if a.Len() != len(tt.want) {
+				t.Errorf("Len() = %d, want %d", a.Len(), len(tt.want))
+			}
+			for k, want := range tt.want {
+				if v, ok := a.Get(k); !ok || v != want {
+					t.Errorf("Get(%v) = %v, %v, want %v", k, v, ok, want)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkAsyncGet compares parallel reads of AsyncCache and Cache while
+// one goroutine keeps writing, run it with -race to check the read paths
+func BenchmarkAsyncGet(b *testing.B) {
+	const keys = 1024
+
+	a, err := NewAsync(keys, WithSize(keys))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer a.Close(context.Background())
+	c, err := New(WithSize(keys))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < keys; i++ {
+		a.Set(i, i)
+		c.Set(i, i)
+	}
+	a.Sync(context.Background())
+
+	benchmarks := []struct {
+		name string
+		get  func(k int) (interface{}, bool)
+		set  func(k int)
+	}{
+		{"async", func(k int) (interface{}, bool) { return a.Get(k) }, func(k int) { a.Set(k, k) }},
+		{"rwmutex", func(k int) (interface{}, bool) { return c.Get(k) }, func(k int) { c.Set(k, k) }},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+						bm.set(i % keys)
+					}
+				}
+			}()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					bm.get(i % keys)
+				}
+			})
+			b.StopTimer()
+			close(stop)
+			<-done
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package lrucache
+
+import "sync"
+
+// WithAsyncEviction runs the eviction callback set by WithOnEvict on workers
+// goroutines instead of under the cache lock, so it may be slow and may call
+// back into the cache. Up to queue callbacks wait for a worker, one that
+// does not fit runs inline as without this option. Callbacks for a key may
+// run out of order. Close waits for the queued callbacks and stops the
+// workers, see Drain
+func WithAsyncEviction(workers, queue int) Option {
+	return func(c *Cache) {
+		if workers <= 0 {
+			workers = 1
+		}
+		if queue < 0 {
+			queue = 0
+		}
+		// onEvicted is read when called, it may be set by a later option
+		c.evictPool = newEvictPool(workers, queue, func(k, v interface{}) {
+			c.onEvicted(k, v)
+		})
+	}
+}
+
+// Drain waits until the eviction callbacks dispatched so far have returned
+func (c *Cache) Drain() {
+	if c.evictPool != nil {
+		c.evictPool.drain()
+	}
+}
+
+type evictJob struct {
+	k, v interface{}
+}
+
+type evictPool struct {
+	jobs    chan evictJob
+	onEvict func(k, v interface{})
+
+	// closed is guarded by the cache lock, like every dispatch
+	closed bool
+
+	mu      sync.Mutex
+	idle    *sync.Cond
+	pending int
+}
+
+func newEvictPool(workers, queue int, onEvict func(k, v interface{})) *evictPool {
+	p := &evictPool{
+		jobs:    make(chan evictJob, queue),
+		onEvict: onEvict,
+	}
+	p.idle = sync.NewCond(&p.mu)
+
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *evictPool) work() {
+	for job := range p.jobs {
+		p.onEvict(job.k, job.v)
+
+		p.mu.Lock()
+		p.pending--
+		if p.pending == 0 {
+			p.idle.Broadcast()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// dispatch queues the callback for k, it returns false if the caller must
+// run it inline because the queue is full or the pool is closed
+func (p *evictPool) dispatch(k, v interface{}) bool {
+	if p.closed {
+		return false
+	}
+
+	p.mu.Lock()
+	p.pending++
+	p.mu.Unlock()
+
+	select {
+	case p.jobs <- evictJob{k: k, v: v}:
+		return true
+	default:
+	}
+
+	p.mu.Lock()
+	p.pending--
+	if p.pending == 0 {
+		p.idle.Broadcast()
+	}
+	p.mu.Unlock()
+	return false
+}
+
+func (p *evictPool) drain() {
+	p.mu.Lock()
+	for p.pending > 0 {
+		p.idle.Wait()
+	}
+	p.mu.Unlock()
+}
+
+// close stops accepting callbacks, the workers exit once the queue is empty
+func (p *evictPool) close() {
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+}
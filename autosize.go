@@ -0,0 +1,149 @@
+package lrucache
+
+import (
+	"math"
+	"runtime"
+	"time"
+)
+
+// AutoSize configures WithAutoSize
+type AutoSize struct {
+	// Min and Max bound the size the controller sets, Min defaults to 1
+	// and Max must be set
+	Min, Max int
+
+	// Interval is how often the size is adjusted, a minute by default
+	Interval time.Duration
+
+	// TargetHitRatio is the hit ratio the controller aims for, 0.9 by
+	// default. A full cache grows while it hits less and shrinks while it
+	// hits more than TargetHitRatio plus Band, 0.05 by default
+	TargetHitRatio float64
+	Band           float64
+
+	// Step is the fraction of the size added or removed per adjustment,
+	// 0.1 by default
+	Step float64
+
+	// MaxHeapBytes shrinks the cache whenever runtime.MemStats.HeapAlloc is
+	// above it, whatever the hit ratio, 0 ignores the heap
+	MaxHeapBytes uint64
+
+	// OnResize is called after every change of the size, from the
+	// controller goroutine
+	OnResize func(oldSize, newSize int)
+}
+
+// WithAutoSize starts a goroutine adjusting the size limit every interval
+// from the hit ratio seen since the last adjustment and the heap size, it
+// replaces WithSize and is stopped by Close. A full cache missing its
+// target hit ratio grows by a step, one well above it shrinks by a step,
+// and any heap above MaxHeapBytes shrinks it. The cache starts at Min
+func WithAutoSize(cfg AutoSize) Option {
+	return func(c *Cache) {
+		if cfg.Min <= 0 {
+			cfg.Min = 1
+		}
+		if cfg.Max < cfg.Min {
+			cfg.Max = cfg.Min
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = time.Minute
+		}
+		if cfg.TargetHitRatio <= 0 {
+			cfg.TargetHitRatio = 0.9
+		}
+		if cfg.Band <= 0 {
+			cfg.Band = 0.05
+		}
+		if cfg.Step <= 0 {
+			cfg.Step = 0.1
+		}
+		c.autoSize = &autoSizer{cfg: cfg, heapAlloc: heapAlloc}
+		c.lru.Resize(cfg.Min)
+	}
+}
+
+type autoSizer struct {
+	cfg AutoSize
+
+	// hits and misses are the counters at the last adjustment
+	hits, misses uint64
+
+	heapAlloc func() uint64
+}
+
+func heapAlloc() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func (c *Cache) startAutoSize() {
+	a := c.autoSize
+	if a == nil {
+		return
+	}
+
+	ticker := c.clock.NewTicker(a.cfg.Interval)
+	if c.stop == nil {
+		c.stop = make(chan struct{})
+	}
+	stop := c.stop
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				c.adjustSize()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// adjustSize runs one step of the controller
+func (c *Cache) adjustSize() {
+	a := c.autoSize
+	st := c.Stats()
+	prevHits, prevMisses := a.hits, a.misses
+	a.hits, a.misses = st.Hits, st.Misses
+
+	// after a ResetStats the counters count from the reset
+	if st.Hits < prevHits || st.Misses < prevMisses {
+		prevHits, prevMisses = 0, 0
+	}
+	hits, misses := st.Hits-prevHits, st.Misses-prevMisses
+
+	c.lock.Lock()
+	size := c.lru.Cap()
+	full := c.lru.Len() >= size
+	c.lock.Unlock()
+
+	target := size
+	step := max(int(math.Ceil(float64(size)*a.cfg.Step)), 1)
+	switch {
+	case a.cfg.MaxHeapBytes > 0 && a.heapAlloc() > a.cfg.MaxHeapBytes:
+		target = size - step
+	case hits+misses == 0:
+	case ratio(hits, misses) < a.cfg.TargetHitRatio && full:
+		target = size + step
+	case ratio(hits, misses) > a.cfg.TargetHitRatio+a.cfg.Band:
+		target = size - step
+	}
+	target = min(max(target, a.cfg.Min), a.cfg.Max)
+	if target == size {
+		return
+	}
+
+	c.Resize(target)
+	if a.cfg.OnResize != nil {
+		a.cfg.OnResize(size, target)
+	}
+}
+
+func ratio(hits, misses uint64) float64 {
+	return float64(hits) / float64(hits+misses)
+}
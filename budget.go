@@ -0,0 +1,123 @@
+package lrucache
+
+import (
+	"sync"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// SharedBudget bounds the approximate memory of several caches together,
+// for instance one cache per tenant, instead of giving each one a limit of
+// its own. It is safe for concurrent use
+type SharedBudget struct {
+	mu       sync.Mutex
+	maxBytes int64
+
+	// caches holds the bytes of each cache when last seen
+	caches map[*Cache]int64
+}
+
+// NewSharedBudget creates a budget of maxBytes shared by the caches created
+// with WithSharedBudget
+func NewSharedBudget(maxBytes int64) *SharedBudget {
+	return &SharedBudget{maxBytes: maxBytes, caches: make(map[*Cache]int64)}
+}
+
+// WithSharedBudget counts the entries of the cache against b, measured as
+// by WithMaxBytes, which it replaces along with WithCost. A write that takes
+// the caches of b over its limit sheds entries from the largest of them,
+// coldest first and reporting simplelru.EvictReasonPressure, until they fit
+// again. A cache busy with another write when that happens is skipped and
+// the next largest one sheds instead. Close takes the cache off b
+func WithSharedBudget(b *SharedBudget) Option {
+	return func(c *Cache) {
+		c.budget = b
+	}
+}
+
+// Bytes returns the bytes of the caches of b when last seen
+func (b *SharedBudget) Bytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var total int64
+	for _, n := range b.caches {
+		total += n
+	}
+	return total
+}
+
+func (b *SharedBudget) add(c *Cache) {
+	b.mu.Lock()
+	b.caches[c] = c.lru.Cost()
+	b.mu.Unlock()
+}
+
+func (b *SharedBudget) remove(c *Cache) {
+	b.mu.Lock()
+	delete(b.caches, c)
+	b.mu.Unlock()
+}
+
+// charge brings the caches back under the budget after c grew, the lock of
+// c is held. The locks of the other caches are only tried, so two caches
+// charging at once never wait for each other
+func (b *SharedBudget) charge(c *Cache) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.caches[c]; !ok {
+		return
+	}
+	var total int64
+	for o := range b.caches {
+		if o == c {
+			b.caches[o] = c.lru.Cost()
+		} else if o.lock.TryRLock() {
+			b.caches[o] = o.lru.Cost()
+			o.lock.RUnlock()
+		}
+		total += b.caches[o]
+	}
+
+	skip := make(map[*Cache]bool)
+	for total > b.maxBytes {
+		var largest *Cache
+		for o, n := range b.caches {
+			if !skip[o] && (largest == nil || n > b.caches[largest]) {
+				largest = o
+			}
+		}
+		if largest == nil {
+			return
+		}
+		skip[largest] = true
+		if largest != c && !largest.lock.TryLock() {
+			continue
+		}
+		before := largest.lru.Cost()
+		largest.lru.ShedCost(total - b.maxBytes)
+		b.caches[largest] = largest.lru.Cost()
+		if largest != c {
+			largest.lock.Unlock()
+		}
+		total -= before - b.caches[largest]
+	}
+}
+
+// chargeBudget charges the shared budget of c, if any, for the entries just
+// added, the lock is held
+func (c *Cache) chargeBudget() {
+	if c.budget != nil {
+		c.budget.charge(c)
+	}
+}
+
+// joinBudget measures the cache for its shared budget and registers it
+func (c *Cache) joinBudget() {
+	if c.budget == nil {
+		return
+	}
+	c.lru.SetMaxBytes(simplelru.NoLimitCost)
+	c.budget.add(c)
+}
@@ -0,0 +1,92 @@
+package lrucache
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSharedBudget(t *testing.T) {
+	value := strings.Repeat("v", 1<<10)
+
+	tests := []struct {
+		name string
+		// fill sets entries into the two caches of a budget of 16KiB
+		fill func(a, b *Cache)
+		// minA and minB are how many entries each keeps at least, maxA and
+		// maxB at most
+		minA, maxA, minB, maxB int
+	}{
+		{
+			name: "under budget",
+			fill: func(a, b *Cache) {
+				for i := 0; i < 4; i++ {
+					a.Set(i, value)
+					b.Set(i, value)
+				}
+			},
+			minA: 4, maxA: 4, minB: 4, maxB: 4,
+		},
+		{
+			name: "inserting into one evicts from the other",
+			fill: func(a, b *Cache) {
+				for i := 0; i < 12; i++ {
+					a.Set(i, value)
+				}
+				for i := 0; i < 12; i++ {
+					b.Set(i, value)
+				}
+			},
+			minA: 1, maxA: 11, minB: 1, maxB: 12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := NewSharedBudget(16 << 10)
+			a, err := New(WithSize(100), WithSharedBudget(budget))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := New(WithSize(100), WithSharedBudget(budget))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.fill(a, b)
+			if n := a.Len(); n < tt.minA || n > tt.maxA {
+				t.Errorf("a holds %d entries, want %d to %d", n, tt.minA, tt.maxA)
+			}
+			if n := b.Len(); n < tt.minB || n > tt.maxB {
+				t.Errorf("b holds %d entries, want %d to %d", n, tt.minB, tt.maxB)
+			}
+			if total := budget.Bytes(); total > 16<<10 {
+				t.Errorf("the caches hold %d bytes, over the budget", total)
+			}
+			if total := a.Stats().Bytes + b.Stats().Bytes; total > 16<<10 {
+				t.Errorf("the caches report %d bytes, over the budget", total)
+			}
+		})
+	}
+}
+
+func TestSharedBudgetClose(t *testing.T) {
+	budget := NewSharedBudget(16 << 10)
+	a, _ := New(WithSize(100), WithSharedBudget(budget))
+	b, _ := New(WithSize(100), WithSharedBudget(budget))
+	value := strings.Repeat("v", 1<<10)
+	for i := 0; i < 12; i++ {
+		a.Set(i, value)
+	}
+
+	// a closed cache no longer counts, so b fills the budget on its own
+	if err := a.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 12; i++ {
+		b.Set(i, value)
+	}
+	if a.Len() != 12 || b.Len() != 12 {
+		t.Errorf("a holds %d and b %d entries, want 12 each", a.Len(), b.Len())
+	}
+}
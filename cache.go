@@ -0,0 +1,709 @@
+// Package lrucache implements a thread safe lru cache on top of simplelru
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Cache is a thread safe simplelru.LRU. Methods that change recency hold the
+// write lock, pure reads share the read lock. The eviction callback runs
+// with the lock held and must not call back into the cache
+type Cache struct {
+	lock sync.RWMutex
+
+	lru       *simplelru.LRU
+	onEvicted simplelru.EvictCallback
+	onSet     func(c simplelru.LRUCache, k, v interface{})
+
+	store  Store
+	behind *writeBehind
+
+	events *events
+	opLog  func(op Op)
+
+	evictPool *evictPool
+
+	// reads buffers the recency updates of Get, see WithBufferedReads.
+	// readHits and readMisses count the buffered Gets as they happen, the
+	// lru only sees them replayed
+	reads      *readBuffer
+	readHits   atomic.Uint64
+	readMisses atomic.Uint64
+
+	// equal compares values for CompareAndSwap
+	equal func(a, b interface{}) bool
+
+	loader   Loader
+	loads    flightGroup
+	maxStale time.Duration
+
+	// loadSlots holds a token per running load, see WithMaxConcurrentLoads
+	loadSlots chan struct{}
+
+	// negativeTTL is how long loads of missing keys are remembered, and
+	// negativeHits counts Gets finding such an entry, see SetNegative
+	negativeTTL  time.Duration
+	negativeHits atomic.Uint64
+
+	// refreshBelow is the ttl left under which reads reload an entry, see
+	// WithRefreshAhead
+	refreshBelow time.Duration
+
+	// retries and retryBackoff say how failed loads are retried, see
+	// WithLoaderRetry
+	retries      int
+	retryBackoff func(attempt int) time.Duration
+
+	// sweeping is set while a goroutine removes the entries of old
+	// generations, see NewGeneration
+	sweeping atomic.Bool
+
+	clock clock.Clock
+
+	janitorInterval time.Duration
+	janitorJitter   time.Duration
+	autoSize        *autoSizer
+	pressure        *pressureWatcher
+	window          *statsWindow
+	thrash          *thrashDetector
+	budget          *SharedBudget
+	stop            chan struct{}
+	closeOnce       sync.Once
+	closeErr        error
+	closed          atomic.Bool
+
+	// snapshotOnClose receives a snapshot from Close, see
+	// WithSnapshotOnClose
+	snapshotOnClose io.Writer
+}
+
+// New creates a cache configured by opts, without options it has no size
+// limit and no ttl
+func New(opts ...Option) (*Cache, error) {
+	c := &Cache{onEvicted: func(k, v interface{}) {}, equal: defaultEqual, clock: clock.Real}
+	lru, err := simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, c.evicted)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = lru
+	lru.SetContextWatch(c.contextDone)
+
+	c.configure(opts)
+	return c, nil
+}
+
+// configure applies opts and starts the goroutines they ask for
+func (c *Cache) configure(opts []Option) {
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.setEventHook()
+	c.joinBudget()
+	c.startJanitor()
+	c.startAutoSize()
+	c.startPressureWatcher()
+	c.startStatsWindow()
+	c.startThrashDetector()
+	if c.behind != nil {
+		c.behind.start(c.clock)
+	}
+}
+
+// NewLRU creates a cache of the given size, ttl and eviction callback, it is
+// New with WithSize, WithTTL and WithOnEvict in front of opts
+func NewLRU(size int, ttl time.Duration, onEvict simplelru.EvictCallback, opts ...Option) (*Cache, error) {
+	return New(append([]Option{WithSize(size), WithTTL(ttl), WithOnEvict(onEvict)}, opts...)...)
+}
+
+// Set adds or updates an entry, with a store it is written as described by
+// WithWriteThrough or WithWriteBehind, see Put for the error
+func (c *Cache) Set(k, v interface{}) {
+	c.Put(k, v)
+}
+
+// SetX works like Set and returns the entry evicted to make room, if any
+func (c *Cache) SetX(k, v interface{}) (evictedKey, evictedValue interface{}, evicted bool) {
+	c.write(k, v, func() {
+		evictedKey, evictedValue, evicted = c.lru.SetX(k, v)
+	})
+	return
+}
+
+// Add works like Set and reports whether an entry was evicted to make room
+func (c *Cache) Add(k, v interface{}) (evicted bool) {
+	c.write(k, v, func() { evicted = c.lru.Add(k, v) })
+	return
+}
+
+// ContainsOrAdd adds k unless it is live in the cache, in one step. evicted
+// reports whether adding it evicted another entry
+func (c *Cache) ContainsOrAdd(k, v interface{}) (existed, evicted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.lru.Contains(k) {
+		return true, false
+	}
+	c.writeLocked(k, v, func() { _, _, evicted = c.lru.SetX(k, v) })
+	return false, evicted
+}
+
+// PeekOrAdd returns the live value of k without moving it to head, or adds
+// v if there is none, in one step
+func (c *Cache) PeekOrAdd(k, v interface{}) (prev interface{}, existed, evicted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if prev, ok := c.lru.Peek(k); ok {
+		return prev, true, false
+	}
+	c.writeLocked(k, v, func() { _, _, evicted = c.lru.SetX(k, v) })
+	return nil, false, evicted
+}
+
+// SetWithTTL adds an entry that expires after ttl instead of the cache ttl
+func (c *Cache) SetWithTTL(k, v interface{}, ttl time.Duration) {
+	c.write(k, v, func() { c.lru.SetWithTTL(k, v, ttl) })
+}
+
+// SetWithCost adds an entry with the given cost instead of the computed one
+func (c *Cache) SetWithCost(k, v interface{}, cost int64) {
+	c.write(k, v, func() { c.lru.SetWithCost(k, v, cost) })
+}
+
+// SetWithPriority adds an entry that capacity eviction only takes once the
+// entries of lower priorities are gone
+func (c *Cache) SetWithPriority(k, v interface{}, priority int) {
+	c.write(k, v, func() { c.lru.SetWithPriority(k, v, priority) })
+}
+
+// SetWithTags adds an entry carrying tags, see InvalidateTag
+func (c *Cache) SetWithTags(k, v interface{}, tags ...string) {
+	c.write(k, v, func() { c.lru.SetWithTags(k, v, tags...) })
+}
+
+// SetWithContext adds an entry that is treated as expired once ctx is done,
+// and removed then. The watch of ctx stops when the entry is replaced or
+// removed first
+func (c *Cache) SetWithContext(ctx context.Context, k, v interface{}) {
+	c.write(k, v, func() { c.lru.SetWithContext(ctx, k, v) })
+}
+
+// contextDone removes k once the context it was set with is done
+func (c *Cache) contextDone(k interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.closed.Load() {
+		c.lru.ExpireContext(k)
+	}
+}
+
+// Get returns the value of k, filling a miss through the loader set by
+// WithLoader if any. Load errors are reported as a miss, see GetContext. A
+// negative entry is returned as Negative, see SetNegative
+func (c *Cache) Get(k interface{}) (v interface{}, ok bool) {
+	if c.loader == nil {
+		return c.get(k)
+	}
+	v, err := c.getOrLoad(context.Background(), k, c.loader.Load)
+	return v, err == nil || IsNegative(v)
+}
+
+func (c *Cache) get(k interface{}) (v interface{}, ok bool) {
+	if c.reads != nil {
+		v, ok = c.getBuffered(k)
+	} else {
+		c.lock.Lock()
+		v, ok = c.lru.Get(k)
+		c.lock.Unlock()
+	}
+	c.countNegative(v)
+	return v, ok
+}
+
+// MGet returns the live values of keys, missing keys are left out. The lock
+// is taken once for the whole batch and misses are not loaded
+func (c *Cache) MGet(keys ...interface{}) map[interface{}]interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	m := make(map[interface{}]interface{}, len(keys))
+	for _, k := range keys {
+		if v, ok := c.lru.Get(k); ok {
+			c.countNegative(v)
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// MSet sets every entry of m like Set, taking the lock once for the whole
+// batch. Entries are set in map order, so with a full cache any of them may
+// evict another
+func (c *Cache) MSet(m map[interface{}]interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for k, v := range m {
+		c.writeLocked(k, v, func() { c.lru.Set(k, v) })
+	}
+}
+
+// GetTagged works like Get and attributes the hit or miss to callerTag
+func (c *Cache) GetTagged(k interface{}, callerTag string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.GetTagged(k, callerTag)
+}
+
+// TagStats returns the counters recorded by GetTagged per tag
+func (c *Cache) TagStats() map[string]simplelru.Stats {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.TagStats()
+}
+
+// Stats returns the counters of the cache
+func (c *Cache) Stats() simplelru.Stats {
+	c.lock.RLock()
+	st := c.lru.Stats()
+	c.lock.RUnlock()
+	st.Hits += c.readHits.Load()
+	st.Misses += c.readMisses.Load()
+	st.NegativeHits = min(c.negativeHits.Load(), st.Hits)
+	st.Hits -= st.NegativeHits
+	return st
+}
+
+// WouldHit returns how many misses a cache holding extra more entries would
+// have hit, see WithGhostList
+func (c *Cache) WouldHit(extra int) uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.reads != nil {
+		c.reads.flush(c)
+	}
+	return c.lru.WouldHit(extra)
+}
+
+// ResetStats zeroes the counters of the cache
+func (c *Cache) ResetStats() {
+	c.lock.Lock()
+	c.lru.ResetStats()
+	c.negativeHits.Store(0)
+	c.readHits.Store(0)
+	c.readMisses.Store(0)
+	c.lock.Unlock()
+}
+
+// GetBytes works like Get for []byte values and returns a copy
+func (c *Cache) GetBytes(k interface{}) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.GetBytes(k)
+}
+
+// GetWithTTL works like Get and also returns the remaining ttl of the entry
+func (c *Cache) GetWithTTL(k interface{}) (v interface{}, ttl time.Duration, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.GetWithTTL(k)
+}
+
+// GetWithExpiration works like Get and also returns when the entry expires,
+// the zero time if it does not
+func (c *Cache) GetWithExpiration(k interface{}) (v interface{}, expiresAt time.Time, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.GetWithExpiration(k)
+}
+
+// EntryInfo returns the metadata of a live entry without touching it
+func (c *Cache) EntryInfo(k interface{}) (simplelru.EntryInfo, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.EntryInfo(k)
+}
+
+// PeekWithTTL works like GetWithTTL without moving the entry to head
+func (c *Cache) PeekWithTTL(k interface{}) (v interface{}, ttl time.Duration, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.PeekWithTTL(k)
+}
+
+// GetDetailed works like Get and removes the entry if it is found expired
+func (c *Cache) GetDetailed(k interface{}) (v interface{}, result simplelru.GetResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.GetDetailed(k)
+}
+
+// SetTTLForKey gives a live entry its own ttl counted from now
+func (c *Cache) SetTTLForKey(k interface{}, ttl time.Duration) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return false
+	}
+	return c.lru.SetTTLForKey(k, ttl)
+}
+
+// Touch resets the ttl of a live entry and moves it to head
+func (c *Cache) Touch(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return false
+	}
+	return c.lru.Touch(k)
+}
+
+// UpdateTTL changes the ttl of a live entry without touching it
+func (c *Cache) UpdateTTL(k interface{}, ttl time.Duration) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return false
+	}
+	return c.lru.UpdateTTL(k, ttl)
+}
+
+// RefreshMany resets the ttl of the given live keys
+func (c *Cache) RefreshMany(keys []interface{}) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.RefreshMany(keys)
+}
+
+// NextToExpire returns the live entry with the earliest expiry time
+func (c *Cache) NextToExpire() (k, v interface{}, at time.Time, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.NextToExpire()
+}
+
+func (c *Cache) Contains(k interface{}) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Contains(k)
+}
+
+// Peek get a cache without move it to head
+func (c *Cache) Peek(k interface{}) (v interface{}, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Peek(k)
+}
+
+// Remove removes an entry, with a store it is also deleted there, see Delete
+// for the error
+func (c *Cache) Remove(k interface{}) bool {
+	ok, _ := c.remove(k)
+	return ok
+}
+
+// ErrAllPinned is returned by Put for a new key when the cache is full and
+// every entry is pinned
+var ErrAllPinned = errors.New("lrucache: cache is full of pinned entries")
+
+// Pin keeps a live entry in the cache until Unpin, it is skipped by
+// capacity eviction and ttl expiry. It returns false if k is absent
+func (c *Cache) Pin(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return false
+	}
+	return c.lru.Pin(k)
+}
+
+// Unpin makes a pinned entry evictable again, it returns false if k is not
+// pinned
+func (c *Cache) Unpin(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return false
+	}
+	return c.lru.Unpin(k)
+}
+
+// RemoveFunc removes every entry for which pred returns true under one lock
+// acquisition, it returns how many were removed. pred runs with the lock
+// held. With a store the entries are removed from the cache only
+func (c *Cache) RemoveFunc(pred func(k, v interface{}) bool) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.RemoveFunc(pred)
+}
+
+// InvalidateTag removes every entry carrying tag, it returns how many were
+// removed. With a store the entries are removed from the cache only
+func (c *Cache) InvalidateTag(tag string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.InvalidateTag(tag)
+}
+
+// Tags returns the tags of a live entry
+func (c *Cache) Tags(k interface{}) []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Tags(k)
+}
+
+// TakeOrCreate removes and returns the live value of k in one step, on a
+// miss it returns the result of create. create runs with the lock held
+func (c *Cache) TakeOrCreate(k interface{}, create func() interface{}) interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return create()
+	}
+	return c.lru.TakeOrCreate(k, create)
+}
+
+// Pop removes and returns the live value of k in one step, without calling
+// the eviction callback. A store keeps the value, see Delete
+func (c *Cache) Pop(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return nil, false
+	}
+	return c.lru.Pop(k)
+}
+
+func (c *Cache) RemoveOldest() (k, v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return nil, nil, false
+	}
+	return c.lru.RemoveOldest()
+}
+
+// GetOldest returns the entry at the tail of the eviction list without
+// changing the order, it may be expired
+func (c *Cache) GetOldest() (k, v interface{}, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.GetOldest()
+}
+
+// GetNewest returns the entry at the head of the eviction list without
+// changing the order, it may be expired
+func (c *Cache) GetNewest() (k, v interface{}, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.GetNewest()
+}
+
+// RemoveOldestN removes up to n oldest entries without changing the size
+func (c *Cache) RemoveOldestN(n int) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.RemoveOldestN(n)
+}
+
+// Shed removes the coldest fraction of the entries reporting
+// simplelru.EvictReasonPressure, see WithMemoryPressure. It returns how many
+// were removed
+func (c *Cache) Shed(fraction float64) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.Shed(fraction)
+}
+
+// Len returns the number of entries, expired ones that were not removed yet
+// included, see LenValid and EvictExpired
+func (c *Cache) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Len()
+}
+
+// LenValid returns the number of entries that are not expired
+func (c *Cache) LenValid() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.LenValid()
+}
+
+// IsFull reports whether the cache holds as many entries as its size allows
+func (c *Cache) IsFull() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.IsFull()
+}
+
+// Cap returns the size limit of the cache, NoLimitSize if unbounded
+func (c *Cache) Cap() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Cap()
+}
+
+// Cost returns the total cost of the entries in the cache
+func (c *Cache) Cost() int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Cost()
+}
+
+// SetMaxCost changes the cost limit of the cache, it returns how many entries
+// were evicted
+func (c *Cache) SetMaxCost(maxCost int64, cost func(k, v interface{}) int64) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.SetMaxCost(maxCost, cost)
+}
+
+// SetMaxBytes bounds the cache by the approximate memory of its entries, it
+// returns how many entries were evicted
+func (c *Cache) SetMaxBytes(maxBytes int64) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.SetMaxBytes(maxBytes)
+}
+
+// Keys returns keys that are not expired from oldest to newest
+func (c *Cache) Keys() []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Keys()
+}
+
+// AppendKeys appends keys that are not expired from oldest to newest to dst
+func (c *Cache) AppendKeys(dst []interface{}) []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.AppendKeys(dst)
+}
+
+// Range calls fn for every entry that is not expired from newest to oldest
+// until fn returns false. fn runs with the read lock held and must not call
+// back into the cache
+func (c *Cache) Range(fn func(k, v interface{}) bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	c.lru.Range(fn)
+}
+
+// Values returns the values that are not expired from oldest to newest
+func (c *Cache) Values() []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Values()
+}
+
+// Items returns a point in time copy of the entries that are not expired,
+// the values themselves are not copied
+func (c *Cache) Items() map[interface{}]interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Items()
+}
+
+// KeysN returns up to limit keys that are not expired after skipping offset
+// of them in the given order, see simplelru.LRU.KeysN
+func (c *Cache) KeysN(offset, limit int, order simplelru.Order) []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.KeysN(offset, limit, order)
+}
+
+// RangeOrder works like Range in the given order. For a large cache KeysN
+// holds the read lock for one page at a time instead
+func (c *Cache) RangeOrder(order simplelru.Order, fn func(k, v interface{}) bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	c.lru.RangeOrder(order, fn)
+}
+
+// TimestampMap returns the last update time of every key that is not expired
+func (c *Cache) TimestampMap() map[interface{}]time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.TimestampMap()
+}
+
+// EvictExpired removes all expired entries, it returns how many were removed
+func (c *Cache) EvictExpired() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.EvictExpired()
+}
+
+func (c *Cache) Purge() {
+	c.lock.Lock()
+	c.lru.Purge()
+	c.lock.Unlock()
+}
+
+func (c *Cache) Resize(size int) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.Resize(size)
+}
+
+// SetTTL changes the cache ttl at runtime, entries without their own ttl
+// expire by it from their last update. Entries already past it are removed
+// as they are read or by the janitor, see ResizeTTL
+func (c *Cache) SetTTL(ttl time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return
+	}
+	c.lru.SetTTL(ttl)
+}
+
+// ResizeTTL works like SetTTL and removes the entries already past the new
+// ttl right away, it returns how many were removed
+func (c *Cache) ResizeTTL(ttl time.Duration) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0
+	}
+	return c.lru.ResizeTTL(ttl)
+}
+
+var _ simplelru.LRUCache = (*Cache)(nil)
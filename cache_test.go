@@ -0,0 +1,229 @@
+package lrucache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// waitFor polls cond until it holds or a second went by
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+func TestSetWithContext(t *testing.T) {
+	tests := []struct {
+		name string
+		// run sets keys and cancels contexts, it returns the keys expected
+		// in the cache at the end
+		run func(c *Cache) []interface{}
+	}{
+		{
+			name: "cancel removes the entry",
+			run: func(c *Cache) []interface{} {
+				ctx, cancel := context.WithCancel(context.Background())
+				c.SetWithContext(ctx, "a", 1)
+				c.Set("b", 2)
+				cancel()
+				return []interface{}{"b"}
+			},
+		},
+		{
+			name: "remove then set again outlives the old context",
+			run: func(c *Cache) []interface{} {
+				ctx, cancel := context.WithCancel(context.Background())
+				c.SetWithContext(ctx, "a", 1)
+				c.Remove("a")
+				c.Set("a", 2)
+				cancel()
+				return []interface{}{"a"}
+			},
+		},
+		{
+			name: "pop then set again with a new context",
+			run: func(c *Cache) []interface{} {
+				old, cancelOld := context.WithCancel(context.Background())
+				c.SetWithContext(old, "a", 1)
+				c.Pop("a")
+				c.SetWithContext(context.Background(), "a", 2)
+				cancelOld()
+				return []interface{}{"a"}
+			},
+		},
+		{
+			name: "replaced by a plain set",
+			run: func(c *Cache) []interface{} {
+				ctx, cancel := context.WithCancel(context.Background())
+				c.SetWithContext(ctx, "a", 1)
+				c.Set("a", 2)
+				cancel()
+				return []interface{}{"a"}
+			},
+		},
+		{
+			name: "already done context",
+			run: func(c *Cache) []interface{} {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				c.SetWithContext(ctx, "a", 1)
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(WithSize(10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.run(c)
+			ok := waitFor(t, func() bool { return c.Len() == len(want) })
+			if !ok {
+				t.Fatalf("Len() = %d, want %d", c.Len(), len(want))
+			}
+			// give a wrong removal the time to happen
+			time.Sleep(10 * time.Millisecond)
+			for _, k := range want {
+				if !c.Contains(k) {
+					t.Errorf("%v was removed", k)
+				}
+			}
+			if c.Len() != len(want) {
+				t.Errorf("Len() = %d, want %d", c.Len(), len(want))
+			}
+		})
+	}
+}
+
+func TestAppendKeys(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c, err := New(WithSize(10), WithClock(clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Second)
+	c.Set("c", 3)
+	clk.Advance(2 * time.Second)
+
+	tests := []struct {
+		name string
+		dst  []interface{}
+		want []interface{}
+	}{
+		{name: "nil", dst: nil, want: []interface{}{"a", "c"}},
+		{name: "appends", dst: []interface{}{"x"}, want: []interface{}{"x", "a", "c"}},
+		{name: "room", dst: make([]interface{}, 0, 8), want: []interface{}{"a", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.AppendKeys(tt.dst)
+			if len(got) != len(tt.want) {
+				t.Fatalf("AppendKeys() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("AppendKeys() = %v, want %v", got, tt.want)
+				}
+			}
+			if cap(tt.dst) >= len(tt.want) && &got[0] != &tt.dst[:1][0] {
+				t.Error("AppendKeys reallocated a buffer with room")
+			}
+		})
+	}
+}
+
+func BenchmarkAppendKeys(b *testing.B) {
+	const keys = 1024
+
+	c, err := New(WithSize(keys))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < keys; i++ {
+		c.Set(i, i)
+	}
+	buf := make([]interface{}, 0, keys)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = c.AppendKeys(buf[:0])
+	}
+}
+
+func TestRemoveOldestN(t *testing.T) {
+	lru, _ := simplelru.NewLRU(10, simplelru.NoLimitTTL, nil)
+	cache, _ := New(WithSize(10))
+	caches := map[string]simplelru.LRUCache{"simplelru": lru, "Cache": cache}
+
+	for name, c := range caches {
+		for _, n := range []int{-1, 0, 2, 10} {
+			t.Run(fmt.Sprint(name, "/", n), func(t *testing.T) {
+				c.Purge()
+				for i := 0; i < 4; i++ {
+					c.Set(i, i)
+				}
+				want := min(max(n, 0), 4)
+				if got := c.RemoveOldestN(n); got != want || c.Len() != 4-want {
+					t.Errorf("RemoveOldestN(%d) = %d leaving %d, want %d leaving %d", n, got, c.Len(), want, 4-want)
+				}
+				if n > 0 && c.Contains(0) {
+					t.Error("the oldest entry was kept")
+				}
+			})
+		}
+	}
+}
+
+func TestTakeOrCreate(t *testing.T) {
+	c, err := New(WithSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("buf", "pooled")
+
+	// the pooled value goes to exactly one caller, the others create
+	var created atomic.Int32
+	results := make(chan interface{}, 50)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- c.TakeOrCreate("buf", func() interface{} {
+				created.Add(1)
+				return "new"
+			})
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	pooled := 0
+	for v := range results {
+		if v == "pooled" {
+			pooled++
+		}
+	}
+	if pooled != 1 || created.Load() != 49 {
+		t.Errorf("pooled value taken %d times and create called %d times, want 1 and 49", pooled, created.Load())
+	}
+	if c.Contains("buf") {
+		t.Error("buf is still cached after TakeOrCreate")
+	}
+}
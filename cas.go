@@ -0,0 +1,57 @@
+package lrucache
+
+import "reflect"
+
+// WithEqual sets how CompareAndSwap compares values. The default uses ==
+// for values of comparable types and treats other values as different
+func WithEqual(equal func(a, b interface{}) bool) Option {
+	return func(c *Cache) {
+		if equal != nil {
+			c.equal = equal
+		}
+	}
+}
+
+func defaultEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta.Comparable() && a == b
+}
+
+// SetIfAbsent adds k unless it is live in the cache, in one step. It reports
+// whether k was added, so only one of several concurrent callers wins
+func (c *Cache) SetIfAbsent(k, v interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.lru.Contains(k) {
+		return false
+	}
+	added := false
+	c.writeLocked(k, v, func() {
+		c.lru.Set(k, v)
+		added = true
+	})
+	return added
+}
+
+// CompareAndSwap sets k to newValue if its live value equals oldValue, see
+// WithEqual, in one step. It reports whether the value was swapped, the
+// swap counts as a Set of k
+func (c *Cache) CompareAndSwap(k, oldValue, newValue interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	v, ok := c.lru.Peek(k)
+	if !ok || !c.equal(v, oldValue) {
+		return false
+	}
+	swapped := false
+	c.writeLocked(k, newValue, func() {
+		c.lru.Set(k, newValue)
+		swapped = true
+	})
+	return swapped
+}
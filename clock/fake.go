@@ -0,0 +1,90 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to, its tickers fire from
+// Advance and Set. It is safe for concurrent use
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFake creates a fake clock reading start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the clock forward by d, see Set
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the clock to t. Every ticker due by t fires once, a ticker due
+// several times only delivers one tick like time.Ticker with a slow reader
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	f.now = t
+	var due []*fakeTicker
+	for _, tk := range f.tickers {
+		if !tk.next.After(t) {
+			for !tk.next.After(t) {
+				tk.next = tk.next.Add(tk.d)
+			}
+			due = append(due, tk)
+		}
+	}
+	f.mu.Unlock()
+
+	for _, tk := range due {
+		select {
+		case tk.c <- t:
+		default:
+		}
+	}
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tk := &fakeTicker{f: f, d: d, next: f.now.Add(d), c: make(chan time.Time, 1)}
+	f.tickers = append(f.tickers, tk)
+	return tk
+}
+
+type fakeTicker struct {
+	f    *Fake
+	d    time.Duration
+	next time.Time
+	c    chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	for i, tk := range t.f.tickers {
+		if tk == t {
+			t.f.tickers = append(t.f.tickers[:i], t.f.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+var _ Clock = (*Fake)(nil)
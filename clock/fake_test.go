@@ -0,0 +1,93 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeNow(t *testing.T) {
+	start := time.Unix(1000, 0)
+	f := NewFake(start)
+	if !f.Now().Equal(start) {
+		t.Fatalf("Now() = %v, want %v", f.Now(), start)
+	}
+
+	f.Advance(time.Minute)
+	if want := start.Add(time.Minute); !f.Now().Equal(want) {
+		t.Errorf("Now() after Advance = %v, want %v", f.Now(), want)
+	}
+	f.Set(start)
+	if !f.Now().Equal(start) {
+		t.Errorf("Now() after Set = %v, want %v", f.Now(), start)
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	tests := []struct {
+		name string
+		// moves advance the clock one after the other, ticks tells after
+		// each whether the ticker fired
+		moves []time.Duration
+		ticks []bool
+	}{
+		{name: "before the interval", moves: []time.Duration{9 * time.Second}, ticks: []bool{false}},
+		{name: "at the interval", moves: []time.Duration{10 * time.Second}, ticks: []bool{true}},
+		{name: "in steps", moves: []time.Duration{6 * time.Second, 6 * time.Second, 6 * time.Second, 6 * time.Second}, ticks: []bool{false, true, false, true}},
+		// several intervals in one move deliver a single tick, and the next
+		// one is due at the following interval
+		{name: "several intervals at once", moves: []time.Duration{35 * time.Second, 4 * time.Second, time.Second}, ticks: []bool{true, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFake(time.Unix(0, 0))
+			tk := f.NewTicker(10 * time.Second)
+			defer tk.Stop()
+
+			for i, d := range tt.moves {
+				f.Advance(d)
+				select {
+				case at := <-tk.C():
+					if !tt.ticks[i] {
+						t.Errorf("move %d: unexpected tick", i)
+					}
+					if !at.Equal(f.Now()) {
+						t.Errorf("move %d: tick at %v, want %v", i, at, f.Now())
+					}
+				default:
+					if tt.ticks[i] {
+						t.Errorf("move %d: no tick", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFakeTickerStop(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	stopped := f.NewTicker(time.Second)
+	running := f.NewTicker(time.Second)
+	stopped.Stop()
+
+	f.Advance(time.Second)
+	select {
+	case <-stopped.C():
+		t.Error("a stopped ticker fired")
+	default:
+	}
+	select {
+	case <-running.C():
+	default:
+		t.Error("stopping one ticker stopped another")
+	}
+}
+
+func TestFakeTickerInterval(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewTicker(0) did not panic")
+		}
+	}()
+	NewFake(time.Unix(0, 0)).NewTicker(0)
+}
@@ -0,0 +1,87 @@
+package lrucache
+
+import (
+	"time"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Clone returns an independent cache holding a copy of the entries with
+// their recency and ttl, and the size, ttl, policy and other settings of c,
+// see simplelru.LRU.Clone. The eviction callbacks, clock and equality are
+// kept. A store, loader, events, op log, shared budget, eviction workers and
+// background goroutines belong to c and are not, opts configures them on the
+// clone
+func (c *Cache) Clone(opts ...Option) *Cache {
+	c.lock.Lock()
+	if c.reads != nil {
+		c.reads.flush(c)
+	}
+	lru := c.lru.Clone()
+	c.lock.Unlock()
+
+	n := &Cache{lru: lru, onEvicted: c.onEvicted, equal: c.equal, clock: c.clock}
+	n.readHits.Store(c.readHits.Load())
+	n.readMisses.Store(c.readMisses.Load())
+	lru.SetEvictCallback(n.evicted)
+	lru.SetEventHook(nil)
+	lru.SetContextWatch(n.contextDone)
+	n.configure(opts)
+	return n
+}
+
+// Merge sets the live entries of other into c from oldest to newest, so they
+// keep their order and come out newest in c. A key live in both gets the
+// value conflict returns for the value of c and the value of other, a nil
+// conflict takes the value of other. Entries of a cache reporting their ttl,
+// such as Cache and simplelru.LRU, keep the time they have left. It returns
+// how many keys were set
+func (c *Cache) Merge(other simplelru.LRUCache, conflict func(k, a, b interface{}) interface{}) int {
+	type ttlPeeker interface {
+		PeekWithTTL(k interface{}) (v interface{}, ttl time.Duration, ok bool)
+	}
+
+	// read other before locking c, other may be c
+	type item struct {
+		k, v interface{}
+		ttl  time.Duration
+	}
+	keys := other.Keys()
+	items := make([]item, 0, len(keys))
+	tp, hasTTL := other.(ttlPeeker)
+	for _, k := range keys {
+		var it item
+		var ok bool
+		if hasTTL {
+			it.v, it.ttl, ok = tp.PeekWithTTL(k)
+		} else {
+			it.v, ok = other.Peek(k)
+		}
+		if ok {
+			it.k = k
+			items = append(items, it)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	n := 0
+	for _, it := range items {
+		v := it.v
+		if mine, ok := c.lru.Peek(it.k); ok && conflict != nil {
+			v = conflict(it.k, mine, it.v)
+		}
+		err := c.writeLocked(it.k, v, func() {
+			if it.ttl > simplelru.NoLimitTTL {
+				c.lru.SetWithTTL(it.k, v, it.ttl)
+			} else {
+				c.lru.Set(it.k, v)
+			}
+		})
+		if err == nil {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,170 @@
+package lrucache
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Codec turns values into bytes and back
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte) (interface{}, error)
+}
+
+// GobCodec encodes values with encoding/gob, concrete value types must be
+// registered with gob.Register
+var GobCodec Codec = gobCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Compressed wraps codec to deflate what it marshals at level, one of the
+// compress/flate levels. Other compressions such as snappy or zstd fit in
+// the same way, as a Codec wrapping another
+func Compressed(codec Codec, level int) Codec {
+	return flateCodec{codec: codec, level: level}
+}
+
+type flateCodec struct {
+	codec Codec
+	level int
+}
+
+func (f flateCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := f.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, f.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (f flateCodec) Unmarshal(b []byte) (interface{}, error) {
+	r := flate.NewReader(bytes.NewReader(b))
+	defer r.Close()
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return f.codec.Unmarshal(raw)
+}
+
+// Encoded stores the values of a Cache marshaled by a Codec instead of as
+// live pointers, trading the cost of encoding every Set and decoding every
+// Get for a smaller heap. WithMaxBytes then budgets the encoded size Get
+// decodes from. Every Get returns a fresh copy, so callers cannot change a
+// cached value. Values must only reach the cache through Encoded, a loader
+// of the cache must return the encoded bytes
+type Encoded struct {
+	cache *Cache
+	codec Codec
+}
+
+// NewEncoded stores the values of c encoded by codec
+func NewEncoded(c *Cache, codec Codec) *Encoded {
+	return &Encoded{cache: c, codec: codec}
+}
+
+// Set encodes v and sets it, see Cache.Put for the error
+func (e *Encoded) Set(k, v interface{}) error {
+	b, err := e.encode(v)
+	if err != nil {
+		return err
+	}
+	return e.cache.Put(k, b)
+}
+
+// SetWithTTL encodes v and sets it with its own ttl, see Cache.SetWithTTL,
+// and Cache.Put for the error
+func (e *Encoded) SetWithTTL(k, v interface{}, ttl time.Duration) error {
+	b, err := e.encode(v)
+	if err != nil {
+		return err
+	}
+	c := e.cache
+	return c.write(k, b, func() { c.lru.SetWithTTL(k, b, ttl) })
+}
+
+// Get returns the decoded value of k like Cache.Get, ErrNotFound on a miss
+func (e *Encoded) Get(k interface{}) (interface{}, error) {
+	v, ok := e.cache.Get(k)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return e.decode(v)
+}
+
+// Peek works like Get without changing recency
+func (e *Encoded) Peek(k interface{}) (interface{}, error) {
+	v, ok := e.cache.Peek(k)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return e.decode(v)
+}
+
+// Remove removes k, see Cache.Remove
+func (e *Encoded) Remove(k interface{}) bool {
+	return e.cache.Remove(k)
+}
+
+// Cache returns the cache holding the encoded values
+func (e *Encoded) Cache() *Cache {
+	return e.cache
+}
+
+func (e *Encoded) encode(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, errors.New("lrucache: nil value")
+	}
+	b, err := e.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// the spare capacity of the encoding buffer would be cached too
+	if cap(b) > len(b) {
+		b = bytes.Clone(b)
+	}
+	return b, nil
+}
+
+func (e *Encoded) decode(v interface{}) (interface{}, error) {
+	if IsNegative(v) {
+		return nil, ErrNotFound
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("lrucache: cached value %T is not encoded", v)
+	}
+	return e.codec.Unmarshal(b)
+}
@@ -0,0 +1,37 @@
+package lrucache
+
+// Increment adds delta to the live int64 value of k in one step and returns
+// the result. The entry keeps its ttl, so a counter set with a ttl expires
+// that long after it was created however often it is incremented. ok is
+// false if k is absent or its value is not an int64
+func (c *Cache) Increment(k interface{}, delta int64) (n int64, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.incrementLocked(k, delta, false, 0)
+}
+
+// IncrementWithDefault works like Increment but adds k with the value
+// initial plus delta if it is absent, expiring by the cache ttl
+func (c *Cache) IncrementWithDefault(k interface{}, delta, initial int64) (n int64, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.incrementLocked(k, delta, true, initial)
+}
+
+func (c *Cache) incrementLocked(k interface{}, delta int64, create bool, initial int64) (int64, bool) {
+	v, found := c.lru.Peek(k)
+	if !found {
+		if !create {
+			return 0, false
+		}
+		n := initial + delta
+		return n, c.writeLocked(k, n, func() { c.lru.Set(k, n) }) == nil
+	}
+
+	n, ok := v.(int64)
+	if !ok {
+		return 0, false
+	}
+	n += delta
+	return n, c.writeLocked(k, n, func() { c.lru.Replace(k, n) }) == nil
+}
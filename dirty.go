@@ -0,0 +1,52 @@
+package lrucache
+
+import "errors"
+
+// WithDirtyTracking marks every Set dirty until a Flush writes it, for
+// caches the caller writes back to a store itself. Values filled by a loader
+// or Warm came from the source of truth and stay clean. Dirty entries are
+// evicted and expire like the others
+func WithDirtyTracking() Option {
+	return func(c *Cache) {
+		c.lru.SetDirtyTracking(true)
+	}
+}
+
+type dirtyEntry struct {
+	k, v    interface{}
+	version uint64
+}
+
+// Flush calls fn for each dirty entry, least recently used first, and marks
+// it clean when fn succeeds. fn runs without the lock, so the cache serves
+// other callers meanwhile: an entry set again while fn writes it stays dirty
+// for the next Flush, and entries set after Flush started are left to it.
+// Flush visits every entry even when fn fails, it returns the errors joined
+func (c *Cache) Flush(fn func(k, v interface{}) error) error {
+	var dirty []dirtyEntry
+	c.lock.RLock()
+	c.lru.RangeDirty(func(k, v interface{}, version uint64) bool {
+		dirty = append(dirty, dirtyEntry{k: k, v: v, version: version})
+		return true
+	})
+	c.lock.RUnlock()
+
+	var errs []error
+	for _, d := range dirty {
+		if err := fn(d.k, d.v); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.lock.Lock()
+		c.lru.MarkClean(d.k, d.version)
+		c.lock.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// IsDirty reports whether k is cached with a write not yet flushed
+func (c *Cache) IsDirty(k interface{}) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.IsDirty(k)
+}
@@ -0,0 +1,87 @@
+package lrucache
+
+import (
+	"sync/atomic"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// DropPolicy tells which event is lost when the event channel is full
+type DropPolicy int
+
+const (
+	// DropNewest discards the event that does not fit
+	DropNewest DropPolicy = iota
+	// DropOldest discards the oldest buffered event to make room
+	DropOldest
+)
+
+// events buffers cache activity for Events
+type events struct {
+	ch      chan simplelru.Event
+	policy  DropPolicy
+	dropped atomic.Uint64
+}
+
+// WithEvents buffers up to buffer events for Events, sending never blocks
+// the cache: once the buffer is full events are dropped as policy says
+func WithEvents(buffer int, policy DropPolicy) Option {
+	return func(c *Cache) {
+		if buffer <= 0 {
+			buffer = 1
+		}
+		c.events = &events{ch: make(chan simplelru.Event, buffer), policy: policy}
+	}
+}
+
+// setEventHook routes the events of the lru to WithEvents and WithOpLog
+func (c *Cache) setEventHook() {
+	if c.events == nil && c.opLog == nil {
+		return
+	}
+	c.lru.SetEventHook(func(ev simplelru.Event) {
+		if c.events != nil {
+			c.events.send(ev)
+		}
+		if c.opLog != nil {
+			c.logOp(ev)
+		}
+	})
+}
+
+// Events returns the channel the events enabled by WithEvents are sent to,
+// nil without WithEvents. The channel is never closed
+func (c *Cache) Events() <-chan simplelru.Event {
+	if c.events == nil {
+		return nil
+	}
+	return c.events.ch
+}
+
+// EventsDropped returns how many events did not fit in the buffer
+func (c *Cache) EventsDropped() uint64 {
+	if c.events == nil {
+		return 0
+	}
+	return c.events.dropped.Load()
+}
+
+func (e *events) send(ev simplelru.Event) {
+	select {
+	case e.ch <- ev:
+		return
+	default:
+	}
+
+	if e.policy == DropOldest {
+		select {
+		case <-e.ch:
+		default:
+		}
+		select {
+		case e.ch <- ev:
+		default:
+		}
+	}
+	e.dropped.Add(1)
+}
@@ -0,0 +1,301 @@
+// Package expirable implements a thread safe map whose entries expire after
+// a ttl, without size limit nor recency list, for caches bounded by their
+// keys that still want the callbacks and stats of the lru caches
+package expirable
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Map is a concurrent map of expiring entries. Reads share the read lock
+// and expired entries read as misses until the janitor or EvictExpired
+// removes them. Callbacks run with the lock held and must not call back
+// into the map
+type Map struct {
+	lock sync.RWMutex
+
+	items map[interface{}]item
+	ttl   time.Duration
+
+	onEvicted       simplelru.EvictCallback
+	onEvictedReason simplelru.EvictReasonCallback
+
+	// hits and misses are counted under the read lock
+	hits, misses atomic.Uint64
+	stats        simplelru.Stats
+
+	clock           clock.Clock
+	janitorInterval time.Duration
+	stop            chan struct{}
+	closeOnce       sync.Once
+}
+
+type item struct {
+	value interface{}
+
+	// expiresAt is zero for entries that do not expire
+	expiresAt time.Time
+}
+
+// Option configures a Map
+type Option func(*Map)
+
+// WithOnEvict sets the callback called for every entry leaving the map but
+// replaced ones
+func WithOnEvict(fn simplelru.EvictCallback) Option {
+	return func(m *Map) {
+		if fn != nil {
+			m.onEvicted = fn
+		}
+	}
+}
+
+// WithEvictReasonCallback sets a callback told why each entry left the map,
+// replaced ones included
+func WithEvictReasonCallback(fn simplelru.EvictReasonCallback) Option {
+	return func(m *Map) {
+		m.onEvictedReason = fn
+	}
+}
+
+// WithClock makes the map and its janitor read time from clk
+func WithClock(clk clock.Clock) Option {
+	return func(m *Map) {
+		m.clock = clk
+	}
+}
+
+// WithJanitor removes expired entries every interval from a goroutine
+// stopped by Close
+func WithJanitor(interval time.Duration) Option {
+	return func(m *Map) {
+		m.janitorInterval = interval
+	}
+}
+
+// New creates a map whose entries expire after ttl, simplelru.NoLimitTTL
+// for entries that only expire with their own ttl
+func New(ttl time.Duration, opts ...Option) *Map {
+	m := &Map{
+		items:     make(map[interface{}]item),
+		ttl:       ttl,
+		onEvicted: func(k, v interface{}) {},
+		clock:     clock.Real,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.startJanitor()
+	return m
+}
+
+func (m *Map) startJanitor() {
+	if m.janitorInterval <= 0 {
+		return
+	}
+
+	ticker := m.clock.NewTicker(m.janitorInterval)
+	m.stop = make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				m.EvictExpired()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the janitor, the map stays usable afterwards
+func (m *Map) Close() {
+	m.closeOnce.Do(func() {
+		if m.stop != nil {
+			close(m.stop)
+		}
+	})
+}
+
+// Set adds or updates an entry expiring after the map ttl
+func (m *Map) Set(k, v interface{}) {
+	m.SetWithTTL(k, v, simplelru.NoLimitTTL)
+}
+
+// SetWithTTL adds or updates an entry expiring after ttl,
+// simplelru.NoLimitTTL falling back to the map ttl
+func (m *Map) SetWithTTL(k, v interface{}, ttl time.Duration) {
+	if k == nil || v == nil {
+		return
+	}
+	if ttl == simplelru.NoLimitTTL {
+		ttl = m.ttl
+	}
+	it := item{value: v}
+	if ttl != simplelru.NoLimitTTL {
+		it.expiresAt = m.clock.Now().Add(ttl)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.stats.Sets++
+	old, ok := m.items[k]
+	m.items[k] = it
+	if ok && m.onEvictedReason != nil {
+		m.onEvictedReason(k, old.value, simplelru.EvictReasonReplaced)
+	}
+}
+
+// Get returns the value of k unless it expired
+func (m *Map) Get(k interface{}) (v interface{}, ok bool) {
+	m.lock.RLock()
+	it, ok := m.items[k]
+	m.lock.RUnlock()
+
+	if !ok || m.expired(it) {
+		m.misses.Add(1)
+		return nil, false
+	}
+	m.hits.Add(1)
+	return it.value, true
+}
+
+// GetWithTTL works like Get and also returns the ttl left,
+// simplelru.NoLimitTTL if the entry does not expire
+func (m *Map) GetWithTTL(k interface{}) (v interface{}, ttl time.Duration, ok bool) {
+	m.lock.RLock()
+	it, ok := m.items[k]
+	m.lock.RUnlock()
+
+	if !ok || m.expired(it) {
+		m.misses.Add(1)
+		return nil, 0, false
+	}
+	m.hits.Add(1)
+	if it.expiresAt.IsZero() {
+		return it.value, simplelru.NoLimitTTL, true
+	}
+	return it.value, it.expiresAt.Sub(m.clock.Now()), true
+}
+
+// Contains reports whether k is live, without counting a hit or miss
+func (m *Map) Contains(k interface{}) bool {
+	m.lock.RLock()
+	it, ok := m.items[k]
+	m.lock.RUnlock()
+	return ok && !m.expired(it)
+}
+
+func (m *Map) Remove(k interface{}) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	it, ok := m.items[k]
+	if ok {
+		m.removeItem(k, it, simplelru.EvictReasonRemoved)
+	}
+	return ok
+}
+
+// Len returns the number of entries, expired ones not yet removed included
+func (m *Map) Len() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return len(m.items)
+}
+
+// Keys returns the live keys in no particular order
+func (m *Map) Keys() []interface{} {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	keys := make([]interface{}, 0, len(m.items))
+	for k, it := range m.items {
+		if !m.expired(it) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Range calls fn for each live entry in no particular order until fn
+// returns false, holding the read lock
+func (m *Map) Range(fn func(k, v interface{}) bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	for k, it := range m.items {
+		if !m.expired(it) && !fn(k, it.value) {
+			return
+		}
+	}
+}
+
+// EvictExpired removes the expired entries, it returns how many were removed
+func (m *Map) EvictExpired() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	n := 0
+	for k, it := range m.items {
+		if m.expired(it) {
+			m.removeItem(k, it, simplelru.EvictReasonExpired)
+			n++
+		}
+	}
+	return n
+}
+
+func (m *Map) Purge() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for k, it := range m.items {
+		m.removeItem(k, it, simplelru.EvictReasonPurged)
+	}
+}
+
+// Stats returns the counters of the map, Evictions stays 0 as nothing is
+// evicted for room
+func (m *Map) Stats() simplelru.Stats {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	st := m.stats
+	st.Hits, st.Misses = m.hits.Load(), m.misses.Load()
+	st.Len = len(m.items)
+	return st
+}
+
+// ResetStats zeroes the counters of the map
+func (m *Map) ResetStats() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.stats = simplelru.Stats{}
+	m.hits.Store(0)
+	m.misses.Store(0)
+}
+
+func (m *Map) expired(it item) bool {
+	return !it.expiresAt.IsZero() && m.clock.Now().After(it.expiresAt)
+}
+
+func (m *Map) removeItem(k interface{}, it item, reason simplelru.EvictReason) {
+	delete(m.items, k)
+	if reason == simplelru.EvictReasonExpired {
+		m.stats.Expirations++
+	}
+	m.onEvicted(k, it.value)
+	if m.onEvictedReason != nil {
+		m.onEvictedReason(k, it.value, reason)
+	}
+}
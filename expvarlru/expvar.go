@@ -0,0 +1,56 @@
+// Package expvarlru exposes cache statistics through expvar, so they show up
+// under /debug/vars
+package expvarlru
+
+import (
+	"expvar"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// StatsSource is implemented by caches that report simplelru.Stats, such as
+// lrucache.Cache and shardedlru.Cache
+type StatsSource interface {
+	Stats() simplelru.Stats
+}
+
+type stats struct {
+	Size         int     `json:"size"`
+	Cost         int64   `json:"cost"`
+	Bytes        int64   `json:"bytes"`
+	Hits         uint64  `json:"hits"`
+	Misses       uint64  `json:"misses"`
+	HitRatio     float64 `json:"hit_ratio"`
+	Sets         uint64  `json:"sets"`
+	Evictions    uint64  `json:"evictions"`
+	Expirations  uint64  `json:"expirations"`
+	GhostHits    uint64  `json:"ghost_hits"`
+	NegativeHits uint64  `json:"negative_hits"`
+}
+
+// Func returns an expvar.Func reading the stats of src each time it is
+// shown, to add to an expvar.Map of the caller
+func Func(src StatsSource) expvar.Func {
+	return func() interface{} {
+		st := src.Stats()
+		return stats{
+			Size:         st.Len,
+			Cost:         st.Cost,
+			Bytes:        st.Bytes,
+			Hits:         st.Hits,
+			Misses:       st.Misses,
+			HitRatio:     st.HitRatio(),
+			Sets:         st.Sets,
+			Evictions:    st.Evictions,
+			Expirations:  st.Expirations,
+			GhostHits:    st.GhostHits,
+			NegativeHits: st.NegativeHits,
+		}
+	}
+}
+
+// Publish publishes the stats of src under name, like expvar.Publish it
+// panics if name is already taken
+func Publish(name string, src StatsSource) {
+	expvar.Publish(name, Func(src))
+}
@@ -0,0 +1,39 @@
+package lrucache
+
+// sweepBatch is how many old entries a sweep removes per lock hold
+const sweepBatch = 1024
+
+// NewGeneration invalidates every entry at once instead of removing them
+// under the lock like Purge, see simplelru.LRU.NewGeneration. A goroutine
+// then removes the invalidated entries a batch at a time, calling the
+// eviction callback for each, so other callers get the lock in between, it
+// is stopped by Close. It returns how many entries were invalidated, 0 once
+// the cache is closed
+func (c *Cache) NewGeneration() int {
+	c.lock.Lock()
+	if c.closed.Load() {
+		c.lock.Unlock()
+		return 0
+	}
+	n := c.lru.NewGeneration()
+	c.lock.Unlock()
+
+	if c.sweeping.CompareAndSwap(false, true) {
+		go c.sweepGenerations()
+	}
+	return n
+}
+
+func (c *Cache) sweepGenerations() {
+	for {
+		c.lock.Lock()
+		n := c.lru.EvictOldGenerations(sweepBatch)
+		if n < sweepBatch || c.closed.Load() {
+			// under the lock, so a later NewGeneration starts a new sweep
+			c.sweeping.Store(false)
+			c.lock.Unlock()
+			return
+		}
+		c.lock.Unlock()
+	}
+}
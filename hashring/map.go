@@ -0,0 +1,123 @@
+// Package hashring spreads keys over nodes by consistent hashing, so adding
+// or removing a node only moves the keys of that node
+package hashring
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas is the number of virtual nodes of a node on the ring
+const DefaultReplicas = 100
+
+// Map places every node at replicas points of a ring of 64 bit hashes, a key
+// belongs to the first node point at or after its own hash. The hash is
+// fixed, so processes given the same nodes agree on the owner of a key. It
+// is not safe for concurrent use
+type Map struct {
+	replicas int
+	hashes   []uint64
+	owners   map[uint64]string
+	nodes    map[string]struct{}
+}
+
+// NewMap creates an empty map, replicas <= 0 means DefaultReplicas
+func NewMap(replicas int) *Map {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	return &Map{
+		replicas: replicas,
+		owners:   make(map[uint64]string),
+		nodes:    make(map[string]struct{}),
+	}
+}
+
+// Add puts nodes on the ring, adding a node already there does nothing
+func (m *Map) Add(nodes ...string) {
+	for _, node := range nodes {
+		if _, ok := m.nodes[node]; ok {
+			continue
+		}
+		m.nodes[node] = struct{}{}
+		for i := 0; i < m.replicas; i++ {
+			h := hash(strconv.Itoa(i) + "#" + node)
+			// a point two nodes hash to goes to the smaller name, whatever
+			// the order they were added in
+			if owner, ok := m.owners[h]; ok {
+				if owner < node {
+					continue
+				}
+			} else {
+				m.hashes = append(m.hashes, h)
+			}
+			m.owners[h] = node
+		}
+	}
+	sort.Slice(m.hashes, func(i, j int) bool { return m.hashes[i] < m.hashes[j] })
+}
+
+// Remove takes nodes off the ring, their keys move to the following nodes
+func (m *Map) Remove(nodes ...string) {
+	removed := false
+	for _, node := range nodes {
+		if _, ok := m.nodes[node]; ok {
+			delete(m.nodes, node)
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+
+	// rebuilt from the remaining nodes so points they shared with a removed
+	// node go back to them
+	rest := m.Nodes()
+	m.hashes = m.hashes[:0]
+	m.owners = make(map[uint64]string, m.replicas*len(rest))
+	m.nodes = make(map[string]struct{}, len(rest))
+	m.Add(rest...)
+}
+
+// Get returns the node owning key, "" for an empty map
+func (m *Map) Get(key string) string {
+	if len(m.hashes) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(m.hashes), func(i int) bool { return m.hashes[i] >= h })
+	if i == len(m.hashes) {
+		i = 0
+	}
+	return m.owners[m.hashes[i]]
+}
+
+// Nodes returns the nodes on the ring sorted by name
+func (m *Map) Nodes() []string {
+	nodes := make([]string, 0, len(m.nodes))
+	for node := range m.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Len returns the number of nodes
+func (m *Map) Len() int {
+	return len(m.nodes)
+}
+
+// hash is fnv-1a followed by the murmur3 finalizer, which spreads keys that
+// differ in one byte, such as the points of a node, over the whole ring
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
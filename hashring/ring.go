@@ -0,0 +1,243 @@
+package hashring
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Ring routes every key to one of several named caches, local shards or
+// adapters of remote ones, by consistent hashing. It is safe for concurrent
+// use as long as the caches are
+type Ring struct {
+	lock sync.RWMutex
+
+	m      *Map
+	caches map[string]simplelru.LRUCache
+
+	keyString func(k interface{}) string
+}
+
+// Option configures a Ring
+type Option func(*Ring)
+
+// WithReplicas replaces DefaultReplicas
+func WithReplicas(replicas int) Option {
+	return func(r *Ring) {
+		r.m = NewMap(replicas)
+	}
+}
+
+// WithKeyString sets how keys are turned into the strings that are hashed,
+// the default uses string keys as is and fmt.Sprint for other keys. Keys
+// routed to remote caches need a form every process agrees on
+func WithKeyString(fn func(k interface{}) string) Option {
+	return func(r *Ring) {
+		r.keyString = fn
+	}
+}
+
+func New(opts ...Option) *Ring {
+	r := &Ring{
+		m:         NewMap(DefaultReplicas),
+		caches:    make(map[string]simplelru.LRUCache),
+		keyString: defaultKeyString,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func defaultKeyString(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprint(k)
+}
+
+// AddNode puts c on the ring as name, replacing the cache of a node of that
+// name. About 1/n of the keys move to it and are misses until set again
+func (r *Ring) AddNode(name string, c simplelru.LRUCache) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.m.Add(name)
+	r.caches[name] = c
+}
+
+// RemoveNode takes the node off the ring and returns its cache, which is
+// left as is. Its keys move to the other nodes
+func (r *Ring) RemoveNode(name string) (simplelru.LRUCache, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c, ok := r.caches[name]
+	if ok {
+		r.m.Remove(name)
+		delete(r.caches, name)
+	}
+	return c, ok
+}
+
+// Nodes returns the node names sorted
+func (r *Ring) Nodes() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.m.Nodes()
+}
+
+// Node returns the name and cache of the node owning k, ok is false for a
+// ring without nodes
+func (r *Ring) Node(k interface{}) (name string, c simplelru.LRUCache, ok bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	name = r.m.Get(r.keyString(k))
+	c, ok = r.caches[name]
+	return name, c, ok
+}
+
+// each calls fn for every cache in node name order until it returns false
+func (r *Ring) each(fn func(c simplelru.LRUCache) bool) {
+	r.lock.RLock()
+	caches := make([]simplelru.LRUCache, 0, len(r.caches))
+	for _, name := range r.m.Nodes() {
+		caches = append(caches, r.caches[name])
+	}
+	r.lock.RUnlock()
+
+	for _, c := range caches {
+		if !fn(c) {
+			return
+		}
+	}
+}
+
+// Set does nothing on a ring without nodes
+func (r *Ring) Set(k, v interface{}) {
+	if _, c, ok := r.Node(k); ok {
+		c.Set(k, v)
+	}
+}
+
+func (r *Ring) Get(k interface{}) (v interface{}, ok bool) {
+	if _, c, ok := r.Node(k); ok {
+		return c.Get(k)
+	}
+	return nil, false
+}
+
+func (r *Ring) Contains(k interface{}) bool {
+	if _, c, ok := r.Node(k); ok {
+		return c.Contains(k)
+	}
+	return false
+}
+
+// Peek get a cache without move it to head
+func (r *Ring) Peek(k interface{}) (v interface{}, ok bool) {
+	if _, c, ok := r.Node(k); ok {
+		return c.Peek(k)
+	}
+	return nil, false
+}
+
+func (r *Ring) Remove(k interface{}) bool {
+	if _, c, ok := r.Node(k); ok {
+		return c.Remove(k)
+	}
+	return false
+}
+
+// RemoveOldest removes the oldest entry of the first node that has one,
+// recency is tracked per node
+func (r *Ring) RemoveOldest() (k, v interface{}, ok bool) {
+	r.each(func(c simplelru.LRUCache) bool {
+		k, v, ok = c.RemoveOldest()
+		return !ok
+	})
+	return k, v, ok
+}
+
+// RemoveOldestN removes up to n of the oldest entries node by node, it
+// returns how many were removed
+func (r *Ring) RemoveOldestN(n int) int {
+	removed := 0
+	r.each(func(c simplelru.LRUCache) bool {
+		removed += c.RemoveOldestN(n - removed)
+		return removed < n
+	})
+	return removed
+}
+
+// Len returns the number of entries summed over all nodes
+func (r *Ring) Len() int {
+	n := 0
+	r.each(func(c simplelru.LRUCache) bool {
+		n += c.Len()
+		return true
+	})
+	return n
+}
+
+// IsFull reports whether every node is full
+func (r *Ring) IsFull() bool {
+	full, some := true, false
+	r.each(func(c simplelru.LRUCache) bool {
+		some = true
+		full = c.IsFull()
+		return full
+	})
+	return full && some
+}
+
+// Cap returns the size limit summed over all nodes
+func (r *Ring) Cap() int {
+	n := 0
+	r.each(func(c simplelru.LRUCache) bool {
+		n += c.Cap()
+		return true
+	})
+	return n
+}
+
+// Keys returns the keys node by node in node name order
+func (r *Ring) Keys() []interface{} {
+	keys := make([]interface{}, 0)
+	r.each(func(c simplelru.LRUCache) bool {
+		keys = append(keys, c.Keys()...)
+		return true
+	})
+	return keys
+}
+
+func (r *Ring) Purge() {
+	r.each(func(c simplelru.LRUCache) bool {
+		c.Purge()
+		return true
+	})
+}
+
+// Resize splits size evenly over the nodes rounded up, it returns the number
+// of entries evicted from all nodes
+func (r *Ring) Resize(size int) int {
+	r.lock.RLock()
+	nodes := len(r.caches)
+	r.lock.RUnlock()
+	if nodes == 0 {
+		return 0
+	}
+
+	per := simplelru.NoLimitSize
+	if size > simplelru.NoLimitSize {
+		per = (size + nodes - 1) / nodes
+	}
+
+	evicted := 0
+	r.each(func(c simplelru.LRUCache) bool {
+		evicted += c.Resize(per)
+		return true
+	})
+	return evicted
+}
+
+var _ simplelru.LRUCache = (*Ring)(nil)
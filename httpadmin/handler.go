@@ -0,0 +1,257 @@
+// Package httpadmin serves an http.Handler to inspect and manage a
+// lrucache.Cache of a running service
+package httpadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jingke11235/lrucache"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 10000
+)
+
+// Option configures the handler
+type Option func(*handler)
+
+// WithAuth sets a hook every request must pass, requests it rejects get 401
+// Unauthorized. Without it every request is served
+func WithAuth(auth func(r *http.Request) bool) Option {
+	return func(h *handler) {
+		h.auth = auth
+	}
+}
+
+// WithKeyParser sets how the key in a request path is turned into a cache
+// key, the default uses the path segment as a string key. A parse error
+// answers 400 Bad Request
+func WithKeyParser(parse func(s string) (interface{}, error)) Option {
+	return func(h *handler) {
+		h.parseKey = parse
+	}
+}
+
+// WithReadOnly disables the endpoints that change the cache
+func WithReadOnly() Option {
+	return func(h *handler) {
+		h.readOnly = true
+	}
+}
+
+type handler struct {
+	cache *lrucache.Cache
+	mux   *http.ServeMux
+
+	auth     func(r *http.Request) bool
+	parseKey func(s string) (interface{}, error)
+	readOnly bool
+}
+
+// New returns a handler for cache answering with JSON:
+//
+//	GET    /stats                    the counters, length and cost
+//	GET    /keys?offset&limit&order  a page of live keys, oldest first or
+//	                                 with order=newest newest first
+//	GET    /keys/{key}               the value of key, counted as a Get
+//	GET    /keys/{key}?peek=1        the value of key without touching it
+//	DELETE /keys/{key}               removes key
+//	POST   /purge                    removes every entry
+//	POST   /evict-expired            removes the expired entries
+//
+// Mount it under a prefix with http.StripPrefix
+func New(cache *lrucache.Cache, opts ...Option) http.Handler {
+	h := &handler{
+		cache:    cache,
+		mux:      http.NewServeMux(),
+		parseKey: func(s string) (interface{}, error) { return s, nil },
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("GET /stats", h.stats)
+	h.mux.HandleFunc("GET /keys", h.keys)
+	h.mux.HandleFunc("GET /keys/{key...}", h.get)
+	if !h.readOnly {
+		h.mux.HandleFunc("DELETE /keys/{key...}", h.remove)
+		h.mux.HandleFunc("POST /purge", h.purge)
+		h.mux.HandleFunc("POST /evict-expired", h.evictExpired)
+	}
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.auth != nil && !h.auth(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+type statsResponse struct {
+	Hits         uint64  `json:"hits"`
+	Misses       uint64  `json:"misses"`
+	HitRatio     float64 `json:"hit_ratio"`
+	Sets         uint64  `json:"sets"`
+	Evictions    uint64  `json:"evictions"`
+	Expirations  uint64  `json:"expirations"`
+	GhostHits    uint64  `json:"ghost_hits"`
+	NegativeHits uint64  `json:"negative_hits"`
+	Len          int     `json:"len"`
+	Cap          int     `json:"cap"`
+	Cost         int64   `json:"cost"`
+	Bytes        int64   `json:"bytes"`
+}
+
+func (h *handler) stats(w http.ResponseWriter, r *http.Request) {
+	st := h.cache.Stats()
+	writeJSON(w, http.StatusOK, statsResponse{
+		Hits:         st.Hits,
+		Misses:       st.Misses,
+		HitRatio:     st.HitRatio(),
+		Sets:         st.Sets,
+		Evictions:    st.Evictions,
+		Expirations:  st.Expirations,
+		GhostHits:    st.GhostHits,
+		NegativeHits: st.NegativeHits,
+		Len:          st.Len,
+		Cap:          h.cache.Cap(),
+		Cost:         st.Cost,
+		Bytes:        st.Bytes,
+	})
+}
+
+type keysResponse struct {
+	Total  int           `json:"total"`
+	Offset int           `json:"offset"`
+	Keys   []interface{} `json:"keys"`
+}
+
+func (h *handler) keys(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "invalid offset")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultLimit)
+	if err != nil || limit <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	limit = min(limit, maxLimit)
+	order := simplelru.OldestFirst
+	switch r.URL.Query().Get("order") {
+	case "", "oldest":
+	case "newest":
+		order = simplelru.NewestFirst
+	default:
+		writeError(w, http.StatusBadRequest, "invalid order")
+		return
+	}
+
+	page := h.cache.KeysN(offset, limit, order)
+	resp := keysResponse{Total: h.cache.LenValid(), Offset: offset, Keys: make([]interface{}, len(page))}
+	for i, k := range page {
+		resp.Keys[i] = jsonable(k)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type valueResponse struct {
+	Key   interface{} `json:"key"`
+	Value interface{} `json:"value"`
+
+	// TTLMillis is left out for entries that do not expire
+	TTLMillis int64 `json:"ttl_ms,omitempty"`
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request) {
+	k, ok := h.key(w, r)
+	if !ok {
+		return
+	}
+
+	var v interface{}
+	var ttl time.Duration
+	if peek, _ := strconv.ParseBool(r.URL.Query().Get("peek")); peek {
+		v, ttl, ok = h.cache.PeekWithTTL(k)
+	} else {
+		v, ttl, ok = h.cache.GetWithTTL(k)
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, valueResponse{Key: jsonable(k), Value: jsonable(v), TTLMillis: ttl.Milliseconds()})
+}
+
+func (h *handler) remove(w http.ResponseWriter, r *http.Request) {
+	k, ok := h.key(w, r)
+	if !ok {
+		return
+	}
+	if !h.cache.Remove(k) {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) purge(w http.ResponseWriter, r *http.Request) {
+	n := h.cache.Len()
+	h.cache.Purge()
+	writeJSON(w, http.StatusOK, map[string]int{"removed": n})
+}
+
+func (h *handler) evictExpired(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]int{"removed": h.cache.EvictExpired()})
+}
+
+// key parses the key of the request path, it answers the request itself if
+// the key is invalid
+func (h *handler) key(w http.ResponseWriter, r *http.Request) (interface{}, bool) {
+	k, err := h.parseKey(r.PathValue("key"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid key: "+err.Error())
+		return nil, false
+	}
+	return k, true
+}
+
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// jsonable returns v if it encodes to JSON and its fmt.Sprint form if not,
+// so one odd value does not fail the whole response
+func jsonable(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,111 @@
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+func (c *Cache) startJanitor() {
+	if c.janitorInterval <= 0 {
+		return
+	}
+
+	// created before the goroutine so a fake clock advanced right after New
+	// already sees it
+	ticker := c.clock.NewTicker(c.janitorTick())
+	c.stop = make(chan struct{})
+	go func() {
+		defer func() { ticker.Stop() }()
+
+		for {
+			select {
+			case <-ticker.C():
+				c.EvictExpired()
+				if c.janitorJitter > 0 {
+					ticker.Stop()
+					ticker = c.clock.NewTicker(c.janitorTick())
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// janitorTick returns the wait before the next sweep, the interval moved by
+// a random amount within the jitter
+func (c *Cache) janitorTick() time.Duration {
+	jitter := c.janitorJitter
+	if jitter <= 0 {
+		return c.janitorInterval
+	}
+	if jitter >= c.janitorInterval {
+		jitter = c.janitorInterval / 2
+	}
+	return c.janitorInterval + time.Duration((rand.Float64()*2-1)*float64(jitter))
+}
+
+// ErrClosed is returned by the methods of a closed cache, see Close
+var ErrClosed = errors.New("lrucache: cache closed")
+
+// Close shuts the cache down: it stops the janitor, the size controller,
+// the memory pressure watcher, the stats window, the thrash detector and the
+// generation sweep, waits for the background loads of stale revalidation and
+// refresh-ahead, for the queued eviction callbacks and writes the queued
+// writes of a write-behind cache, then writes a snapshot if
+// WithSnapshotOnClose asked for one. It returns ctx.Err() if ctx is done first, the draining goes on
+// in the background and no snapshot is written. Later calls return the
+// result of the first one.
+//
+// Afterwards the cache refuses changes: Put, Delete, Restore and Warm return
+// ErrClosed, Set, Remove, Touch, Pin, Pop, Resize, SetTTL, EvictExpired and
+// the other mutators do nothing and report that nothing changed, and misses
+// are no longer loaded, GetContext and GetOrLoad return ErrClosed for them.
+// TakeOrCreate only calls create. Reads, Snapshot and Purge keep working on
+// the entries left
+func (c *Cache) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.shutdown(ctx)
+	})
+	return c.closeErr
+}
+
+func (c *Cache) shutdown(ctx context.Context) error {
+	c.lock.Lock()
+	c.closed.Store(true)
+	c.lock.Unlock()
+	if c.budget != nil {
+		c.budget.remove(c)
+	}
+	if c.stop != nil {
+		close(c.stop)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+
+		c.loads.wait()
+		if c.evictPool != nil {
+			c.lock.Lock()
+			c.evictPool.close()
+			c.lock.Unlock()
+			c.evictPool.drain()
+		}
+		if c.behind != nil {
+			c.behind.close()
+		}
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if c.snapshotOnClose != nil {
+		return c.Snapshot(c.snapshotOnClose)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+func TestJanitorTick(t *testing.T) {
+	tests := []struct {
+		name             string
+		interval, jitter time.Duration
+		// band is the spread expected around the interval
+		band time.Duration
+	}{
+		{name: "no jitter", interval: 10 * time.Second, band: 0},
+		{name: "jitter", interval: 10 * time.Second, jitter: 2 * time.Second, band: 2 * time.Second},
+		{name: "jitter cut to half the interval", interval: 10 * time.Second, jitter: time.Minute, band: 5 * time.Second},
+	}
+
+	const samples = 10000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cache{janitorInterval: tt.interval, janitorJitter: tt.jitter}
+
+			var sum time.Duration
+			lo, hi := tt.interval, tt.interval
+			for i := 0; i < samples; i++ {
+				d := c.janitorTick()
+				if d < tt.interval-tt.band || d > tt.interval+tt.band {
+					t.Fatalf("tick %v outside %v ± %v", d, tt.interval, tt.band)
+				}
+				sum += d
+				lo, hi = min(lo, d), max(hi, d)
+			}
+
+			// the mean of a uniform spread is known to within a few
+			// hundredths of the band over this many samples
+			if mean := sum / samples; mean < tt.interval-tt.band/20 || mean > tt.interval+tt.band/20 {
+				t.Errorf("mean tick %v, want about %v", mean, tt.interval)
+			}
+			if tt.band > 0 && hi-lo < tt.band {
+				t.Errorf("ticks spread over %v only, want most of ± %v", hi-lo, tt.band)
+			}
+		})
+	}
+}
+
+func TestJanitorJitterSweeps(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c, err := New(WithSize(10), WithClock(clk), WithJanitor(10*time.Second), WithJanitorJitter(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(t.Context())
+	c.SetWithTTL("a", 1, time.Millisecond)
+
+	// the first sweep is due between 8s and 12s
+	clk.Advance(8*time.Second - time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if c.Len() != 1 {
+		t.Fatal("the janitor swept before the jitter band")
+	}
+	clk.Advance(4 * time.Second)
+	if !waitFor(t, func() bool { return c.Len() == 0 }) {
+		t.Fatal("the janitor did not sweep within the jitter band")
+	}
+}
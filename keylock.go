@@ -0,0 +1,28 @@
+package lrucache
+
+import "context"
+
+// KeyLock locks k against the loads of GetOrLoad, GetContext and Warm and
+// against other KeyLock and Do holders of k, waiting for a load of k in
+// flight to finish first. Loads of k made while k is locked wait for unlock
+// and then load again, so work done under the lock, such as updating the
+// source of truth and the cache together, is never overwritten by a load
+// that started before it. Holders must not load k themselves, that waits
+// for their own unlock. Other keys and methods are not affected
+func (c *Cache) KeyLock(k interface{}) (unlock func()) {
+	unlock, _ = c.loads.lock(context.Background(), k)
+	return unlock
+}
+
+// KeyLockContext works like KeyLock and gives up waiting with ctx.Err()
+// once ctx is done
+func (c *Cache) KeyLockContext(ctx context.Context, k interface{}) (unlock func(), err error) {
+	return c.loads.lock(ctx, k)
+}
+
+// Do runs fn holding KeyLock(k) and returns its error
+func (c *Cache) Do(k interface{}, fn func() error) error {
+	unlock := c.KeyLock(k)
+	defer unlock()
+	return fn()
+}
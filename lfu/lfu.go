@@ -0,0 +1,272 @@
+// Package lfu implements a thread safe least frequently used cache with
+// O(1) operations
+package lfu
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// LFU keeps entries in buckets of equal access count, buckets are ordered by
+// count. Eviction removes the least recently used entry of the lowest bucket
+type LFU struct {
+	lock sync.Mutex
+
+	size int
+
+	cache map[interface{}]*list.Element
+
+	// freqList holds *bucket, lowest count at the front
+	freqList *list.List
+
+	onEvicted simplelru.EvictCallback
+}
+
+type bucket struct {
+	freq int
+
+	// entries holds *entry, most recently used at the front
+	entries *list.List
+}
+
+type entry struct {
+	key   interface{}
+	value interface{}
+
+	bucket *list.Element
+}
+
+func New(size int, onEvict simplelru.EvictCallback) (*LFU, error) {
+	if size <= 0 {
+		return nil, errors.New("lfu: size must be positive")
+	}
+	if onEvict == nil {
+		onEvict = func(k, v interface{}) {}
+	}
+
+	return &LFU{
+		size:      size,
+		cache:     make(map[interface{}]*list.Element),
+		freqList:  list.New(),
+		onEvicted: onEvict,
+	}, nil
+}
+
+func (c *LFU) Set(k, v interface{}) {
+	if k == nil || v == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if item, ok := c.cache[k]; ok {
+		item.Value.(*entry).value = v
+		c.increment(item)
+		return
+	}
+
+	if len(c.cache) >= c.size {
+		c.removeOldest()
+	}
+
+	front := c.freqList.Front()
+	if front == nil || front.Value.(*bucket).freq != 1 {
+		front = c.freqList.PushFront(&bucket{freq: 1, entries: list.New()})
+	}
+	e := &entry{key: k, value: v, bucket: front}
+	c.cache[k] = front.Value.(*bucket).entries.PushFront(e)
+}
+
+// increment moves item to the bucket of the next count, creating it if
+// needed and dropping the old one if it becomes empty
+func (c *LFU) increment(item *list.Element) {
+	e := item.Value.(*entry)
+	cur := e.bucket
+	freq := cur.Value.(*bucket).freq
+
+	next := cur.Next()
+	if next == nil || next.Value.(*bucket).freq != freq+1 {
+		next = c.freqList.InsertAfter(&bucket{freq: freq + 1, entries: list.New()}, cur)
+	}
+
+	c.unlink(item)
+	e.bucket = next
+	c.cache[e.key] = next.Value.(*bucket).entries.PushFront(e)
+}
+
+// unlink removes item from its bucket, dropping the bucket if empty
+func (c *LFU) unlink(item *list.Element) {
+	e := item.Value.(*entry)
+	b := e.bucket.Value.(*bucket)
+
+	b.entries.Remove(item)
+	if b.entries.Len() == 0 {
+		c.freqList.Remove(e.bucket)
+	}
+}
+
+func (c *LFU) Get(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if item, ok := c.cache[k]; ok {
+		c.increment(item)
+		return item.Value.(*entry).value, true
+	}
+	return nil, false
+}
+
+func (c *LFU) Contains(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, ok := c.cache[k]
+	return ok
+}
+
+// Peek get a cache without counting an access
+func (c *LFU) Peek(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if item, ok := c.cache[k]; ok {
+		return item.Value.(*entry).value, true
+	}
+	return nil, false
+}
+
+// Frequency returns the access count of k, 0 if absent
+func (c *LFU) Frequency(k interface{}) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if item, ok := c.cache[k]; ok {
+		return item.Value.(*entry).bucket.Value.(*bucket).freq
+	}
+	return 0
+}
+
+func (c *LFU) Remove(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if item, ok := c.cache[k]; ok {
+		c.removeElement(item)
+		return true
+	}
+	return false
+}
+
+// RemoveOldest removes the least recently used of the least frequently used
+// entries
+func (c *LFU) RemoveOldest() (k, v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.removeOldest()
+}
+
+func (c *LFU) removeOldest() (k, v interface{}, ok bool) {
+	front := c.freqList.Front()
+	if front == nil {
+		return nil, nil, false
+	}
+
+	item := front.Value.(*bucket).entries.Back()
+	e := item.Value.(*entry)
+	c.removeElement(item)
+	return e.key, e.value, true
+}
+
+// RemoveOldestN removes up to n entries in RemoveOldest order
+func (c *LFU) RemoveOldestN(n int) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	removed := 0
+	for ; removed < n; removed++ {
+		if _, _, ok := c.removeOldest(); !ok {
+			break
+		}
+	}
+	return removed
+}
+
+func (c *LFU) removeElement(item *list.Element) {
+	e := item.Value.(*entry)
+
+	c.unlink(item)
+	delete(c.cache, e.key)
+
+	c.onEvicted(e.key, e.value)
+}
+
+func (c *LFU) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.cache)
+}
+
+// IsFull reports whether the cache holds as many entries as its size allows
+func (c *LFU) IsFull() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.cache) >= c.size
+}
+
+// Cap returns the size limit of the cache
+func (c *LFU) Cap() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.size
+}
+
+// Keys returns keys in eviction order, from least to most frequently used
+// and from oldest to newest within the same count
+func (c *LFU) Keys() []interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	keys := make([]interface{}, 0, len(c.cache))
+	for b := c.freqList.Front(); b != nil; b = b.Next() {
+		for item := b.Value.(*bucket).entries.Back(); item != nil; item = item.Prev() {
+			keys = append(keys, item.Value.(*entry).key)
+		}
+	}
+	return keys
+}
+
+func (c *LFU) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for k, item := range c.cache {
+		c.onEvicted(k, item.Value.(*entry).value)
+		delete(c.cache, k)
+	}
+	c.freqList.Init()
+}
+
+// Resize changes the size limit, it returns how many entries were evicted.
+// Non positive sizes are ignored
+func (c *LFU) Resize(size int) int {
+	if size <= 0 {
+		return 0
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	evicted := 0
+	for len(c.cache) > size {
+		c.removeOldest()
+		evicted++
+	}
+	c.size = size
+	return evicted
+}
+
+var _ simplelru.LRUCache = (*LFU)(nil)
@@ -0,0 +1,143 @@
+package lrucache
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by GetContext for a miss when no loader is set
+var ErrNotFound = errors.New("lrucache: key not found")
+
+// LoaderFunc loads the value of a key missing from the cache
+type LoaderFunc func(k interface{}) (interface{}, error)
+
+// Loader loads the value of a key missing from the cache, Load must give up
+// when ctx is done
+type Loader interface {
+	Load(ctx context.Context, k interface{}) (interface{}, error)
+}
+
+// ContextLoaderFunc adapts a function to the Loader interface
+type ContextLoaderFunc func(ctx context.Context, k interface{}) (interface{}, error)
+
+func (f ContextLoaderFunc) Load(ctx context.Context, k interface{}) (interface{}, error) {
+	return f(ctx, k)
+}
+
+// WithLoader makes the cache read-through: Get and GetContext fill misses by
+// calling l, once per key for concurrent misses
+func WithLoader(l Loader) Option {
+	return func(c *Cache) {
+		c.loader = l
+	}
+}
+
+// WithMaxConcurrentLoads lets at most n loads of distinct keys run at once,
+// for GetOrLoad, GetContext and the background reloads of stale and
+// refresh-ahead entries. Further misses wait for a running load to finish, or
+// return ctx.Err() if their ctx is done first. Concurrent misses on one key
+// still share a single load and take one slot
+func WithMaxConcurrentLoads(n int) Option {
+	return func(c *Cache) {
+		if n > 0 {
+			c.loadSlots = make(chan struct{}, n)
+		}
+	}
+}
+
+// GetOrLoad returns the cached value of k, or loads it with loader and caches
+// it. Concurrent misses on the same key share a single loader call, a failed
+// load is returned to all of them and nothing is cached, except for
+// ErrNotFound with WithNegativeCaching. A negative entry returns ErrNotFound
+func (c *Cache) GetOrLoad(k interface{}, loader LoaderFunc) (interface{}, error) {
+	v, err := c.getOrLoad(context.Background(), k, func(ctx context.Context, k interface{}) (interface{}, error) {
+		return loader(k)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetContext returns the cached value of k, or loads it with the loader set
+// by WithLoader. It returns ErrNotFound on a miss without loader or on a
+// negative entry, and ctx.Err() if ctx is done before the value is loaded.
+// The load is shared with concurrent callers, so it fails for all of them if
+// the ctx of the caller that started it is done
+func (c *Cache) GetContext(ctx context.Context, k interface{}) (interface{}, error) {
+	if c.loader == nil {
+		if v, ok := c.get(k); ok && !IsNegative(v) {
+			return v, nil
+		}
+		return nil, ErrNotFound
+	}
+	v, err := c.getOrLoad(ctx, k, c.loader.Load)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// getOrLoad returns Negative with ErrNotFound for a negative entry, cached or
+// just loaded
+func (c *Cache) getOrLoad(ctx context.Context, k interface{}, load ContextLoaderFunc) (interface{}, error) {
+	if v, ok := c.getOrRevalidate(k, load); ok {
+		if c.refreshBelow > 0 {
+			c.refreshAhead(k, load)
+		}
+		if IsNegative(v) {
+			return v, ErrNotFound
+		}
+		return v, nil
+	}
+	if c.closed.Load() {
+		return nil, ErrClosed
+	}
+
+	v, err, _ := c.loads.doContext(ctx, k, func() (interface{}, error) {
+		// the previous flight for k may have finished since our miss
+		if v, ok := c.Peek(k); ok {
+			if IsNegative(v) {
+				return v, ErrNotFound
+			}
+			return v, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		v, err := c.loadRetry(ctx, k, load)
+		if err != nil {
+			return c.loadNegative(k, err)
+		}
+		c.fill(k, v)
+		return v, nil
+	})
+	return v, err
+}
+
+// fill caches a loaded value, it came from the source of truth so it is not
+// written back to a store
+func (c *Cache) fill(k, v interface{}) {
+	c.lock.Lock()
+	if !c.closed.Load() {
+		c.lru.Set(k, v)
+		c.lru.MarkClean(k, 0)
+		c.chargeBudget()
+	}
+	c.lock.Unlock()
+}
+
+// loadLimited calls load for k once a slot is free, see
+// WithMaxConcurrentLoads
+func (c *Cache) loadLimited(ctx context.Context, k interface{}, load ContextLoaderFunc) (interface{}, error) {
+	if c.loadSlots != nil {
+		select {
+		case c.loadSlots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() { <-c.loadSlots }()
+	}
+	return load(ctx, k)
+}
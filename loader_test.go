@@ -0,0 +1,99 @@
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrentLoads(t *testing.T) {
+	tests := []struct {
+		name string
+		max  int
+		// keys are loaded by callers goroutines each
+		keys, callers int
+	}{
+		{name: "one at a time", max: 1, keys: 20, callers: 1},
+		{name: "a few at a time", max: 4, keys: 50, callers: 1},
+		{name: "shared loads take one slot", max: 2, keys: 10, callers: 5},
+		{name: "more slots than keys", max: 100, keys: 10, callers: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(WithSize(1000), WithMaxConcurrentLoads(tt.max))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var running, peak, calls atomic.Int32
+			load := func(k interface{}) (interface{}, error) {
+				calls.Add(1)
+				n := running.Add(1)
+				for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+				return k, nil
+			}
+
+			var wg sync.WaitGroup
+			for k := 0; k < tt.keys; k++ {
+				for i := 0; i < tt.callers; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						if v, err := c.GetOrLoad(k, load); err != nil || v != k {
+							t.Errorf("GetOrLoad(%d) = %v, %v", k, v, err)
+						}
+					}()
+				}
+			}
+			wg.Wait()
+
+			if p := peak.Load(); p > int32(tt.max) {
+				t.Errorf("%d loads ran at once, want at most %d", p, tt.max)
+			}
+			if n := calls.Load(); n > int32(tt.keys*tt.callers) || n < int32(tt.keys) {
+				t.Errorf("loader called %d times for %d keys", n, tt.keys)
+			}
+		})
+	}
+}
+
+func TestMaxConcurrentLoadsContext(t *testing.T) {
+	release := make(chan struct{})
+	c, err := New(WithSize(10), WithMaxConcurrentLoads(1),
+		WithLoader(ContextLoaderFunc(func(ctx context.Context, k interface{}) (interface{}, error) {
+			if k == "slow" {
+				<-release
+			}
+			return k, nil
+		})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.GetContext(context.Background(), "slow")
+	}()
+	// the slow load holds the only slot until released
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetContext(ctx, "other"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetContext() waiting for a slot = %v, want the context error", err)
+	}
+
+	close(release)
+	<-done
+	if v, err := c.GetContext(context.Background(), "other"); err != nil || v != "other" {
+		t.Errorf("GetContext() once the slot is free = %v, %v", v, err)
+	}
+}
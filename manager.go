@@ -0,0 +1,187 @@
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Manager owns named caches created on demand from the same options, for
+// instance one per tenant. It is safe for concurrent use
+type Manager struct {
+	lock sync.RWMutex
+
+	opts   []Option
+	caches map[string]*Cache
+
+	// budget is split evenly over the caches, see SetBudget. hadBudget is
+	// set while the caches carry a limit given by the manager, so lifting
+	// the budget leaves the limits of the options alone
+	budget    int64
+	hadBudget bool
+}
+
+// NewManager creates a manager whose caches are built with opts
+func NewManager(opts ...Option) *Manager {
+	return &Manager{
+		opts:   opts,
+		caches: make(map[string]*Cache),
+	}
+}
+
+// Cache returns the cache called name, creating it if needed
+func (m *Manager) Cache(name string) (*Cache, error) {
+	m.lock.RLock()
+	c, ok := m.caches[name]
+	m.lock.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if c, ok := m.caches[name]; ok {
+		return c, nil
+	}
+	c, err := New(m.opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.caches[name] = c
+	m.rebalance()
+	return c, nil
+}
+
+// Lookup returns the cache called name without creating it
+func (m *Manager) Lookup(name string) (*Cache, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	c, ok := m.caches[name]
+	return c, ok
+}
+
+// Remove closes and purges the cache called name and forgets it, it
+// returns false if there is none
+func (m *Manager) Remove(name string) bool {
+	m.lock.Lock()
+	c, ok := m.caches[name]
+	if ok {
+		delete(m.caches, name)
+		m.rebalance()
+	}
+	m.lock.Unlock()
+
+	if ok {
+		c.Close(context.Background())
+		c.Purge()
+	}
+	return ok
+}
+
+// Names returns the names of the caches in order
+func (m *Manager) Names() []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	names := make([]string, 0, len(m.caches))
+	for name := range m.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetBudget shares maxBytes of approximate memory evenly between the caches,
+// redistributed whenever a cache is created or removed. It uses the byte
+// limit of the caches, so it replaces their cost function, see
+// WithMaxBytes. A budget of 0 or less lifts the limit it set, caches never
+// given a share keep the one of their options. See SharedBudget for a
+// budget the caches draw from as they need instead
+func (m *Manager) SetBudget(maxBytes int64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	m.budget = maxBytes
+	m.rebalance()
+}
+
+// rebalance gives every cache its share of the budget, the lock is held
+func (m *Manager) rebalance() {
+	if len(m.caches) == 0 {
+		return
+	}
+	if m.budget == 0 {
+		if m.hadBudget {
+			for _, c := range m.caches {
+				c.SetMaxCost(simplelru.NoLimitCost, nil)
+			}
+			m.hadBudget = false
+		}
+		return
+	}
+
+	share := m.budget / int64(len(m.caches))
+	if share == 0 {
+		share = 1
+	}
+	for _, c := range m.caches {
+		c.SetMaxBytes(share)
+	}
+	m.hadBudget = true
+}
+
+// PurgeAll purges every cache
+func (m *Manager) PurgeAll() {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	for _, c := range m.caches {
+		c.Purge()
+	}
+}
+
+// Stats returns the counters summed over all caches
+func (m *Manager) Stats() simplelru.Stats {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var st simplelru.Stats
+	for _, c := range m.caches {
+		st = st.Add(c.Stats())
+	}
+	return st
+}
+
+// StatsByName returns the counters of every cache
+func (m *Manager) StatsByName() map[string]simplelru.Stats {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	stats := make(map[string]simplelru.Stats, len(m.caches))
+	for name, c := range m.caches {
+		stats[name] = c.Stats()
+	}
+	return stats
+}
+
+// Close closes every cache, see Cache.Close, they stay managed afterwards.
+// It returns the errors of the caches joined
+func (m *Manager) Close(ctx context.Context) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var errs []error
+	for _, c := range m.caches {
+		if err := c.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
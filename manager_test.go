@@ -0,0 +1,64 @@
+package lrucache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestManagerBudget(t *testing.T) {
+	unit := func(k, v interface{}) int64 { return 1 }
+
+	tests := []struct {
+		name string
+		opts []Option
+		// setup sets the budget around creating the caches a and b
+		setup func(m *Manager)
+		want  int
+	}{
+		{
+			name:  "keeps the option limit",
+			opts:  []Option{WithSize(100), WithCost(3, unit)},
+			setup: func(m *Manager) { m.Cache("a"); m.Cache("b") },
+			want:  3,
+		},
+		{
+			name:  "keeps it when lifting an unset budget",
+			opts:  []Option{WithSize(100), WithCost(3, unit)},
+			setup: func(m *Manager) { m.SetBudget(0); m.Cache("a"); m.Cache("b") },
+			want:  3,
+		},
+		{
+			name:  "lifting removes the share",
+			opts:  []Option{WithSize(100)},
+			setup: func(m *Manager) { m.SetBudget(64); m.Cache("a"); m.Cache("b"); m.SetBudget(0) },
+			want:  10,
+		},
+		{
+			name: "caches added after lifting keep the option limit",
+			opts: []Option{WithSize(100), WithCost(3, unit)},
+			setup: func(m *Manager) {
+				m.SetBudget(64)
+				m.SetBudget(0)
+				m.Cache("a")
+				m.Cache("b")
+			},
+			want: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(tt.opts...)
+			tt.setup(m)
+			for _, name := range []string{"a", "b"} {
+				c, _ := m.Lookup(name)
+				for i := 0; i < 10; i++ {
+					c.Set(fmt.Sprint(i), i)
+				}
+				if c.Len() != tt.want {
+					t.Errorf("cache %s holds %d entries, want %d", name, c.Len(), tt.want)
+				}
+			}
+		})
+	}
+}
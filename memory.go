@@ -0,0 +1,78 @@
+package lrucache
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+)
+
+// WithMemoryLimit bounds the cache by the approximate memory of its entries
+// like WithMaxBytes, evicting the oldest entries after a Set until they fit.
+// With WithSize too, whichever limit is reached first evicts
+func WithMemoryLimit(bytes int64) Option {
+	return WithMaxBytes(bytes)
+}
+
+// WithMemoryPercent bounds the cache by percent of the memory available to
+// the process, see WithMemoryLimit. The memory is read once by New: the Go
+// memory limit of debug.SetMemoryLimit or GOMEMLIMIT if set, else the limit
+// of the cgroup of the process, else the physical memory. The cache is not
+// bounded by memory when none of them is known
+func WithMemoryPercent(percent float64) Option {
+	return func(c *Cache) {
+		limit, ok := availableMemory()
+		if !ok || percent <= 0 {
+			return
+		}
+		c.lru.SetMaxBytes(max(int64(float64(limit)*min(percent, 100)/100), 1))
+	}
+}
+
+// availableMemory returns the bytes the process may use, see
+// WithMemoryPercent. It is a variable for tests
+var availableMemory = func() (int64, bool) {
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		return limit, true
+	}
+	if limit, ok := cgroupMemoryLimit(); ok {
+		return limit, true
+	}
+	return physicalMemory()
+}
+
+// cgroupMemoryLimit reads the memory limit of cgroup v2, or else v1, of the
+// process. An unlimited cgroup reports no limit
+func cgroupMemoryLimit() (int64, bool) {
+	if b, err := os.ReadFile("/sys/fs/cgroup/memory.max"); err == nil {
+		limit, err := strconv.ParseInt(string(bytes.TrimSpace(b)), 10, 64)
+		return limit, err == nil
+	}
+	if b, err := os.ReadFile("/sys/fs/cgroup/memory/memory.limit_in_bytes"); err == nil {
+		limit, err := strconv.ParseInt(string(bytes.TrimSpace(b)), 10, 64)
+		// v1 reports no limit as the largest page aligned int64
+		return limit, err == nil && limit < math.MaxInt64&^(1<<12-1)
+	}
+	return 0, false
+}
+
+// physicalMemory reads MemTotal from /proc/meminfo
+func physicalMemory() (int64, bool) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := bytes.Fields(s.Bytes())
+		if len(fields) >= 2 && string(fields[0]) == "MemTotal:" {
+			kb, err := strconv.ParseInt(string(fields[1]), 10, 64)
+			return kb << 10, err == nil
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,62 @@
+package lrucache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	const limit = 64 << 10
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		value string
+		// min and max bound how many of the 100 entries are kept
+		min, max int
+	}{
+		{name: "small values fit", opts: []Option{WithSize(1000), WithMemoryLimit(limit)}, value: "v", min: 100, max: 100},
+		{name: "large values evict", opts: []Option{WithSize(1000), WithMemoryLimit(limit)}, value: strings.Repeat("v", 4<<10), min: 1, max: 16},
+		{name: "size is stricter", opts: []Option{WithSize(10), WithMemoryLimit(limit)}, value: "v", min: 10, max: 10},
+		{name: "percent of memory", opts: []Option{WithSize(1000), WithMemoryPercent(50)}, value: strings.Repeat("v", 4<<10), min: 1, max: 8},
+	}
+
+	defer func(f func() (int64, bool)) { availableMemory = f }(availableMemory)
+	availableMemory = func() (int64, bool) { return limit, true }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 100; i++ {
+				c.Set(i, tt.value)
+				if st := c.Stats(); st.Bytes > limit {
+					t.Fatalf("after %d sets %d bytes are held, over %d", i+1, st.Bytes, limit)
+				}
+			}
+			if n := c.Len(); n < tt.min || n > tt.max {
+				t.Errorf("Len() = %d, want %d to %d", n, tt.min, tt.max)
+			}
+			// the newest entry is always kept
+			if !c.Contains(99) {
+				t.Error("the last entry set was evicted")
+			}
+		})
+	}
+}
+
+func TestMemoryPercentUnknown(t *testing.T) {
+	defer func(f func() (int64, bool)) { availableMemory = f }(availableMemory)
+	availableMemory = func() (int64, bool) { return 0, false }
+
+	c, err := New(WithSize(10), WithMemoryPercent(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("a", strings.Repeat("v", 1<<20))
+	if !c.Contains("a") {
+		t.Error("an unknown memory limit bounded the cache")
+	}
+}
@@ -0,0 +1,60 @@
+package lrucache
+
+import (
+	"errors"
+	"time"
+)
+
+// Negative is the value of an entry set by SetNegative, recording that its
+// key does not exist. Get, Peek, Range and the other accessors return it as
+// is, check it with IsNegative
+var Negative interface{} = negative{}
+
+type negative struct{}
+
+// IsNegative reports whether v is the value of a negative entry
+func IsNegative(v interface{}) bool {
+	return v == Negative
+}
+
+// WithNegativeCaching makes loads failing with ErrNotFound cache a negative
+// entry for ttl, so misses on keys that do not exist stop reaching the
+// loader until it expires, see SetNegative
+func WithNegativeCaching(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// SetNegative caches that k does not exist for ttl, simplelru.NoLimitTTL
+// falling back to the cache ttl. Get then returns Negative, and GetContext
+// and GetOrLoad return ErrNotFound without calling the loader. The entry is
+// counted as a Set but not written to the store
+func (c *Cache) SetNegative(k interface{}, ttl time.Duration) {
+	if k == nil {
+		return
+	}
+	c.lock.Lock()
+	if !c.closed.Load() {
+		c.lru.SetWithTTL(k, Negative, ttl)
+		c.chargeBudget()
+	}
+	c.lock.Unlock()
+}
+
+// countNegative records a hit on v if it is negative, see Stats.NegativeHits
+func (c *Cache) countNegative(v interface{}) {
+	if IsNegative(v) {
+		c.negativeHits.Add(1)
+	}
+}
+
+// loadNegative caches a negative entry for a load failing with ErrNotFound,
+// when WithNegativeCaching is set
+func (c *Cache) loadNegative(k interface{}, err error) (interface{}, error) {
+	if c.negativeTTL == 0 || !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	c.SetNegative(k, c.negativeTTL)
+	return Negative, ErrNotFound
+}
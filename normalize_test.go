@@ -0,0 +1,63 @@
+package lrucache
+
+import (
+	"strings"
+	"testing"
+)
+
+func lowerKey(k interface{}) interface{} {
+	if s, ok := k.(string); ok {
+		return strings.ToLower(s)
+	}
+	return k
+}
+
+func TestKeyNormalizer(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(c *Cache) bool
+		// present tells whether "Foo" is left in the cache
+		present bool
+	}{
+		{"Remove", func(c *Cache) bool { return c.Remove("foo") }, false},
+		{"Contains", func(c *Cache) bool { return c.Contains("FOO") }, true},
+		{"Peek", func(c *Cache) bool { v, ok := c.Peek("foo"); return ok && v == 1 }, true},
+		{"Get", func(c *Cache) bool { v, ok := c.Get("fOO"); return ok && v == 1 }, true},
+		{"Pop", func(c *Cache) bool { v, ok := c.Pop("foo"); return ok && v == 1 }, false},
+		{"Set replaces", func(c *Cache) bool { c.Set("foo", 2); v, ok := c.Peek("Foo"); return ok && v == 2 && c.Len() == 1 }, true},
+		{"Touch", func(c *Cache) bool { return c.Touch("foo") }, true},
+		{"Pin", func(c *Cache) bool { return c.Pin("foo") }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(WithSize(10), WithKeyNormalizer(lowerKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Set("Foo", 1)
+
+			if !tt.op(c) {
+				t.Errorf("%s did not find Foo through foo", tt.name)
+			}
+			if got := c.Contains("Foo"); got != tt.present {
+				t.Errorf("Contains(Foo) = %v, want %v", got, tt.present)
+			}
+		})
+	}
+}
+
+func TestKeyNormalizerKeys(t *testing.T) {
+	c, err := New(WithSize(10), WithKeyNormalizer(lowerKey), WithBufferedReads(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("Foo", 1)
+	c.Set("BAR", 2)
+	c.Get("FOO")
+
+	keys := c.Keys()
+	if len(keys) != 2 || keys[0] != "bar" || keys[1] != "foo" {
+		t.Errorf("Keys() = %v, want [bar foo]", keys)
+	}
+}
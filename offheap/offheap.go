@@ -0,0 +1,261 @@
+// Package offheap implements a thread safe lru cache of byte values kept in
+// one arena allocated up front, so the garbage collector has no values to
+// scan and its pauses do not grow with the cache
+package offheap
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jingke11235/lrucache"
+	"github.com/jingke11235/lrucache/typedlru"
+)
+
+// DefaultChunkSize is the chunk size used when New is given none
+const DefaultChunkSize = 256
+
+// ErrTooLarge is returned for a value that does not fit in the arena
+var ErrTooLarge = errors.New("offheap: value larger than the arena")
+
+// none ends a chain of chunks
+const none = ^uint32(0)
+
+// Cache splits its arena into chunks of equal size and stores each value in
+// a chain of them, so any free chunks serve any value and the arena does not
+// fragment. Only the keys and the lru bookkeeping live on the heap, entries
+// are evicted least recently used first once the arena is full
+type Cache struct {
+	lock sync.Mutex
+
+	lru *typedlru.LRU[string, span]
+
+	arena     []byte
+	chunkSize int
+
+	// next chains the chunks of a value, free holds the free chunks
+	next []uint32
+	free []uint32
+
+	codec lrucache.Codec
+}
+
+// span locates a value in the arena, it holds no pointer
+type span struct {
+	first uint32
+	n     uint32
+}
+
+// New creates a cache with an arena of arenaBytes split in chunks of
+// chunkSize, DefaultChunkSize if 0. Entries expire after ttl unless it is
+// typedlru.NoLimitTTL
+func New(arenaBytes, chunkSize int, ttl time.Duration) (*Cache, error) {
+	if arenaBytes <= 0 {
+		return nil, errors.New("offheap: arena size must be positive")
+	}
+	return NewWithArena(make([]byte, arenaBytes), chunkSize, ttl)
+}
+
+// NewWithArena works like New with arena as the memory of the values, such
+// as a region mapped with syscall.Mmap. The cache owns arena afterwards
+func NewWithArena(arena []byte, chunkSize int, ttl time.Duration) (*Cache, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < 0 {
+		return nil, errors.New("offheap: chunk size must be positive")
+	}
+	chunks := len(arena) / chunkSize
+	if chunks == 0 || chunks >= int(none) {
+		return nil, errors.New("offheap: arena must hold between one and 2^32-1 chunks")
+	}
+
+	c := &Cache{
+		arena:     arena,
+		chunkSize: chunkSize,
+		next:      make([]uint32, chunks),
+		free:      make([]uint32, chunks),
+		codec:     lrucache.GobCodec,
+	}
+	for i := range c.free {
+		c.free[i] = uint32(chunks - 1 - i)
+	}
+	lru, err := typedlru.NewLRU[string, span](typedlru.NoLimitSize, ttl, func(k string, s span) {
+		c.release(s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.lru = lru
+	return c, nil
+}
+
+// SetCodec sets the codec of SetValue and GetValue, lrucache.GobCodec by
+// default
+func (c *Cache) SetCodec(codec lrucache.Codec) {
+	c.lock.Lock()
+	c.codec = codec
+	c.lock.Unlock()
+}
+
+// Set copies v into the arena under k, evicting entries until it fits. It
+// returns ErrTooLarge if v is larger than the whole arena
+func (c *Cache) Set(k string, v []byte) error {
+	return c.SetWithTTL(k, v, typedlru.NoLimitTTL)
+}
+
+// SetWithTTL works like Set with its own ttl, typedlru.NoLimitTTL falling
+// back to the cache ttl
+func (c *Cache) SetWithTTL(k string, v []byte, ttl time.Duration) error {
+	n := max((len(v)+c.chunkSize-1)/c.chunkSize, 1)
+	if n > len(c.next) {
+		return ErrTooLarge
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// the chunks of the previous value are freed first
+	c.lru.Remove(k)
+	if len(c.free) < n {
+		c.lru.EvictExpired()
+	}
+	for len(c.free) < n {
+		c.lru.RemoveOldest()
+	}
+
+	s := span{first: none, n: uint32(len(v))}
+	prev := none
+	for i := 0; i < n; i++ {
+		chunk := c.free[len(c.free)-1]
+		c.free = c.free[:len(c.free)-1]
+		c.next[chunk] = none
+		if prev == none {
+			s.first = chunk
+		} else {
+			c.next[prev] = chunk
+		}
+		copy(c.chunk(chunk), v[i*c.chunkSize:])
+		prev = chunk
+	}
+	c.lru.SetWithTTL(k, s, ttl)
+	return nil
+}
+
+// Get returns a copy of the value of k, the arena memory is reused once the
+// entry is evicted
+func (c *Cache) Get(k string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	s, ok := c.lru.Get(k)
+	if !ok {
+		return nil, false
+	}
+	return c.read(s), true
+}
+
+// Peek works like Get without changing recency
+func (c *Cache) Peek(k string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	s, ok := c.lru.Peek(k)
+	if !ok {
+		return nil, false
+	}
+	return c.read(s), true
+}
+
+// SetValue encodes v with the codec of the cache and sets it
+func (c *Cache) SetValue(k string, v interface{}) error {
+	c.lock.Lock()
+	codec := c.codec
+	c.lock.Unlock()
+
+	b, err := codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Set(k, b)
+}
+
+// GetValue returns the value of k decoded with the codec of the cache, or
+// lrucache.ErrNotFound
+func (c *Cache) GetValue(k string) (interface{}, error) {
+	c.lock.Lock()
+	codec := c.codec
+	c.lock.Unlock()
+
+	b, ok := c.Get(k)
+	if !ok {
+		return nil, lrucache.ErrNotFound
+	}
+	return codec.Unmarshal(b)
+}
+
+func (c *Cache) Contains(k string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Contains(k)
+}
+
+func (c *Cache) Remove(k string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Remove(k)
+}
+
+func (c *Cache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Len()
+}
+
+// FreeBytes returns the bytes of the free chunks of the arena
+func (c *Cache) FreeBytes() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.free) * c.chunkSize
+}
+
+// EvictExpired removes the expired entries, it returns how many were removed
+func (c *Cache) EvictExpired() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.EvictExpired()
+}
+
+// Stats returns the counters of the cache
+func (c *Cache) Stats() typedlru.Stats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Stats()
+}
+
+func (c *Cache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lru.Purge()
+}
+
+func (c *Cache) chunk(i uint32) []byte {
+	off := int(i) * c.chunkSize
+	return c.arena[off : off+c.chunkSize]
+}
+
+// read copies the value of s out of the arena
+func (c *Cache) read(s span) []byte {
+	v := make([]byte, s.n)
+	for off, chunk := 0, s.first; off < len(v); chunk = c.next[chunk] {
+		off += copy(v[off:], c.chunk(chunk))
+	}
+	return v
+}
+
+// release returns the chunks of s to the free list
+func (c *Cache) release(s span) {
+	for chunk := s.first; chunk != none; chunk = c.next[chunk] {
+		c.free = append(c.free, chunk)
+	}
+}
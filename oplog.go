@@ -0,0 +1,88 @@
+package lrucache
+
+import (
+	"time"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// OpKind tells what an Op does
+type OpKind int
+
+const (
+	// OpSet is a write of Key, Value and TTL
+	OpSet OpKind = iota
+	// OpRemove is Key leaving the cache for Reason, anything but expiry
+	OpRemove
+	// OpExpire is Key removed after it expired
+	OpExpire
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case OpSet:
+		return "set"
+	case OpRemove:
+		return "remove"
+	case OpExpire:
+		return "expire"
+	}
+	return "unknown"
+}
+
+// Op is one change of a cache as logged by WithOpLog, see ApplyOp. TTL is
+// the ttl left after an OpSet, simplelru.NoLimitTTL without one, and Reason
+// tells why an OpRemove or OpExpire left the cache
+type Op struct {
+	Kind   OpKind
+	Key    interface{}
+	Value  interface{}
+	TTL    time.Duration
+	Reason simplelru.EvictReason
+}
+
+// WithOpLog passes every write and removal of the cache to sink in the order
+// they happen, for ApplyOp to replay them on a replica. Evictions for room
+// are logged as OpRemove, so a replica ends up with the same live entries
+// whatever its size and recency. Reads, ttl changes and pins are not logged.
+// sink runs with the lock held, it must not block nor call the cache
+func WithOpLog(sink func(op Op)) Option {
+	return func(c *Cache) {
+		c.opLog = sink
+	}
+}
+
+// ApplyOp replays op, logged by WithOpLog, on c. An OpSet with a ttl uses the
+// SetWithTTL method of c when it has one, like Cache and simplelru.LRU do
+func ApplyOp(c simplelru.LRUCache, op Op) {
+	type ttlSetter interface {
+		SetWithTTL(k, v interface{}, ttl time.Duration)
+	}
+
+	switch op.Kind {
+	case OpSet:
+		if s, ok := c.(ttlSetter); ok && op.TTL != simplelru.NoLimitTTL {
+			s.SetWithTTL(op.Key, op.Value, op.TTL)
+		} else {
+			c.Set(op.Key, op.Value)
+		}
+	case OpRemove, OpExpire:
+		c.Remove(op.Key)
+	}
+}
+
+// logOp turns the events of the lru into ops for the sink of WithOpLog
+func (c *Cache) logOp(ev simplelru.Event) {
+	switch ev.Kind {
+	case simplelru.EventSet:
+		_, ttl, ok := c.lru.PeekWithTTL(ev.Key)
+		if !ok {
+			ttl = simplelru.NoLimitTTL
+		}
+		c.opLog(Op{Kind: OpSet, Key: ev.Key, Value: ev.Value, TTL: ttl})
+	case simplelru.EventEvict:
+		c.opLog(Op{Kind: OpRemove, Key: ev.Key, Value: ev.Value, Reason: ev.Reason})
+	case simplelru.EventExpire:
+		c.opLog(Op{Kind: OpExpire, Key: ev.Key, Value: ev.Value, Reason: ev.Reason})
+	}
+}
@@ -0,0 +1,97 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+func TestOpLogReplay(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(c *Cache, clk *clock.Fake)
+	}{
+		{
+			name: "sets and removes",
+			ops: func(c *Cache, clk *clock.Fake) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Set("a", 3)
+				c.Remove("b")
+			},
+		},
+		{
+			name: "evicted for room",
+			ops: func(c *Cache, clk *clock.Fake) {
+				for i := 0; i < 6; i++ {
+					c.Set(i, i)
+				}
+			},
+		},
+		{
+			name: "expired",
+			ops: func(c *Cache, clk *clock.Fake) {
+				c.SetWithTTL("a", 1, time.Second)
+				c.Set("b", 2)
+				clk.Advance(2 * time.Second)
+				c.EvictExpired()
+			},
+		},
+		{
+			name: "popped",
+			ops: func(c *Cache, clk *clock.Fake) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Pop("a")
+			},
+		},
+		{
+			name: "taken",
+			ops: func(c *Cache, clk *clock.Fake) {
+				c.Set("a", 1)
+				c.TakeOrCreate("a", func() interface{} { return 0 })
+				c.TakeOrCreate("b", func() interface{} { return 0 })
+			},
+		},
+		{
+			name: "purged",
+			ops: func(c *Cache, clk *clock.Fake) {
+				c.Set("a", 1)
+				c.Purge()
+				c.Set("b", 2)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			var log []Op
+			primary, err := New(WithSize(4), WithClock(clk), WithOpLog(func(op Op) { log = append(log, op) }))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the replica is larger, so it only drops what the log tells it
+			replica, err := New(WithSize(100), WithClock(clk))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.ops(primary, clk)
+			for _, op := range log {
+				ApplyOp(replica, op)
+			}
+
+			want, got := primary.Items(), replica.Items()
+			if len(got) != len(want) {
+				t.Fatalf("replica holds %v, want %v", got, want)
+			}
+			for k, v := range want {
+				if got[k] != v {
+					t.Errorf("replica[%v] = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
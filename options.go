@@ -0,0 +1,207 @@
+package lrucache
+
+import (
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Option configures a Cache
+type Option func(*Cache)
+
+// WithSize bounds the number of entries, simplelru.NoLimitSize or less
+// leaves it unbounded
+func WithSize(size int) Option {
+	return func(c *Cache) {
+		if size <= simplelru.NoLimitSize {
+			size = simplelru.NoLimitSize
+		}
+		c.lru.Resize(size)
+	}
+}
+
+// WithTTL expires entries ttl after their last update, simplelru.NoLimitTTL
+// or less disables expiry
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.lru.SetTTL(ttl)
+	}
+}
+
+// WithOnEvict sets the callback called for every entry leaving the cache,
+// it runs with the lock held unless WithAsyncEviction is given
+func WithOnEvict(onEvict simplelru.EvictCallback) Option {
+	return func(c *Cache) {
+		if onEvict != nil {
+			c.onEvicted = onEvict
+		}
+	}
+}
+
+// WithOnSet calls onSet after every successful write of k, by Set, Put,
+// MSet, CompareAndSwap or any other write, so one write can act on other
+// keys, like touching a companion window key. onSet runs with the lock held
+// and gets the unlocked cache under it as c: its own Sets on c neither take
+// the lock nor call onSet again, so a hook that sets keys cannot recurse,
+// but they skip the store. Calling the Cache itself from onSet deadlocks
+func WithOnSet(onSet func(c simplelru.LRUCache, k, v interface{})) Option {
+	return func(c *Cache) {
+		c.onSet = onSet
+	}
+}
+
+// WithClock makes the cache, its janitor and its write-behind flusher read
+// the time from clk, see clock.Fake for tests
+func WithClock(clk clock.Clock) Option {
+	return func(c *Cache) {
+		c.clock = clk
+		c.lru.SetClock(clk)
+	}
+}
+
+// WithJanitor starts a goroutine removing expired entries every interval,
+// it is stopped by Close
+func WithJanitor(interval time.Duration) Option {
+	return func(c *Cache) {
+		c.janitorInterval = interval
+	}
+}
+
+// WithJanitorJitter moves each sweep of the janitor by a random amount
+// within jitter of its interval, so caches created together do not all sweep
+// at once. A jitter of the interval or more is cut to half the interval
+func WithJanitorJitter(jitter time.Duration) Option {
+	return func(c *Cache) {
+		c.janitorJitter = jitter
+	}
+}
+
+// WithCost bounds the cache by the total cost of its entries, see
+// simplelru.LRU.SetMaxCost
+func WithCost(maxCost int64, cost func(k, v interface{}) int64) Option {
+	return func(c *Cache) {
+		c.lru.SetMaxCost(maxCost, cost)
+	}
+}
+
+// WithMaxBytes bounds the cache by the approximate memory of its entries, see
+// simplelru.LRU.SetMaxBytes. It replaces WithCost
+func WithMaxBytes(maxBytes int64) Option {
+	return func(c *Cache) {
+		c.lru.SetMaxBytes(maxBytes)
+	}
+}
+
+// WithByteTracking makes Stats report the estimated memory of the entries
+// in Bytes, see simplelru.LRU.SetByteTracking. WithMaxBytes and
+// WithSharedBudget track it anyway
+func WithByteTracking() Option {
+	return func(c *Cache) {
+		c.lru.SetByteTracking(true)
+	}
+}
+
+// WithEvictReasonCallback sets a callback told why each entry left the
+// cache, replaced values included. Like the WithOnEvict callback it runs
+// with the lock held
+func WithEvictReasonCallback(fn simplelru.EvictReasonCallback) Option {
+	return func(c *Cache) {
+		c.lru.SetEvictReasonCallback(fn)
+	}
+}
+
+// WithOnAdd sets a callback called for every key added to the cache, it runs
+// with the lock held
+func WithOnAdd(fn func(k, v interface{})) Option {
+	return func(c *Cache) {
+		c.lru.SetOnAdd(fn)
+	}
+}
+
+// WithOnUpdate sets a callback called with the old and new value of every
+// key updated in place, it runs with the lock held
+func WithOnUpdate(fn func(k, oldValue, newValue interface{})) Option {
+	return func(c *Cache) {
+		c.lru.SetOnUpdate(fn)
+	}
+}
+
+// WithSlidingExpiration makes reads restart the ttl of the entries they hit,
+// see simplelru.LRU.SetSlidingExpiration
+func WithSlidingExpiration() Option {
+	return func(c *Cache) {
+		c.lru.SetSlidingExpiration(true)
+	}
+}
+
+// WithFIFO stops reads from moving entries to head, turning the cache into a
+// FIFO with ttl, see simplelru.LRU.SetFIFO
+func WithFIFO() Option {
+	return func(c *Cache) {
+		c.lru.SetFIFO(true)
+	}
+}
+
+// WithEvictionPolicy picks which entry capacity eviction takes, see
+// simplelru.LRU.SetEvictionPolicy
+func WithEvictionPolicy(p simplelru.EvictionPolicy) Option {
+	return func(c *Cache) {
+		c.lru.SetEvictionPolicy(p)
+	}
+}
+
+// WithLRUK evicts by the k-th most recent reference of entries, see
+// simplelru.LRU.SetLRUK
+func WithLRUK(k int) Option {
+	return func(c *Cache) {
+		c.lru.SetLRUK(k)
+	}
+}
+
+// WithGhostList remembers the last n keys evicted for capacity to count the
+// misses a larger cache would have hit, see Stats.GhostHits and WouldHit
+func WithGhostList(n int) Option {
+	return func(c *Cache) {
+		c.lru.SetGhostSize(n)
+	}
+}
+
+// WithTTLJitter spreads the expiry of entries set together by randomly
+// lengthening or shortening each ttl by up to fraction, see
+// simplelru.LRU.SetTTLJitter
+func WithTTLJitter(fraction float64) Option {
+	return func(c *Cache) {
+		c.lru.SetTTLJitter(fraction)
+	}
+}
+
+// WithMaxLifetime expires entries maxLifetime after their key was first set,
+// however often they are read. With WithSlidingExpiration the cache ttl acts
+// as the idle timeout and maxLifetime as the absolute one, see
+// simplelru.LRU.SetMaxLifetime
+func WithMaxLifetime(maxLifetime time.Duration) Option {
+	return func(c *Cache) {
+		c.lru.SetMaxLifetime(maxLifetime)
+	}
+}
+
+// WithExpiryHeap tracks expiry times in a heap so EvictExpired and the
+// janitor remove the due entries without walking the whole cache, see
+// simplelru.LRU.SetExpiryHeap
+func WithExpiryHeap() Option {
+	return func(c *Cache) {
+		c.lru.SetExpiryHeap(true)
+	}
+}
+
+// WithKeyNormalizer stores and looks up every key as normalize(k), so keys
+// differing only in a way normalize removes, like case, are the same entry
+// for Set, Get, Contains, Peek, Remove and every other method, see
+// simplelru.LRU.SetKeyNormalizer. Loaders and stores get keys as passed by
+// the caller
+func WithKeyNormalizer(normalize func(k interface{}) interface{}) Option {
+	return func(c *Cache) {
+		c.lru.SetKeyNormalizer(normalize)
+	}
+}
@@ -0,0 +1,73 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+func TestWithOnSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(c *Cache)
+		calls int
+	}{
+		{name: "Set", write: func(c *Cache) { c.Set("a", 1) }, calls: 1},
+		{name: "SetWithTTL", write: func(c *Cache) { c.SetWithTTL("a", 1, time.Hour) }, calls: 1},
+		{name: "Put", write: func(c *Cache) { c.Put("a", 1) }, calls: 1},
+		{name: "several", write: func(c *Cache) { c.Set("a", 1); c.Set("b", 2); c.Set("a", 3) }, calls: 3},
+		{name: "Remove", write: func(c *Cache) { c.Remove("a") }, calls: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			calls := 0
+			// every write touches the window key, which the hook sets itself
+			// without being called again
+			c, err := New(WithSize(10), WithClock(clk), WithTTL(time.Minute), WithOnSet(func(c simplelru.LRUCache, k, v interface{}) {
+				calls++
+				if k != "window" {
+					c.Set("window", k)
+				}
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.write(c)
+			if calls != tt.calls {
+				t.Errorf("onSet called %d times, want %d", calls, tt.calls)
+			}
+			if _, ok := c.Peek("window"); ok != (tt.calls > 0) {
+				t.Errorf("window set %v, want %v", ok, tt.calls > 0)
+			}
+		})
+	}
+}
+
+func TestWithOnSetSlidingWindow(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c, err := New(WithSize(10), WithClock(clk), WithTTL(time.Minute), WithOnSet(func(c simplelru.LRUCache, k, v interface{}) {
+		c.(*simplelru.LRU).Touch("window")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("window", 0)
+
+	// each write renews the window before its ttl runs out
+	for i := 0; i < 5; i++ {
+		clk.Advance(40 * time.Second)
+		c.Set(i, i)
+	}
+	if !c.Contains("window") {
+		t.Error("the window expired although every write touched it")
+	}
+	clk.Advance(2 * time.Minute)
+	if c.Contains("window") {
+		t.Error("the window outlived its ttl without writes")
+	}
+}
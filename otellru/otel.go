@@ -0,0 +1,172 @@
+// Package otellru records OpenTelemetry metrics and loader spans for a cache
+package otellru
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jingke11235/lrucache"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Option configures a Cache
+type Option func(*Cache)
+
+// WithAttributes attaches attrs to every metric and span, to tell cache
+// instances apart
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *Cache) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithTracer starts a span of tracer around every loader call of GetOrLoad
+func WithTracer(tracer trace.Tracer) Option {
+	return func(c *Cache) {
+		c.tracer = tracer
+	}
+}
+
+// Cache wraps a simplelru.LRUCache and counts the hits and misses of Get
+// and GetOrLoad. Evictions are read from the Stats of caches that report
+// simplelru.Stats, such as lrucache.Cache, and are not recorded for others
+type Cache struct {
+	simplelru.LRUCache
+
+	tracer   trace.Tracer
+	attrs    []attribute.KeyValue
+	attrsOpt metric.MeasurementOption
+
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+
+	reg metric.Registration
+}
+
+type statsSource interface {
+	Stats() simplelru.Stats
+}
+
+type loadingCache interface {
+	GetOrLoad(k interface{}, loader lrucache.LoaderFunc) (interface{}, error)
+}
+
+// New wraps inner and creates its instruments on meter. Close unregisters
+// the observed ones
+func New(inner simplelru.LRUCache, meter metric.Meter, opts ...Option) (*Cache, error) {
+	c := &Cache{LRUCache: inner}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.attrsOpt = metric.WithAttributeSet(attribute.NewSet(c.attrs...))
+
+	var err error
+	if c.hits, err = meter.Int64Counter("lrucache.hits",
+		metric.WithDescription("Number of lookups that found a live entry.")); err != nil {
+		return nil, err
+	}
+	if c.misses, err = meter.Int64Counter("lrucache.misses",
+		metric.WithDescription("Number of lookups that found no live entry.")); err != nil {
+		return nil, err
+	}
+	entries, err := meter.Int64ObservableGauge("lrucache.entries",
+		metric.WithDescription("Number of entries in the cache."))
+	if err != nil {
+		return nil, err
+	}
+	observed := []metric.Observable{entries}
+
+	stats, _ := inner.(statsSource)
+	var evictions metric.Int64ObservableCounter
+	if stats != nil {
+		if evictions, err = meter.Int64ObservableCounter("lrucache.evictions",
+			metric.WithDescription("Number of entries evicted by capacity.")); err != nil {
+			return nil, err
+		}
+		observed = append(observed, evictions)
+	}
+
+	c.reg, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		o.ObserveInt64(entries, int64(inner.Len()), c.attrsOpt)
+		if stats != nil {
+			o.ObserveInt64(evictions, int64(stats.Stats().Evictions), c.attrsOpt)
+		}
+		return nil
+	}, observed...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Cache) Get(k interface{}) (v interface{}, ok bool) {
+	v, ok = c.LRUCache.Get(k)
+	c.record(context.Background(), ok)
+	return v, ok
+}
+
+// GetOrLoad returns the cached value of k, or loads it with loader and caches
+// it. A cache with its own GetOrLoad, such as lrucache.Cache, keeps sharing
+// concurrent loads of a key. With WithTracer each loader call runs in a span
+// started from ctx
+func (c *Cache) GetOrLoad(ctx context.Context, k interface{}, loader lrucache.LoaderFunc) (interface{}, error) {
+	loaded := false
+	load := func(k interface{}) (interface{}, error) {
+		loaded = true
+		return c.load(ctx, k, loader)
+	}
+
+	if inner, ok := c.LRUCache.(loadingCache); ok {
+		v, err := inner.GetOrLoad(k, load)
+		c.record(ctx, !loaded)
+		return v, err
+	}
+
+	if v, ok := c.LRUCache.Get(k); ok {
+		c.record(ctx, true)
+		return v, nil
+	}
+	c.record(ctx, false)
+	v, err := load(k)
+	if err != nil {
+		return nil, err
+	}
+	c.LRUCache.Set(k, v)
+	return v, nil
+}
+
+func (c *Cache) load(ctx context.Context, k interface{}, loader lrucache.LoaderFunc) (interface{}, error) {
+	if c.tracer == nil {
+		return loader(k)
+	}
+
+	_, span := c.tracer.Start(ctx, "lrucache.load", trace.WithAttributes(c.attrs...))
+	defer span.End()
+
+	v, err := loader(k)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return v, err
+}
+
+func (c *Cache) record(ctx context.Context, hit bool) {
+	if hit {
+		c.hits.Add(ctx, 1, c.attrsOpt)
+	} else {
+		c.misses.Add(ctx, 1, c.attrsOpt)
+	}
+}
+
+// Close unregisters the observed instruments, it does not close the wrapped
+// cache
+func (c *Cache) Close() error {
+	return c.reg.Unregister()
+}
+
+var _ simplelru.LRUCache = (*Cache)(nil)
@@ -0,0 +1,137 @@
+// Package peercache lets a cluster of processes share the work of filling
+// their caches, in the manner of groupcache: every key has one owner among
+// the peers, found by consistent hashing. A miss asks the owner, which loads
+// the key once and serves it to all the peers asking
+package peercache
+
+import (
+	"context"
+
+	"github.com/jingke11235/lrucache"
+)
+
+// Getter loads the value of a key owned by this process from the source of
+// truth
+type Getter interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// GetterFunc adapts a function to the Getter interface
+type GetterFunc func(ctx context.Context, key string) ([]byte, error)
+
+func (f GetterFunc) Get(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// Peer fetches a value from the process owning its key
+type Peer interface {
+	Get(ctx context.Context, group, key string) ([]byte, error)
+}
+
+// PeerPicker finds the owner of a key, ok is false when this process owns
+// it
+type PeerPicker interface {
+	PickPeer(key string) (peer Peer, ok bool)
+}
+
+// Group is a cache of one keyspace, filled by the owners of its keys. Values
+// fetched from peers are cached here as well, so a hot key does not go back
+// to its owner on every read. Values are shared with the cache and must not
+// be modified
+type Group struct {
+	name   string
+	getter Getter
+	peers  PeerPicker
+	cache  *lrucache.Cache
+
+	// onPeerError is told about failed peer fetches, which are then loaded
+	// locally
+	onPeerError func(key string, err error)
+}
+
+// GroupOption configures a Group
+type GroupOption func(*Group)
+
+// WithPeerErrorHandler is called with the error of a failed peer fetch,
+// the key is then loaded with the local getter
+func WithPeerErrorHandler(fn func(key string, err error)) GroupOption {
+	return func(g *Group) {
+		g.onPeerError = fn
+	}
+}
+
+// NewGroup creates the group called name, its cache is built with
+// cacheOpts. peers may be nil for a process working alone
+func NewGroup(name string, getter Getter, peers PeerPicker, cacheOpts []lrucache.Option, opts ...GroupOption) (*Group, error) {
+	g := &Group{name: name, getter: getter, peers: peers}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	cacheOpts = append(cacheOpts[:len(cacheOpts):len(cacheOpts)], lrucache.WithLoader(lrucache.ContextLoaderFunc(g.load)))
+	cache, err := lrucache.New(cacheOpts...)
+	if err != nil {
+		return nil, err
+	}
+	g.cache = cache
+	return g, nil
+}
+
+// Name returns the name of the group
+func (g *Group) Name() string {
+	return g.name
+}
+
+// Cache returns the cache of the group
+func (g *Group) Cache() *lrucache.Cache {
+	return g.cache
+}
+
+// Get returns the value of key from the cache, else from its owner, else
+// from the getter. Concurrent misses on a key share one fetch
+func (g *Group) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := g.cache.GetContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// serve answers a peer asking for key, this process is assumed to own it
+// even if its view of the peers differs, so a request is never forwarded
+// twice
+func (g *Group) serve(ctx context.Context, key string) ([]byte, error) {
+	return g.Get(context.WithValue(ctx, ownedKey{}, true), key)
+}
+
+type ownedKey struct{}
+
+func (g *Group) load(ctx context.Context, k interface{}) (interface{}, error) {
+	key := k.(string)
+
+	if owned, _ := ctx.Value(ownedKey{}).(bool); !owned && g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			b, err := peer.Get(ctx, g.name, key)
+			if err == nil {
+				return nonNil(b), nil
+			}
+			if g.onPeerError != nil {
+				g.onPeerError(key, err)
+			}
+		}
+	}
+
+	b, err := g.getter.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return nonNil(b), nil
+}
+
+// nonNil turns a nil value into an empty one, the cache does not hold nil
+func nonNil(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
@@ -0,0 +1,178 @@
+package peercache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jingke11235/lrucache/hashring"
+)
+
+const (
+	// DefaultBasePath is the path prefix HTTPPool serves under
+	DefaultBasePath = "/_peercache/"
+
+	// DefaultReplicas is the number of ring positions of a peer
+	DefaultReplicas = hashring.DefaultReplicas
+)
+
+// HTTPPool is a PeerPicker whose peers talk over HTTP, and the http.Handler
+// answering them. Every process of the cluster runs one, serving it under
+// its base path and giving it the same peer list
+type HTTPPool struct {
+	self     string
+	basePath string
+	replicas int
+	client   *http.Client
+
+	mu     sync.RWMutex
+	ring   *hashring.Map
+	peers  map[string]*httpPeer
+	groups map[string]*Group
+}
+
+// HTTPPoolOption configures an HTTPPool
+type HTTPPoolOption func(*HTTPPool)
+
+// WithBasePath replaces DefaultBasePath, it must be the same on every peer
+func WithBasePath(basePath string) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.basePath = basePath
+	}
+}
+
+// WithReplicas replaces DefaultReplicas, it must be the same on every peer
+func WithReplicas(replicas int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.replicas = replicas
+	}
+}
+
+// WithHTTPClient replaces http.DefaultClient for requests to peers
+func WithHTTPClient(client *http.Client) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.client = client
+	}
+}
+
+// NewHTTPPool creates the pool of the process reachable at self, a base url
+// such as "http://10.0.0.1:8080" in the form the peer list uses
+func NewHTTPPool(self string, opts ...HTTPPoolOption) *HTTPPool {
+	p := &HTTPPool{
+		self:     self,
+		basePath: DefaultBasePath,
+		replicas: DefaultReplicas,
+		client:   http.DefaultClient,
+		ring:     hashring.NewMap(0),
+		groups:   make(map[string]*Group),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.replicas <= 0 {
+		p.replicas = DefaultReplicas
+	}
+	if !strings.HasSuffix(p.basePath, "/") {
+		p.basePath += "/"
+	}
+	return p
+}
+
+// Set replaces the peer list, it should include self. Keys whose owner
+// changed are fetched from the new owner on their next miss
+func (p *HTTPPool) Set(peers ...string) {
+	r := hashring.NewMap(p.replicas)
+	r.Add(peers...)
+	m := make(map[string]*httpPeer, len(peers))
+	for _, peer := range peers {
+		m[peer] = &httpPeer{pool: p, baseURL: strings.TrimSuffix(peer, "/") + p.basePath}
+	}
+
+	p.mu.Lock()
+	p.ring = r
+	p.peers = m
+	p.mu.Unlock()
+}
+
+// Serve makes the pool answer peer requests for g
+func (p *HTTPPool) Serve(g *Group) {
+	p.mu.Lock()
+	p.groups[g.Name()] = g
+	p.mu.Unlock()
+}
+
+func (p *HTTPPool) PickPeer(key string) (Peer, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	owner := p.ring.Get(key)
+	if owner == "" || owner == p.self {
+		return nil, false
+	}
+	return p.peers[owner], true
+}
+
+// ServeHTTP answers GET <basePath><group>/<key> with the value of key
+func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, p.basePath) {
+		http.NotFound(w, r)
+		return
+	}
+
+	name, key, ok := strings.Cut(r.URL.Path[len(p.basePath):], "/")
+	if !ok {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.RLock()
+	g := p.groups[name]
+	p.mu.RUnlock()
+	if g == nil {
+		http.Error(w, "no such group: "+name, http.StatusNotFound)
+		return
+	}
+
+	b, err := g.serve(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(b)
+}
+
+type httpPeer struct {
+	pool    *HTTPPool
+	baseURL string
+}
+
+func (h *httpPeer) Get(ctx context.Context, group, key string) ([]byte, error) {
+	u := h.baseURL + url.PathEscape(group) + "/" + url.PathEscape(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.pool.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("peercache: %s: %s: %s", h.baseURL, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+var _ PeerPicker = (*HTTPPool)(nil)
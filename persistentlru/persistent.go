@@ -0,0 +1,145 @@
+// Package persistentlru implements a thread safe lru cache that is saved to
+// a file on shutdown and reloaded from it on construction
+package persistentlru
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jingke11235/lrucache"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Cache is a lrucache.Cache backed by a snapshot file. Entries whose ttl ran
+// out while the file was on disk are dropped when it is loaded
+type Cache struct {
+	*lrucache.Cache
+
+	path  string
+	codec simplelru.SnapshotCodec
+
+	saveLock sync.Mutex
+
+	closeOnce sync.Once
+	closeErr  error
+	signals   chan os.Signal
+}
+
+// New creates a cache saved to path with simplelru.GobCodec, see
+// NewWithCodec
+func New(path string, size int, ttl time.Duration, onEvict simplelru.EvictCallback, opts ...lrucache.Option) (*Cache, error) {
+	return NewWithCodec(path, simplelru.GobCodec, size, ttl, onEvict, opts...)
+}
+
+// NewWithCodec creates a cache filled from the snapshot at path, if it
+// exists, and saved to it by Close or on SIGTERM and interrupt. A file that
+// cannot be read fails the construction
+func NewWithCodec(path string, codec simplelru.SnapshotCodec, size int, ttl time.Duration, onEvict simplelru.EvictCallback, opts ...lrucache.Option) (*Cache, error) {
+	inner, err := lrucache.NewLRU(size, ttl, onEvict, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cache{Cache: inner, path: path, codec: codec}
+	if err := c.load(); err != nil {
+		inner.Close(context.Background())
+		return nil, err
+	}
+	c.watchSignals()
+
+	return c, nil
+}
+
+func (c *Cache) load() error {
+	f, err := os.Open(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := c.RestoreWith(bufio.NewReader(f), c.codec); err != nil {
+		return fmt.Errorf("persistentlru: load %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// Save writes the cache to its file now. The snapshot is written to a
+// temporary file renamed over the old one, so a failed save keeps the last
+// good file
+func (c *Cache) Save() error {
+	c.saveLock.Lock()
+	defer c.saveLock.Unlock()
+
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	w := bufio.NewWriter(f)
+	err = c.SnapshotWith(w, c.codec)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, c.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("persistentlru: save %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// Close closes the cache, see lrucache.Cache.Close, and saves it unless ctx
+// is done first. Later calls return the result of the first one
+func (c *Cache) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		signal.Stop(c.signals)
+		close(c.signals)
+		if err := c.Cache.Close(ctx); err != nil {
+			c.closeErr = err
+			return
+		}
+		c.closeErr = c.Save()
+	})
+	return c.closeErr
+}
+
+// watchSignals saves the cache on SIGTERM or interrupt, then delivers the
+// signal again. Close has stopped listening by then, so the signal gets its
+// default handling and the process still exits unless someone else listens
+func (c *Cache) watchSignals() {
+	c.signals = make(chan os.Signal, 1)
+	signal.Notify(c.signals, syscall.SIGTERM, os.Interrupt)
+
+	go func() {
+		sig, ok := <-c.signals
+		if !ok {
+			return
+		}
+		c.Close(context.Background())
+
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			p.Signal(sig)
+		}
+	}()
+}
@@ -0,0 +1,93 @@
+package lrucache
+
+import (
+	"runtime"
+	"runtime/metrics"
+)
+
+// WithMemoryPressure sheds the coldest fraction of the entries after every
+// garbage collection that leaves more than maxHeapBytes of heap objects,
+// reporting simplelru.EvictReasonPressure to the eviction reason callback.
+// The check runs on its own goroutine, woken by the collector rather than a
+// timer, and is stopped by Close
+func WithMemoryPressure(maxHeapBytes uint64, fraction float64) Option {
+	return func(c *Cache) {
+		c.pressure = &pressureWatcher{
+			maxHeapBytes: maxHeapBytes,
+			fraction:     fraction,
+			gc:           make(chan struct{}, 1),
+			heapBytes:    heapObjectBytes,
+		}
+	}
+}
+
+type pressureWatcher struct {
+	maxHeapBytes uint64
+	fraction     float64
+
+	// gc is signalled after every garbage collection
+	gc chan struct{}
+
+	heapBytes func() uint64
+}
+
+// gcNotice is garbage the collector finalizes once per cycle, its finalizer
+// signals the watcher and allocates the next one
+type gcNotice struct {
+	w    *pressureWatcher
+	stop <-chan struct{}
+}
+
+func armGCNotice(w *pressureWatcher, stop <-chan struct{}) {
+	runtime.SetFinalizer(&gcNotice{w: w, stop: stop}, func(n *gcNotice) {
+		select {
+		case <-n.stop:
+			return
+		default:
+		}
+		select {
+		case n.w.gc <- struct{}{}:
+		default:
+		}
+		armGCNotice(n.w, n.stop)
+	})
+}
+
+var heapSample = []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+
+// heapObjectBytes reads the bytes of heap objects, live and not yet swept,
+// without stopping the world as runtime.ReadMemStats does
+func heapObjectBytes() uint64 {
+	s := make([]metrics.Sample, len(heapSample))
+	copy(s, heapSample)
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s[0].Value.Uint64()
+}
+
+func (c *Cache) startPressureWatcher() {
+	w := c.pressure
+	if w == nil {
+		return
+	}
+
+	if c.stop == nil {
+		c.stop = make(chan struct{})
+	}
+	stop := c.stop
+	armGCNotice(w, stop)
+	go func() {
+		for {
+			select {
+			case <-w.gc:
+				if w.heapBytes() > w.maxHeapBytes {
+					c.Shed(w.fraction)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,86 @@
+// Package promlru exposes cache statistics as prometheus metrics
+package promlru
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// StatsSource is implemented by caches that report simplelru.Stats, such as
+// lrucache.Cache and shardedlru.Cache
+type StatsSource interface {
+	Stats() simplelru.Stats
+}
+
+// Collector implements prometheus.Collector over the stats of one cache
+type Collector struct {
+	src StatsSource
+
+	hits         *prometheus.Desc
+	misses       *prometheus.Desc
+	sets         *prometheus.Desc
+	evictions    *prometheus.Desc
+	expirations  *prometheus.Desc
+	ghostHits    *prometheus.Desc
+	negativeHits *prometheus.Desc
+	entries      *prometheus.Desc
+	cost         *prometheus.Desc
+	bytes        *prometheus.Desc
+	hitRatio     *prometheus.Desc
+}
+
+// NewCollector creates a collector reading src on every scrape, labels are
+// attached to every metric to tell cache instances apart
+func NewCollector(src StatsSource, namespace string, labels prometheus.Labels) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "lrucache", name), help, nil, labels)
+	}
+
+	return &Collector{
+		src:          src,
+		hits:         desc("hits_total", "Number of lookups that found a live entry."),
+		misses:       desc("misses_total", "Number of lookups that found no live entry."),
+		sets:         desc("sets_total", "Number of accepted sets."),
+		evictions:    desc("evictions_total", "Number of entries evicted by capacity."),
+		expirations:  desc("expirations_total", "Number of expired entries removed."),
+		ghostHits:    desc("ghost_hits_total", "Number of misses on keys recently evicted by capacity."),
+		negativeHits: desc("negative_hits_total", "Number of lookups that found a cached not found."),
+		entries:      desc("entries", "Number of entries in the cache."),
+		cost:         desc("cost", "Total cost of the entries in the cache."),
+		bytes:        desc("bytes", "Estimated memory of the entries in the cache, 0 unless tracked."),
+		hitRatio:     desc("hit_ratio", "Hits over lookups since the last stats reset."),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.sets
+	ch <- c.evictions
+	ch <- c.expirations
+	ch <- c.ghostHits
+	ch <- c.negativeHits
+	ch <- c.entries
+	ch <- c.cost
+	ch <- c.bytes
+	ch <- c.hitRatio
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	st := c.src.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(st.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(st.Misses))
+	ch <- prometheus.MustNewConstMetric(c.sets, prometheus.CounterValue, float64(st.Sets))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(st.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(st.Expirations))
+	ch <- prometheus.MustNewConstMetric(c.ghostHits, prometheus.CounterValue, float64(st.GhostHits))
+	ch <- prometheus.MustNewConstMetric(c.negativeHits, prometheus.CounterValue, float64(st.NegativeHits))
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(st.Len))
+	ch <- prometheus.MustNewConstMetric(c.cost, prometheus.GaugeValue, float64(st.Cost))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(st.Bytes))
+	ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, st.HitRatio())
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
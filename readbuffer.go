@@ -0,0 +1,116 @@
+package lrucache
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+)
+
+// WithBufferedReads lets Get share the read lock instead of taking the
+// write lock to move the entry to head. Reads are recorded in per CPU
+// stripes of batch keys, the reader filling a stripe replays it as Gets
+// under the write lock, so the write lock is taken once per batch instead
+// of once per read. Hits and misses are counted as they happen, while
+// recency, events and sliding expiration lag by up to a batch per stripe
+func WithBufferedReads(batch int) Option {
+	return func(c *Cache) {
+		if batch <= 0 {
+			batch = defaultReadBatch
+		}
+		c.reads = newReadBuffer(runtime.GOMAXPROCS(0), batch)
+	}
+}
+
+const defaultReadBatch = 64
+
+type readBuffer struct {
+	stripes []readStripe
+}
+
+type readStripe struct {
+	mu    sync.Mutex
+	reads []bufferedRead
+
+	// keeps stripes on separate cache lines
+	_ [40]byte
+}
+
+// bufferedRead is a Get of k waiting to be replayed, hit tells what it
+// found
+type bufferedRead struct {
+	k   interface{}
+	hit bool
+}
+
+func newReadBuffer(stripes, batch int) *readBuffer {
+	b := &readBuffer{stripes: make([]readStripe, stripes)}
+	for i := range b.stripes {
+		b.stripes[i].reads = make([]bufferedRead, 0, batch)
+	}
+	return b
+}
+
+// record adds a read of k to a stripe and replays the stripe once it is
+// full. Stripes are locked before the cache only with TryLock, a reader that
+// has to wait for the cache lock hands the batch off and releases the stripe
+// first, so flush may hold the cache lock while it waits for a stripe
+func (b *readBuffer) record(c *Cache, k interface{}, hit bool) {
+	s := &b.stripes[rand.N(len(b.stripes))]
+	s.mu.Lock()
+	s.reads = append(s.reads, bufferedRead{k: k, hit: hit})
+	if len(s.reads) < cap(s.reads) {
+		s.mu.Unlock()
+		return
+	}
+
+	if c.lock.TryLock() {
+		replayReads(c, s.reads)
+		s.reads = s.reads[:0]
+		c.lock.Unlock()
+		s.mu.Unlock()
+		return
+	}
+
+	batch := s.reads
+	s.reads = make([]bufferedRead, 0, cap(batch))
+	s.mu.Unlock()
+
+	c.lock.Lock()
+	replayReads(c, batch)
+	c.lock.Unlock()
+}
+
+// flush replays every stripe, c.lock must be held
+func (b *readBuffer) flush(c *Cache) {
+	for i := range b.stripes {
+		s := &b.stripes[i]
+		s.mu.Lock()
+		replayReads(c, s.reads)
+		s.reads = s.reads[:0]
+		s.mu.Unlock()
+	}
+}
+
+// replayReads applies the recorded reads to the lru and clears them, they
+// were counted by getBuffered. c.lock must be held
+func replayReads(c *Cache, reads []bufferedRead) {
+	for i, r := range reads {
+		c.lru.ReplayRead(r.k, r.hit)
+		reads[i] = bufferedRead{}
+	}
+}
+
+// getBuffered is get with WithBufferedReads
+func (c *Cache) getBuffered(k interface{}) (v interface{}, ok bool) {
+	c.lock.RLock()
+	v, ok = c.lru.Peek(k)
+	c.lock.RUnlock()
+
+	if ok {
+		c.readHits.Add(1)
+	} else {
+		c.readMisses.Add(1)
+	}
+	c.reads.record(c, k, ok)
+	return v, ok
+}
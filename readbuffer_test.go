@@ -0,0 +1,120 @@
+package lrucache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBufferedReadStats(t *testing.T) {
+	tests := []struct {
+		name         string
+		reads        func(c *Cache)
+		hits, misses uint64
+	}{
+		{
+			name:  "hits before replay",
+			reads: func(c *Cache) { c.Get("a"); c.Get("b") },
+			hits:  2,
+		},
+		{
+			name:   "misses before replay",
+			reads:  func(c *Cache) { c.Get("x"); c.Get("y"); c.Get("a") },
+			hits:   1,
+			misses: 2,
+		},
+		{
+			name: "removed before replay",
+			reads: func(c *Cache) {
+				c.Get("a")
+				c.Remove("a")
+				c.Get("a")
+			},
+			hits:   1,
+			misses: 1,
+		},
+		{
+			name: "replayed batches",
+			reads: func(c *Cache) {
+				for i := 0; i < 10; i++ {
+					c.Get("a")
+					c.Get("x")
+				}
+			},
+			hits:   10,
+			misses: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(WithSize(10), WithBufferedReads(4))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Set("a", 1)
+			c.Set("b", 2)
+
+			tt.reads(c)
+			st := c.Stats()
+			if st.Hits != tt.hits || st.Misses != tt.misses {
+				t.Errorf("before flush: %d hits %d misses, want %d %d", st.Hits, st.Misses, tt.hits, tt.misses)
+			}
+
+			// replaying the rest must not count the reads again
+			c.lock.Lock()
+			c.reads.flush(c)
+			c.lock.Unlock()
+			st = c.Stats()
+			if st.Hits != tt.hits || st.Misses != tt.misses {
+				t.Errorf("after flush: %d hits %d misses, want %d %d", st.Hits, st.Misses, tt.hits, tt.misses)
+			}
+
+			c.ResetStats()
+			if st := c.Stats(); st.Hits != 0 || st.Misses != 0 {
+				t.Errorf("after ResetStats: %d hits %d misses", st.Hits, st.Misses)
+			}
+		})
+	}
+}
+
+func TestBufferedReadRecency(t *testing.T) {
+	c, err := New(WithSize(2), WithBufferedReads(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// a batch of one replays right away, so a is the newest
+	c.Get("a")
+	c.Set("c", 3)
+
+	if !c.Contains("a") || c.Contains("b") {
+		t.Errorf("keys = %v, want a to outlive b", c.Keys())
+	}
+}
+
+func TestBufferedReadConcurrent(t *testing.T) {
+	c, err := New(WithSize(100), WithBufferedReads(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("a", 1)
+
+	const readers, reads = 8, 1000
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < reads; j++ {
+				c.Get("a")
+				c.Get("x")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if st := c.Stats(); st.Hits != readers*reads || st.Misses != readers*reads {
+		t.Errorf("%d hits %d misses, want %d each", st.Hits, st.Misses, readers*reads)
+	}
+}
@@ -0,0 +1,28 @@
+package lrucache
+
+import (
+	"time"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// WithRefreshAhead makes reads that go through a loader reload, in the
+// background, a live entry with less than threshold of its ttl left. Keys
+// read often are so refreshed before they expire while keys left unread
+// expire as usual. A key is reloaded once at a time and a failed reload
+// keeps the current value, entries without a ttl are never refreshed
+func WithRefreshAhead(threshold time.Duration) Option {
+	return func(c *Cache) {
+		c.refreshBelow = threshold
+	}
+}
+
+// refreshAhead starts the reload of k if its ttl is about to run out
+func (c *Cache) refreshAhead(k interface{}, load ContextLoaderFunc) {
+	c.lock.RLock()
+	_, ttl, ok := c.lru.PeekWithTTL(k)
+	c.lock.RUnlock()
+	if ok && ttl != simplelru.NoLimitTTL && ttl < c.refreshBelow {
+		c.revalidate(k, load)
+	}
+}
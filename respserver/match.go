@@ -0,0 +1,90 @@
+package respserver
+
+// match reports whether s matches the redis glob pattern: * matches any
+// run of bytes, ? any one byte, [abc], [a-z] and [^a] a set of bytes and \
+// escapes the next byte
+func match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if match(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if s == "" {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+
+		case '[':
+			if s == "" {
+				return false
+			}
+			rest, ok := matchSet(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			pattern, s = rest, s[1:]
+
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if s == "" || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return s == ""
+}
+
+// matchSet matches b against the set at the start of pattern, just after
+// its '[', it returns the pattern after the closing ']'. A set without
+// closing ']' runs to the end of pattern like in redis
+func matchSet(pattern string, b byte) (rest string, ok bool) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		c := pattern[0]
+		if c == '\\' && len(pattern) > 1 {
+			pattern = pattern[1:]
+			c = pattern[0]
+		}
+
+		if len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']' {
+			lo, hi := c, pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if lo <= b && b <= hi {
+				matched = true
+			}
+			pattern = pattern[3:]
+			continue
+		}
+
+		if c == b {
+			matched = true
+		}
+		pattern = pattern[1:]
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return pattern, matched != negate
+}
@@ -0,0 +1,387 @@
+// Package respserver serves a lrucache.Cache over a subset of the redis
+// protocol, so redis-cli and similar tools can look into the cache of a
+// running process. It is meant for debugging and has no authentication
+package respserver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jingke11235/lrucache"
+)
+
+// maxBulk bounds the size of one argument a client can send
+const maxBulk = 512 << 20
+
+// Server answers GET, SET with EX or PX, DEL, EXISTS, TTL, PTTL, KEYS,
+// FLUSHDB and PING. Only string keys are visible, SET stores values as
+// strings and GET returns other values formatted with fmt.Sprint
+type Server struct {
+	cache *lrucache.Cache
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// ErrServerClosed is returned by Serve after Close
+var ErrServerClosed = errors.New("respserver: server closed")
+
+func New(cache *lrucache.Cache) *Server {
+	return &Server{
+		cache:     cache,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe listens on the tcp address addr and calls Serve
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until Close, serving each on its own
+// goroutine. It closes l when it returns
+func (s *Server) Serve(l net.Listener) error {
+	if !s.track(l, nil) {
+		l.Close()
+		return ErrServerClosed
+	}
+	defer s.untrack(l, nil)
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if !s.track(nil, nc) {
+			nc.Close()
+			return ErrServerClosed
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(nil, nc)
+			s.serveConn(nc)
+		}()
+	}
+}
+
+// Close stops the listeners and closes the connections, it waits for the
+// commands being run
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for nc := range s.conns {
+		nc.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) track(l net.Listener, nc net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if l != nil {
+		s.listeners[l] = struct{}{}
+	}
+	if nc != nil {
+		s.conns[nc] = struct{}{}
+	}
+	return true
+}
+
+func (s *Server) untrack(l net.Listener, nc net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l != nil {
+		delete(s.listeners, l)
+		l.Close()
+	}
+	if nc != nil {
+		delete(s.conns, nc)
+		nc.Close()
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) serveConn(nc net.Conn) {
+	r := bufio.NewReader(nc)
+	w := bufio.NewWriter(nc)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			var perr protocolError
+			if errors.As(err, &perr) {
+				writeError(w, "ERR Protocol error: "+string(perr))
+				w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.exec(w, args)
+		// replies of pipelined commands are sent together
+		if r.Buffered() == 0 || quit {
+			if err := w.Flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// exec runs one command and writes its reply, it returns true for QUIT
+func (s *Server) exec(w *bufio.Writer, args []string) (quit bool) {
+	name := strings.ToUpper(args[0])
+	args = args[1:]
+
+	switch name {
+	case "PING":
+		switch len(args) {
+		case 0:
+			w.WriteString("+PONG\r\n")
+		case 1:
+			writeBulk(w, args[0])
+		default:
+			writeArity(w, name)
+		}
+
+	case "GET":
+		if len(args) != 1 {
+			writeArity(w, name)
+			return
+		}
+		if v, ok := s.cache.Get(args[0]); ok {
+			writeBulk(w, format(v))
+		} else {
+			w.WriteString("$-1\r\n")
+		}
+
+	case "SET":
+		s.set(w, args)
+
+	case "DEL":
+		if len(args) == 0 {
+			writeArity(w, name)
+			return
+		}
+		n := 0
+		for _, k := range args {
+			if s.cache.Remove(k) {
+				n++
+			}
+		}
+		writeInt(w, int64(n))
+
+	case "EXISTS":
+		if len(args) == 0 {
+			writeArity(w, name)
+			return
+		}
+		n := 0
+		for _, k := range args {
+			if s.cache.Contains(k) {
+				n++
+			}
+		}
+		writeInt(w, int64(n))
+
+	case "TTL", "PTTL":
+		if len(args) != 1 {
+			writeArity(w, name)
+			return
+		}
+		_, ttl, ok := s.cache.PeekWithTTL(args[0])
+		switch {
+		case !ok:
+			writeInt(w, -2)
+		case ttl <= 0:
+			writeInt(w, -1)
+		case name == "TTL":
+			writeInt(w, int64((ttl+500*time.Millisecond)/time.Second))
+		default:
+			writeInt(w, ttl.Milliseconds())
+		}
+
+	case "KEYS":
+		if len(args) != 1 {
+			writeArity(w, name)
+			return
+		}
+		var keys []string
+		s.cache.Range(func(k, v interface{}) bool {
+			if ks, ok := k.(string); ok && match(args[0], ks) {
+				keys = append(keys, ks)
+			}
+			return true
+		})
+		fmt.Fprintf(w, "*%d\r\n", len(keys))
+		for _, k := range keys {
+			writeBulk(w, k)
+		}
+
+	case "FLUSHDB":
+		if len(args) > 1 {
+			writeArity(w, name)
+			return
+		}
+		s.cache.Purge()
+		w.WriteString("+OK\r\n")
+
+	case "QUIT":
+		w.WriteString("+OK\r\n")
+		return true
+
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name)))
+	}
+	return false
+}
+
+// set runs SET key value [EX seconds | PX milliseconds]
+func (s *Server) set(w *bufio.Writer, args []string) {
+	if len(args) < 2 {
+		writeArity(w, "SET")
+		return
+	}
+	k, v := args[0], args[1]
+
+	var ttl time.Duration
+	for opts := args[2:]; len(opts) > 0; opts = opts[2:] {
+		unit := time.Duration(0)
+		switch strings.ToUpper(opts[0]) {
+		case "EX":
+			unit = time.Second
+		case "PX":
+			unit = time.Millisecond
+		}
+		if unit == 0 || len(opts) < 2 || ttl != 0 {
+			writeError(w, "ERR syntax error")
+			return
+		}
+		n, err := strconv.ParseInt(opts[1], 10, 64)
+		if err != nil || n <= 0 {
+			writeError(w, "ERR invalid expire time in 'set' command")
+			return
+		}
+		ttl = time.Duration(n) * unit
+	}
+
+	if ttl > 0 {
+		s.cache.SetWithTTL(k, v, ttl)
+	} else {
+		s.cache.Set(k, v)
+	}
+	w.WriteString("+OK\r\n")
+}
+
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeInt(w *bufio.Writer, n int64) {
+	fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func writeError(w *bufio.Writer, msg string) {
+	w.WriteString("-" + strings.NewReplacer("\r", " ", "\n", " ").Replace(msg) + "\r\n")
+}
+
+func writeArity(w *bufio.Writer, name string) {
+	writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// protocolError is a malformed request, the connection is closed after it
+// is reported
+type protocolError string
+
+func (e protocolError) Error() string { return "respserver: protocol error: " + string(e) }
+
+// readCommand reads an array of bulk strings, or an inline command of space
+// separated words as typed into telnet
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > 1<<20 {
+		return nil, protocolError("invalid multibulk length")
+	}
+
+	args := make([]string, 0, max(n, 0))
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, protocolError(fmt.Sprintf("expected '$', got '%.1s'", line))
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > maxBulk {
+			return nil, protocolError("invalid bulk length")
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
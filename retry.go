@@ -0,0 +1,45 @@
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WithLoaderRetry makes GetOrLoad and GetContext retry a failed load up to
+// attempts times before returning its error. backoff gives the wait before
+// retry number attempt, counted from 1, a nil backoff retries at once. The
+// wait ends early with ctx.Err() when ctx is done, and ErrNotFound is never
+// retried. A value loaded by a retry is cached like any other
+func WithLoaderRetry(attempts int, backoff func(attempt int) time.Duration) Option {
+	return func(c *Cache) {
+		c.retries = attempts
+		c.retryBackoff = backoff
+	}
+}
+
+// loadRetry calls load for k, again after each failure as WithLoaderRetry
+// asked for
+func (c *Cache) loadRetry(ctx context.Context, k interface{}, load ContextLoaderFunc) (interface{}, error) {
+	v, err := c.loadLimited(ctx, k, load)
+	for attempt := 1; err != nil && attempt <= c.retries; attempt++ {
+		if errors.Is(err, ErrNotFound) || ctx.Err() != nil {
+			break
+		}
+		if c.retryBackoff != nil {
+			if d := c.retryBackoff(attempt); d > 0 {
+				t := c.clock.NewTicker(d)
+				select {
+				case <-t.C():
+				case <-ctx.Done():
+				}
+				t.Stop()
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+			}
+		}
+		v, err = c.loadLimited(ctx, k, load)
+	}
+	return v, err
+}
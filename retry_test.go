@@ -0,0 +1,106 @@
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyLoader fails its first failures calls with errFlaky
+func flakyLoader(failures int32, calls *atomic.Int32) LoaderFunc {
+	return func(k interface{}) (interface{}, error) {
+		if calls.Add(1) <= failures {
+			return nil, errFlaky
+		}
+		return "v", nil
+	}
+}
+
+var errFlaky = errors.New("flaky")
+
+func TestLoaderRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		failures int32
+		err      error
+		calls    int32
+	}{
+		{name: "first load works", attempts: 3, failures: 0, calls: 1},
+		{name: "retry works", attempts: 3, failures: 2, calls: 3},
+		{name: "last retry works", attempts: 3, failures: 3, calls: 4},
+		{name: "retries run out", attempts: 3, failures: 4, err: errFlaky, calls: 4},
+		{name: "no retries", attempts: 0, failures: 1, err: errFlaky, calls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var backoffs []int
+			c, err := New(WithSize(10), WithLoaderRetry(tt.attempts, func(attempt int) time.Duration {
+				backoffs = append(backoffs, attempt)
+				return time.Microsecond
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var calls atomic.Int32
+			v, err := c.GetOrLoad("a", flakyLoader(tt.failures, &calls))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("GetOrLoad() error = %v, want %v", err, tt.err)
+			}
+			if calls.Load() != tt.calls {
+				t.Errorf("loader called %d times, want %d", calls.Load(), tt.calls)
+			}
+			if len(backoffs) != int(tt.calls-1) {
+				t.Errorf("backoff asked for %v, want one per retry", backoffs)
+			}
+
+			cached, ok := c.Peek("a")
+			if tt.err == nil && (v != "v" || !ok || cached != "v") {
+				t.Errorf("GetOrLoad() = %v, cached %v %v, want v cached", v, cached, ok)
+			}
+			if tt.err != nil && ok {
+				t.Errorf("a failed load cached %v", cached)
+			}
+		})
+	}
+}
+
+func TestLoaderRetryContext(t *testing.T) {
+	var calls atomic.Int32
+	load := flakyLoader(10, &calls)
+	c, err := New(WithSize(10),
+		WithLoader(ContextLoaderFunc(func(ctx context.Context, k interface{}) (interface{}, error) { return load(k) })),
+		WithLoaderRetry(3, func(int) time.Duration { return time.Hour }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetContext(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetContext() error = %v, want the context error", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("loader called %d times during the backoff, want 1", calls.Load())
+	}
+}
+
+func TestLoaderRetryNotFound(t *testing.T) {
+	c, err := New(WithSize(10), WithLoaderRetry(3, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int32
+	_, err = c.GetOrLoad("a", func(k interface{}) (interface{}, error) {
+		calls.Add(1)
+		return nil, ErrNotFound
+	})
+	if !errors.Is(err, ErrNotFound) || calls.Load() != 1 {
+		t.Errorf("GetOrLoad() = %v after %d calls, want ErrNotFound after 1", err, calls.Load())
+	}
+}
@@ -0,0 +1,435 @@
+// Package shardedlru implements a thread safe lru cache split into shards,
+// each shard is an independently locked lrucache.Cache
+package shardedlru
+
+import (
+	"context"
+	"errors"
+	"hash/maphash"
+	"time"
+
+	"github.com/jingke11235/lrucache"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// DefaultShards is the shard count used when New is given none
+const DefaultShards = 16
+
+// Cache routes every key to one shard by hash. Recency is tracked per
+// shard, so eviction removes the oldest entry of the key's shard and not the
+// oldest entry overall
+type Cache struct {
+	seed maphash.Seed
+
+	shards []*lrucache.Cache
+}
+
+// New creates a cache of the given number of shards sharing size, each shard
+// holds at most size/shards entries rounded up. opts apply to every shard as
+// is, so a cost limit given by lrucache.WithCost bounds each shard
+func New(shards, size int, ttl time.Duration, onEvict simplelru.EvictCallback, opts ...lrucache.Option) (*Cache, error) {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+
+	c := &Cache{
+		seed:   maphash.MakeSeed(),
+		shards: make([]*lrucache.Cache, shards),
+	}
+
+	for i := range c.shards {
+		shard, err := lrucache.NewLRU(shardSize(size, shards), ttl, onEvict, opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.shards[i] = shard
+	}
+
+	return c, nil
+}
+
+func shardSize(size, shards int) int {
+	if size <= simplelru.NoLimitSize {
+		return simplelru.NoLimitSize
+	}
+	return (size + shards - 1) / shards
+}
+
+func (c *Cache) shard(k interface{}) *lrucache.Cache {
+	return c.shards[maphash.Comparable(c.seed, k)%uint64(len(c.shards))]
+}
+
+func (c *Cache) Set(k, v interface{}) {
+	c.shard(k).Set(k, v)
+}
+
+// Add works like Set and reports whether an entry of the key's shard was
+// evicted to make room
+func (c *Cache) Add(k, v interface{}) (evicted bool) {
+	return c.shard(k).Add(k, v)
+}
+
+// ContainsOrAdd adds k unless it is live in the cache, in one step
+func (c *Cache) ContainsOrAdd(k, v interface{}) (existed, evicted bool) {
+	return c.shard(k).ContainsOrAdd(k, v)
+}
+
+// PeekOrAdd returns the live value of k, or adds v if there is none
+func (c *Cache) PeekOrAdd(k, v interface{}) (prev interface{}, existed, evicted bool) {
+	return c.shard(k).PeekOrAdd(k, v)
+}
+
+// SetWithTTL adds an entry that expires after ttl instead of the cache ttl
+func (c *Cache) SetWithTTL(k, v interface{}, ttl time.Duration) {
+	c.shard(k).SetWithTTL(k, v, ttl)
+}
+
+// SetWithCost adds an entry with the given cost instead of the computed one
+func (c *Cache) SetWithCost(k, v interface{}, cost int64) {
+	c.shard(k).SetWithCost(k, v, cost)
+}
+
+// SetWithPriority adds an entry that capacity eviction of its shard only
+// takes once the entries of lower priorities are gone
+func (c *Cache) SetWithPriority(k, v interface{}, priority int) {
+	c.shard(k).SetWithPriority(k, v, priority)
+}
+
+// SetWithTags adds an entry carrying tags, see InvalidateTag
+func (c *Cache) SetWithTags(k, v interface{}, tags ...string) {
+	c.shard(k).SetWithTags(k, v, tags...)
+}
+
+func (c *Cache) Get(k interface{}) (v interface{}, ok bool) {
+	return c.shard(k).Get(k)
+}
+
+// MGet returns the live values of keys, locking each shard once
+func (c *Cache) MGet(keys ...interface{}) map[interface{}]interface{} {
+	batches := make(map[*lrucache.Cache][]interface{})
+	for _, k := range keys {
+		shard := c.shard(k)
+		batches[shard] = append(batches[shard], k)
+	}
+
+	m := make(map[interface{}]interface{}, len(keys))
+	for shard, batch := range batches {
+		for k, v := range shard.MGet(batch...) {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// MSet sets every entry of m, locking each shard once
+func (c *Cache) MSet(m map[interface{}]interface{}) {
+	batches := make(map[*lrucache.Cache]map[interface{}]interface{})
+	for k, v := range m {
+		shard := c.shard(k)
+		if batches[shard] == nil {
+			batches[shard] = make(map[interface{}]interface{})
+		}
+		batches[shard][k] = v
+	}
+
+	for shard, batch := range batches {
+		shard.MSet(batch)
+	}
+}
+
+// GetWithExpiration works like Get and also returns when the entry expires
+func (c *Cache) GetWithExpiration(k interface{}) (v interface{}, expiresAt time.Time, ok bool) {
+	return c.shard(k).GetWithExpiration(k)
+}
+
+// EntryInfo returns the metadata of a live entry without touching it
+func (c *Cache) EntryInfo(k interface{}) (simplelru.EntryInfo, bool) {
+	return c.shard(k).EntryInfo(k)
+}
+
+// GetTagged works like Get and attributes the hit or miss to callerTag
+func (c *Cache) GetTagged(k interface{}, callerTag string) (interface{}, bool) {
+	return c.shard(k).GetTagged(k, callerTag)
+}
+
+// GetOrLoad returns the cached value of k, or loads it once with loader
+func (c *Cache) GetOrLoad(k interface{}, loader lrucache.LoaderFunc) (interface{}, error) {
+	return c.shard(k).GetOrLoad(k, loader)
+}
+
+// GetContext returns the cached value of k, or loads it with the loader set
+// by lrucache.WithLoader
+func (c *Cache) GetContext(ctx context.Context, k interface{}) (interface{}, error) {
+	return c.shard(k).GetContext(ctx, k)
+}
+
+// Pin keeps a live entry in the cache until Unpin. The size is split over
+// the shards, so a shard can be full of pinned entries before the cache is
+func (c *Cache) Pin(k interface{}) bool {
+	return c.shard(k).Pin(k)
+}
+
+// Unpin makes a pinned entry evictable again
+func (c *Cache) Unpin(k interface{}) bool {
+	return c.shard(k).Unpin(k)
+}
+
+// Touch resets the ttl of a live entry and moves it to head of its shard
+func (c *Cache) Touch(k interface{}) bool {
+	return c.shard(k).Touch(k)
+}
+
+// UpdateTTL changes the ttl of a live entry without touching it
+func (c *Cache) UpdateTTL(k interface{}, ttl time.Duration) bool {
+	return c.shard(k).UpdateTTL(k, ttl)
+}
+
+func (c *Cache) Contains(k interface{}) bool {
+	return c.shard(k).Contains(k)
+}
+
+// Peek get a cache without move it to head
+func (c *Cache) Peek(k interface{}) (v interface{}, ok bool) {
+	return c.shard(k).Peek(k)
+}
+
+func (c *Cache) Remove(k interface{}) bool {
+	return c.shard(k).Remove(k)
+}
+
+// Put works like Set and returns the error of a write-through store
+func (c *Cache) Put(k, v interface{}) error {
+	return c.shard(k).Put(k, v)
+}
+
+// Delete works like Remove and returns the error of a write-through store
+func (c *Cache) Delete(k interface{}) error {
+	return c.shard(k).Delete(k)
+}
+
+// Sync writes the queued writes of all write-behind shards now
+func (c *Cache) Sync() {
+	for _, shard := range c.shards {
+		shard.Sync()
+	}
+}
+
+// ShardCount returns the number of shards
+func (c *Cache) ShardCount() int {
+	return len(c.shards)
+}
+
+// Len returns the number of entries summed over all shards
+func (c *Cache) Len() int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// LenValid returns the number of entries that are not expired summed over
+// all shards
+func (c *Cache) LenValid() int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.LenValid()
+	}
+	return n
+}
+
+// Cap returns the size limit summed over all shards
+func (c *Cache) Cap() int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.Cap()
+	}
+	return n
+}
+
+// Cost returns the total cost summed over all shards
+func (c *Cache) Cost() int64 {
+	var n int64
+	for _, shard := range c.shards {
+		n += shard.Cost()
+	}
+	return n
+}
+
+// Keys returns keys that are not expired shard by shard, each shard's keys
+// from oldest to newest
+func (c *Cache) Keys() []interface{} {
+	keys := make([]interface{}, 0)
+	for _, shard := range c.shards {
+		keys = shard.AppendKeys(keys)
+	}
+	return keys
+}
+
+// Values returns the values that are not expired shard by shard, each
+// shard's values from oldest to newest
+func (c *Cache) Values() []interface{} {
+	values := make([]interface{}, 0)
+	for _, shard := range c.shards {
+		values = append(values, shard.Values()...)
+	}
+	return values
+}
+
+// Items returns a copy of the entries that are not expired, each shard is
+// copied at its own point in time
+func (c *Cache) Items() map[interface{}]interface{} {
+	items := make(map[interface{}]interface{})
+	for _, shard := range c.shards {
+		for k, v := range shard.Items() {
+			items[k] = v
+		}
+	}
+	return items
+}
+
+// RemoveFunc removes every entry for which pred returns true, locking each
+// shard once, it returns how many were removed
+func (c *Cache) RemoveFunc(pred func(k, v interface{}) bool) int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.RemoveFunc(pred)
+	}
+	return n
+}
+
+// InvalidateTag removes every entry carrying tag from all shards, it
+// returns how many were removed
+func (c *Cache) InvalidateTag(tag string) int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.InvalidateTag(tag)
+	}
+	return n
+}
+
+// Range calls fn for every entry that is not expired shard by shard, each
+// shard's entries from newest to oldest, until fn returns false
+func (c *Cache) Range(fn func(k, v interface{}) bool) {
+	more := true
+	for _, shard := range c.shards {
+		shard.Range(func(k, v interface{}) bool {
+			more = fn(k, v)
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+// EvictExpired removes all expired entries, it returns how many were removed
+func (c *Cache) EvictExpired() int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.EvictExpired()
+	}
+	return n
+}
+
+// Close closes all shards, see lrucache.Cache.Close, it returns their
+// errors joined
+func (c *Cache) Close(ctx context.Context) error {
+	var errs []error
+	for _, shard := range c.shards {
+		if err := shard.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Cache) Purge() {
+	for _, shard := range c.shards {
+		shard.Purge()
+	}
+}
+
+// NewGeneration invalidates the entries of every shard, see
+// lrucache.Cache.NewGeneration, it returns how many were invalidated
+func (c *Cache) NewGeneration() int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.NewGeneration()
+	}
+	return n
+}
+
+// Resize splits size over the shards like New, it returns the number of
+// entries evicted from all shards
+func (c *Cache) Resize(size int) int {
+	evicted := 0
+	for _, shard := range c.shards {
+		evicted += shard.Resize(shardSize(size, len(c.shards)))
+	}
+	return evicted
+}
+
+// SetTTL changes the ttl of every shard, see lrucache.Cache.SetTTL
+func (c *Cache) SetTTL(ttl time.Duration) {
+	for _, shard := range c.shards {
+		shard.SetTTL(ttl)
+	}
+}
+
+// ResizeTTL changes the ttl of every shard and removes the entries already
+// past it, it returns how many were removed from all shards
+func (c *Cache) ResizeTTL(ttl time.Duration) int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.ResizeTTL(ttl)
+	}
+	return n
+}
+
+// TagStats returns the GetTagged counters summed over all shards
+func (c *Cache) TagStats() map[string]simplelru.Stats {
+	m := make(map[string]simplelru.Stats)
+	for _, shard := range c.shards {
+		for tag, st := range shard.TagStats() {
+			m[tag] = m[tag].Add(st)
+		}
+	}
+	return m
+}
+
+// Stats returns the counters summed over all shards
+func (c *Cache) Stats() simplelru.Stats {
+	var st simplelru.Stats
+	for _, shard := range c.shards {
+		st = st.Add(shard.Stats())
+	}
+	return st
+}
+
+// ShardStats returns the stats of each shard, in shard order, to tell a
+// skewed key distribution by the Len or the lookups of the shards
+func (c *Cache) ShardStats() []simplelru.Stats {
+	st := make([]simplelru.Stats, len(c.shards))
+	for i, shard := range c.shards {
+		st[i] = shard.Stats()
+	}
+	return st
+}
+
+// WindowStats sums the windowed stats of all shards, see
+// lrucache.Cache.WindowStats
+func (c *Cache) WindowStats(d time.Duration) simplelru.Stats {
+	var st simplelru.Stats
+	for _, shard := range c.shards {
+		st = st.Add(shard.WindowStats(d))
+	}
+	return st
+}
+
+// ResetStats zeroes the counters of all shards
+func (c *Cache) ResetStats() {
+	for _, shard := range c.shards {
+		shard.ResetStats()
+	}
+}
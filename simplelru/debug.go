@@ -0,0 +1,96 @@
+package simplelru
+
+import "fmt"
+
+// debugCache wraps an LRUCache and checks cheap invariants after every
+// mutating operation, reporting violations to onViolation
+type debugCache struct {
+	LRUCache
+
+	onViolation func(msg string)
+}
+
+// NewDebugCache returns inner wrapped with invariant checks, onViolation is
+// called with a description of each broken invariant
+func NewDebugCache(inner LRUCache, onViolation func(msg string)) LRUCache {
+	return &debugCache{
+		LRUCache:    inner,
+		onViolation: onViolation,
+	}
+}
+
+func (d *debugCache) Set(k, v interface{}) {
+	d.LRUCache.Set(k, v)
+
+	if k != nil && v != nil && !d.LRUCache.Contains(k) {
+		d.violate("Set: key %v missing after insert", k)
+	}
+	d.checkLen("Set")
+}
+
+func (d *debugCache) Get(k interface{}) (v interface{}, ok bool) {
+	v, ok = d.LRUCache.Get(k)
+	if ok && v == nil {
+		d.violate("Get: key %v hit with nil value", k)
+	}
+	return v, ok
+}
+
+func (d *debugCache) Remove(k interface{}) bool {
+	ok := d.LRUCache.Remove(k)
+	if d.LRUCache.Contains(k) {
+		d.violate("Remove: key %v still present", k)
+	}
+	d.checkLen("Remove")
+	return ok
+}
+
+func (d *debugCache) RemoveOldest() (k, v interface{}, ok bool) {
+	before := d.LRUCache.Len()
+	k, v, ok = d.LRUCache.RemoveOldest()
+	if ok && d.LRUCache.Len() != before-1 {
+		d.violate("RemoveOldest: len %d, expected %d", d.LRUCache.Len(), before-1)
+	}
+	d.checkLen("RemoveOldest")
+	return k, v, ok
+}
+
+func (d *debugCache) RemoveOldestN(n int) int {
+	before := d.LRUCache.Len()
+	removed := d.LRUCache.RemoveOldestN(n)
+	if removed < 0 || (n > 0 && removed > n) {
+		d.violate("RemoveOldestN: removed %d of %d", removed, n)
+	}
+	if d.LRUCache.Len() != before-removed {
+		d.violate("RemoveOldestN: len %d, expected %d", d.LRUCache.Len(), before-removed)
+	}
+	return removed
+}
+
+func (d *debugCache) Purge() {
+	d.LRUCache.Purge()
+	if n := d.LRUCache.Len(); n != 0 {
+		d.violate("Purge: len %d after purge", n)
+	}
+}
+
+func (d *debugCache) Resize(size int) int {
+	evicted := d.LRUCache.Resize(size)
+	if evicted < 0 {
+		d.violate("Resize: negative eviction count %d", evicted)
+	}
+	d.checkLen("Resize")
+	return evicted
+}
+
+func (d *debugCache) checkLen(op string) {
+	if size := d.LRUCache.Cap(); size != NoLimitSize && d.LRUCache.Len() > size {
+		d.violate("%s: len %d exceeds cap %d", op, d.LRUCache.Len(), size)
+	}
+}
+
+func (d *debugCache) violate(format string, args ...interface{}) {
+	if d.onViolation != nil {
+		d.onViolation(fmt.Sprintf(format, args...))
+	}
+}
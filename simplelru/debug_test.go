@@ -0,0 +1,107 @@
+package simplelru
+
+import (
+	"strings"
+	"testing"
+)
+
+// brokenCache is a LRU with one deliberately wrong method, see
+// TestDebugCache
+type brokenCache struct {
+	*LRU
+	bug string
+}
+
+func (b *brokenCache) Set(k, v interface{}) {
+	switch b.bug {
+	case "drop":
+		return
+	case "overfill":
+		// grows past the size it reports
+		b.LRU.Resize(b.LRU.Len() + 1)
+	}
+	b.LRU.Set(k, v)
+}
+
+func (b *brokenCache) Cap() int {
+	if b.bug == "overfill" {
+		return 2
+	}
+	return b.LRU.Cap()
+}
+
+func (b *brokenCache) Get(k interface{}) (interface{}, bool) {
+	if b.bug == "nil hit" {
+		return nil, true
+	}
+	return b.LRU.Get(k)
+}
+
+func (b *brokenCache) Remove(k interface{}) bool {
+	if b.bug == "keep" {
+		return true
+	}
+	return b.LRU.Remove(k)
+}
+
+func (b *brokenCache) Purge() {
+	if b.bug == "purge" {
+		return
+	}
+	b.LRU.Purge()
+}
+
+func (b *brokenCache) RemoveOldestN(n int) int {
+	if b.bug == "overcount" {
+		return b.LRU.RemoveOldestN(n) + 1
+	}
+	return b.LRU.RemoveOldestN(n)
+}
+
+func TestDebugCache(t *testing.T) {
+	tests := []struct {
+		bug string
+		op  func(c LRUCache)
+		// want is part of the violation reported, empty for none
+		want string
+	}{
+		{bug: "", op: func(c LRUCache) { c.Set("c", 3); c.Get("a"); c.Remove("b"); c.Purge() }},
+		{bug: "drop", op: func(c LRUCache) { c.Set("c", 3) }, want: "Set: key c missing"},
+		{bug: "overfill", op: func(c LRUCache) { c.Set("c", 3) }, want: "exceeds cap 2"},
+		{bug: "nil hit", op: func(c LRUCache) { c.Get("a") }, want: "Get: key a hit with nil value"},
+		{bug: "keep", op: func(c LRUCache) { c.Remove("a") }, want: "Remove: key a still present"},
+		{bug: "purge", op: func(c LRUCache) { c.Purge() }, want: "Purge: len 2"},
+		{bug: "overcount", op: func(c LRUCache) { c.RemoveOldestN(1) }, want: "RemoveOldestN"},
+	}
+
+	for _, tt := range tests {
+		name := tt.bug
+		if name == "" {
+			name = "correct"
+		}
+		t.Run(name, func(t *testing.T) {
+			inner, err := NewLRU(2, NoLimitTTL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inner.Set("a", 1)
+			inner.Set("b", 2)
+
+			var violations []string
+			c := NewDebugCache(&brokenCache{LRU: inner, bug: tt.bug}, func(msg string) {
+				violations = append(violations, msg)
+			})
+			tt.op(c)
+
+			if tt.want == "" {
+				if len(violations) != 0 {
+					t.Errorf("violations %q on a correct cache", violations)
+				}
+				return
+			}
+			if len(violations) == 0 || !strings.Contains(violations[0], tt.want) {
+				t.Errorf("violations %q, want one containing %q", violations, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package simplelru
+
+import "reflect"
+
+// Diff compares the live entries of two caches without changing recency.
+// It returns the keys only present in a, the keys only present in b and
+// the keys present in both whose values differ according to equal, which
+// defaults to reflect.DeepEqual when nil
+func Diff(a, b LRUCache, equal func(x, y interface{}) bool) (onlyA, onlyB []interface{}, valueDiffers []interface{}) {
+	if equal == nil {
+		equal = reflect.DeepEqual
+	}
+
+	for _, k := range a.Keys() {
+		va, ok := a.Peek(k)
+		if !ok {
+			continue
+		}
+		vb, ok := b.Peek(k)
+		if !ok {
+			onlyA = append(onlyA, k)
+			continue
+		}
+		if !equal(va, vb) {
+			valueDiffers = append(valueDiffers, k)
+		}
+	}
+
+	for _, k := range b.Keys() {
+		if !a.Contains(k) {
+			onlyB = append(onlyB, k)
+		}
+	}
+
+	return onlyA, onlyB, valueDiffers
+}
@@ -0,0 +1,71 @@
+package simplelru
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   map[string]int
+		equal  func(x, y interface{}) bool
+		onlyA  []string
+		onlyB  []string
+		differ []string
+	}{
+		{name: "empty"},
+		{name: "same", a: map[string]int{"x": 1, "y": 2}, b: map[string]int{"x": 1, "y": 2}},
+		{
+			name:   "shared, differing and unique keys",
+			a:      map[string]int{"shared": 1, "differs": 2, "a1": 3, "a2": 4},
+			b:      map[string]int{"shared": 1, "differs": 20, "b1": 5},
+			onlyA:  []string{"a1", "a2"},
+			onlyB:  []string{"b1"},
+			differ: []string{"differs"},
+		},
+		{
+			name:  "custom equality",
+			a:     map[string]int{"x": 1, "y": 2},
+			b:     map[string]int{"x": 3, "y": 5},
+			equal: func(x, y interface{}) bool { return x.(int)%2 == y.(int)%2 },
+			// 2 and 5 differ in parity, 1 and 3 do not
+			differ: []string{"y"},
+		},
+	}
+
+	fill := func(t *testing.T, m map[string]int) *LRU {
+		c, err := NewLRU(10, NoLimitTTL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range m {
+			c.Set(k, v)
+		}
+		return c
+	}
+	sorted := func(keys []interface{}) []string {
+		s := make([]string, 0, len(keys))
+		for _, k := range keys {
+			s = append(s, fmt.Sprint(k))
+		}
+		slices.Sort(s)
+		return s
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			onlyA, onlyB, differ := Diff(fill(t, tt.a), fill(t, tt.b), tt.equal)
+			if got := sorted(onlyA); !slices.Equal(got, tt.onlyA) {
+				t.Errorf("onlyA = %v, want %v", got, tt.onlyA)
+			}
+			if got := sorted(onlyB); !slices.Equal(got, tt.onlyB) {
+				t.Errorf("onlyB = %v, want %v", got, tt.onlyB)
+			}
+			if got := sorted(differ); !slices.Equal(got, tt.differ) {
+				t.Errorf("valueDiffers = %v, want %v", got, tt.differ)
+			}
+		})
+	}
+}
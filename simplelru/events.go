@@ -0,0 +1,17 @@
+package simplelru
+
+import "github.com/jingke11235/lrucache/typedlru"
+
+// EventKind tells what an Event reports
+type EventKind = typedlru.EventKind
+
+const (
+	EventSet    = typedlru.EventSet
+	EventHit    = typedlru.EventHit
+	EventMiss   = typedlru.EventMiss
+	EventEvict  = typedlru.EventEvict
+	EventExpire = typedlru.EventExpire
+)
+
+// Event is one step of cache activity
+type Event = typedlru.Event[interface{}, interface{}]
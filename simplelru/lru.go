@@ -13,8 +13,14 @@ type LRUCache interface {
 
 	RemoveOldest() (k, v interface{}, ok bool)
 
+	RemoveOldestN(n int) int
+
 	Len() int
 
+	IsFull() bool
+
+	Cap() int
+
 	Keys() []interface{}
 
 	Purge()
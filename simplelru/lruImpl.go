@@ -2,195 +2,84 @@
 package simplelru
 
 import (
-	"container/list"
 	"time"
-)
 
+	"github.com/jingke11235/lrucache/typedlru"
+)
 
 const (
-	NoLimitSize = 0
-	NoLimitTTL = 0
+	NoLimitSize = typedlru.NoLimitSize
+	NoLimitTTL  = typedlru.NoLimitTTL
+	NoLimitCost = typedlru.NoLimitCost
 )
 
 type EvictCallback func(k, v interface{})
 
-type LRU struct {
-	size int
-
-	ttl time.Duration
-
-	cache map[interface{}]*list.Element
-
-	evictList *list.List
-
-	onEvicted EvictCallback
-}
-
-type entry struct {
-	key   interface{}
-	value interface{}
-	updatedAt time.Time
-}
-
-func NewLRU(size int, ttl time.Duration, onEvict EvictCallback) (*LRU, error) {
-
-	if size <= NoLimitSize {
-		size = NoLimitSize
-	}
-	if ttl <= NoLimitTTL {
-		ttl = NoLimitTTL
-	}
-
-	return &LRU{
-		size:      size,
-		ttl: ttl,
-		cache:     make(map[interface{}]*list.Element),
-		evictList: list.New(),
-		onEvicted: onEvict,
-	}, nil
-}
-
-// Add if not exit - if exited update
-func (c *LRU) Set(k,v interface{}) {
-
-	if k == nil || v == nil {
-		return
-	}
-
-	e := &entry{
-		key: k,
-		value:v,
-		updatedAt:time.Now(),
-	}
-
-	if item, ok := c.cache[k]; ok {
-		item.Value = e
-		c.evictList.MoveToFront(item)
-	} else {
-		c.cache[k] = item
-	}
-
-	if c.size != NoLimitSize && c.evictList.Len() > c.size {
-		c.removeOldest()
-	}
-
-	return
-}
-
-func (c *LRU) Get(k interface{}) (v interface{}, ok bool) {
-	if item, ok := c.cache[k]; ok && !c.expired(k) {
-		c.evictList.MoveToFront(item)
-		if item.Value.(*entry).value == nil {
-			return nil, false
-		}
-		return item.Value.(*entry).value, true
-	}
-	return
-}
-
-func (c *LRU) Contains(k interface{}) bool {
-	_, ok := c.cache[k]
-	return ok && !c.expired(k)
-}
-
-// Peek get a cache without move it to head
-func (c *LRU) Peek(k interface{}) (v interface{}, ok bool) {
-
-	var item *list.Element
+// EvictReasonCallback is an eviction callback that is also told why the
+// entry left the cache
+type EvictReasonCallback = typedlru.EvictReasonCallback[interface{}, interface{}]
 
-	if item, ok = c.cache[k]; ok && !c.expired(k) {
-		return item.Value.(*entry).value, true
-	}
+// EvictReason tells why an entry left the cache
+type EvictReason = typedlru.EvictReason
 
-	return nil, ok
-}
-
-func (c *LRU) Remove(k interface{}) bool {
-	if item, ok := c.cache[k]; ok {
-		c.removeElement(item)
-		return true
-	}
-	return false
-}
+const (
+	EvictReasonCapacity = typedlru.EvictReasonCapacity
+	EvictReasonRemoved  = typedlru.EvictReasonRemoved
+	EvictReasonPurged   = typedlru.EvictReasonPurged
+	EvictReasonExpired  = typedlru.EvictReasonExpired
+	EvictReasonReplaced = typedlru.EvictReasonReplaced
+	EvictReasonPressure = typedlru.EvictReasonPressure
+	EvictReasonTaken    = typedlru.EvictReasonTaken
+)
 
-func (c *LRU) RemoveOldest() (k, v interface{}, ok bool) {
-	item := c.evictList.Back()
-	if item != nil {
-		c.removeElement(item)
-		kv := item.Value.(*entry)
-		return kv.key, kv.value, true
-	}
-	return nil,nil,false
-}
+// GetResult tells how GetDetailed resolved a key
+type GetResult = typedlru.GetResult
 
-func (c *LRU) Len() int {
-	return c.evictList.Len()
-}
+const (
+	Miss             = typedlru.Miss
+	Hit              = typedlru.Hit
+	ExpiredReclaimed = typedlru.ExpiredReclaimed
+)
 
-// Keys returns keys that are not expired from oldest to newest
-func (c *LRU) Keys() []interface{} {
-	keys := make([]interface{}, 0)
+// EvictionPolicy tells which entry capacity eviction takes
+type EvictionPolicy = typedlru.EvictionPolicy
 
-	for item := c.evictList.Back(); item != nil && !c.expired(item.Value.(*entry).key); item = item.Prev() {
-		keys = append(keys, item.Value.(*entry).key)
-	}
+const (
+	PolicyLRU    = typedlru.PolicyLRU
+	PolicyMRU    = typedlru.PolicyMRU
+	PolicyRandom = typedlru.PolicyRandom
+	PolicyLRUK   = typedlru.PolicyLRUK
+)
 
-	return keys
+// ParseEvictionPolicy returns the policy named s
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	return typedlru.ParseEvictionPolicy(s)
 }
 
-func (c *LRU) Purge() {
-	for k, v := range c.cache {
-		if c.onEvicted != nil {
-			c.onEvicted(k, v.Value.(*entry).value)
-		}
-		delete(c.cache, k)
-	}
+// Order is the direction KeysN and RangeOrder walk the cache in
+type Order = typedlru.Order
 
-	c.evictList.Init()
-}
+const (
+	OldestFirst = typedlru.OldestFirst
+	NewestFirst = typedlru.NewestFirst
+)
 
-func (c *LRU) Resize(size int) int {
-	diff := c.Len() - size
-	if diff < 0 {
-		diff = 0
-	}
-	for i := 0; i < diff; i++ {
-		c.removeOldest()
-	}
-	c.size = size
-	return diff
-}
+// EntryInfo describes a live entry
+type EntryInfo = typedlru.EntryInfo
 
-func (c *LRU) removeOldest() {
-	item := c.evictList.Back()
+// LRU is the interface{} keyed and valued instantiation of typedlru.LRU
+type LRU = typedlru.LRU[interface{}, interface{}]
 
-	if item != nil {
-		c.removeElement(item)
-	}
+func NewLRU(size int, ttl time.Duration, onEvict EvictCallback) (*LRU, error) {
+	return typedlru.NewLRU[interface{}, interface{}](size, ttl, typedlru.EvictCallback[interface{}, interface{}](onEvict))
 }
 
-func (c *LRU) removeElement(e *list.Element) {
-	c.evictList.Remove(e)
-
-	kv := e.Value.(*entry)
-
-	delete(c.cache, kv.key)
+// Sizer is implemented by values that know their memory footprint
+type Sizer = typedlru.Sizer
 
-	if c.onEvicted != nil {
-		c.onEvicted(kv.key, kv.value)
-	}
+// EstimateSize approximates the bytes reachable from v
+func EstimateSize(v interface{}) int64 {
+	return typedlru.EstimateSize(v)
 }
 
-func (c *LRU) expired(k interface{}) bool {
-	if c.ttl == NoLimitTTL {
-		return false
-	}
-
-	if item, ok := c.cache[k]; ok {
-		if time.Since(item.Value.(*entry).updatedAt) <= c.ttl {
-			return false
-		}
-	}
-
-	return true
-}
\ No newline at end of file
+var _ LRUCache = (*LRU)(nil)
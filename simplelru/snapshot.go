@@ -0,0 +1,10 @@
+package simplelru
+
+import "github.com/jingke11235/lrucache/typedlru"
+
+// SnapshotCodec creates the encoder and decoder of a snapshot stream
+type SnapshotCodec = typedlru.SnapshotCodec
+
+// GobCodec is the default snapshot codec, concrete key and value types must
+// be registered with gob.Register
+var GobCodec = typedlru.GobCodec
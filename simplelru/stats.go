@@ -0,0 +1,6 @@
+package simplelru
+
+import "github.com/jingke11235/lrucache/typedlru"
+
+// Stats holds access counters of a cache
+type Stats = typedlru.Stats
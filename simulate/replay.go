@@ -0,0 +1,122 @@
+package simulate
+
+import (
+	"github.com/jingke11235/lrucache/arc"
+	"github.com/jingke11235/lrucache/lfu"
+	"github.com/jingke11235/lrucache/simplelru"
+	"github.com/jingke11235/lrucache/slru"
+	"github.com/jingke11235/lrucache/tinylfu"
+	"github.com/jingke11235/lrucache/twoqueue"
+)
+
+// Policy creates the caches a trace is replayed against
+type Policy struct {
+	Name string
+	New  func(size int) (simplelru.LRUCache, error)
+}
+
+// evictionPolicy is a simplelru.LRU evicting by p
+func evictionPolicy(p simplelru.EvictionPolicy) Policy {
+	return Policy{Name: p.String(), New: func(size int) (simplelru.LRUCache, error) {
+		c, err := simplelru.NewLRU(size, simplelru.NoLimitTTL, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.SetEvictionPolicy(p)
+		return c, nil
+	}}
+}
+
+// The policies of this module, simplelru.LRU under each of its eviction
+// policies and in fifo order, and the caches of the policy subpackages
+var (
+	LRU    = evictionPolicy(simplelru.PolicyLRU)
+	MRU    = evictionPolicy(simplelru.PolicyMRU)
+	Random = evictionPolicy(simplelru.PolicyRandom)
+	LRUK   = evictionPolicy(simplelru.PolicyLRUK)
+
+	FIFO = Policy{Name: "fifo", New: func(size int) (simplelru.LRUCache, error) {
+		c, err := simplelru.NewLRU(size, simplelru.NoLimitTTL, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.SetFIFO(true)
+		return c, nil
+	}}
+
+	ARC = Policy{Name: "arc", New: func(size int) (simplelru.LRUCache, error) {
+		return arc.New(size, nil)
+	}}
+	TwoQueue = Policy{Name: "2q", New: func(size int) (simplelru.LRUCache, error) {
+		return twoqueue.New(size, nil)
+	}}
+	SLRU = Policy{Name: "slru", New: func(size int) (simplelru.LRUCache, error) {
+		return slru.New(size, nil)
+	}}
+	TinyLFU = Policy{Name: "tinylfu", New: func(size int) (simplelru.LRUCache, error) {
+		return tinylfu.New(size, nil)
+	}}
+	LFU = Policy{Name: "lfu", New: func(size int) (simplelru.LRUCache, error) {
+		return lfu.New(size, nil)
+	}}
+)
+
+// Policies are all the policies of the module
+var Policies = []Policy{LRU, MRU, Random, LRUK, FIFO, ARC, TwoQueue, SLRU, TinyLFU, LFU}
+
+// Result is the outcome of replaying a trace against one cache
+type Result struct {
+	Policy string
+	Size   int
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRatio returns Hits over Hits plus Misses, 0 without any OpGet
+func (r Result) HitRatio() float64 {
+	if r.Hits+r.Misses == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Hits+r.Misses)
+}
+
+// Replay runs t against c and counts the hits and misses of its OpGets. A
+// missed key is then set, as a loader would, so caches smaller than the one
+// recorded still get to hold it. Timestamps are ignored, c should not
+// expire entries
+func Replay(t Trace, c simplelru.LRUCache) Result {
+	var r Result
+	for _, a := range t {
+		switch a.Op {
+		case OpGet:
+			if _, ok := c.Get(a.Key); ok {
+				r.Hits++
+			} else {
+				r.Misses++
+				c.Set(a.Key, struct{}{})
+			}
+		case OpSet:
+			c.Set(a.Key, struct{}{})
+		}
+	}
+	r.Size = c.Cap()
+	return r
+}
+
+// Compare replays t against every policy at every size, it returns the
+// results policy by policy, sizes in the given order
+func Compare(t Trace, policies []Policy, sizes []int) ([]Result, error) {
+	results := make([]Result, 0, len(policies)*len(sizes))
+	for _, p := range policies {
+		for _, size := range sizes {
+			c, err := p.New(size)
+			if err != nil {
+				return nil, err
+			}
+			r := Replay(t, c)
+			r.Policy, r.Size = p.Name, size
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,156 @@
+// Package simulate records the accesses of a live cache and replays them
+// against other eviction policies and sizes, to compare their hit ratios
+// before changing the configuration of the live cache
+package simulate
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Op is the kind of an access
+type Op int
+
+const (
+	// OpGet is a read, hit or miss
+	OpGet Op = iota
+	// OpSet is an accepted Set
+	OpSet
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpGet:
+		return "get"
+	case OpSet:
+		return "set"
+	default:
+		return "unknown"
+	}
+}
+
+// Access is one access of a trace
+type Access struct {
+	Key interface{}
+	Op  Op
+	At  time.Time
+}
+
+// Trace is a sequence of accesses, oldest first
+type Trace []Access
+
+// Recorder appends the accesses of a live cache to a trace, see Record
+type Recorder struct {
+	mu    sync.Mutex
+	trace Trace
+	max   int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Record starts recording the events of a cache created with
+// lrucache.WithEvents, hits and misses as OpGet and sets as OpSet, until Stop
+// or until max accesses are recorded, 0 for no limit. Events the cache
+// dropped because the channel was full are missing from the trace, see
+// lrucache.Cache.EventsDropped. Nothing else should read events meanwhile
+func Record(events <-chan simplelru.Event, max int) *Recorder {
+	r := &Recorder{max: max, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+
+		for {
+			select {
+			case ev := <-events:
+				if !r.add(ev) {
+					return
+				}
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// add records ev, it returns false once the trace is full
+func (r *Recorder) add(ev simplelru.Event) bool {
+	var op Op
+	switch ev.Kind {
+	case simplelru.EventHit, simplelru.EventMiss:
+		op = OpGet
+	case simplelru.EventSet:
+		op = OpSet
+	default:
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trace = append(r.trace, Access{Key: ev.Key, Op: op, At: clock.Real.Now()})
+	return r.max <= 0 || len(r.trace) < r.max
+}
+
+// Trace returns a copy of the accesses recorded so far
+func (r *Recorder) Trace() Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append(Trace(nil), r.trace...)
+}
+
+// Stop ends the recording and returns the trace
+func (r *Recorder) Stop() Trace {
+	select {
+	case <-r.done:
+	default:
+		close(r.stop)
+		<-r.done
+	}
+	return r.Trace()
+}
+
+type traceHeader struct {
+	Len int
+}
+
+// Write writes t to w with codec, simplelru.GobCodec needs the concrete
+// types of the keys registered with gob.Register
+func (t Trace) Write(w io.Writer, codec simplelru.SnapshotCodec) error {
+	enc := codec.NewEncoder(w)
+	if err := enc.Encode(traceHeader{Len: len(t)}); err != nil {
+		return err
+	}
+	for _, a := range t {
+		if err := enc.Encode(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadTrace reads a trace written by Trace.Write with the same codec
+func ReadTrace(r io.Reader, codec simplelru.SnapshotCodec) (Trace, error) {
+	dec := codec.NewDecoder(r)
+	var h traceHeader
+	if err := dec.Decode(&h); err != nil {
+		return nil, err
+	}
+	if h.Len < 0 {
+		return nil, errors.New("simulate: bad trace length")
+	}
+
+	t := make(Trace, 0, min(h.Len, 1<<20))
+	for range h.Len {
+		var a Access
+		if err := dec.Decode(&a); err != nil {
+			return t, err
+		}
+		t = append(t, a)
+	}
+	return t, nil
+}
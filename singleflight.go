@@ -0,0 +1,137 @@
+package lrucache
+
+import (
+	"context"
+	"sync"
+)
+
+// call is a load in flight or finished for one key, done is closed once val
+// and err are set. A locked call holds the key for lock and has no result
+type call struct {
+	done chan struct{}
+
+	val    interface{}
+	err    error
+	locked bool
+}
+
+// flightGroup runs at most one function per key at a time, concurrent
+// callers for the same key wait for and share its result
+type flightGroup struct {
+	mu sync.Mutex
+	m  map[interface{}]*call
+
+	bg     sync.WaitGroup
+	closed bool
+}
+
+// do runs fn for k unless a call for k is already running, in which case it
+// waits for that call. shared reports whether the result came from another
+// caller's fn
+func (g *flightGroup) do(k interface{}, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	return g.doContext(context.Background(), k, fn)
+}
+
+// doContext works like do but stops waiting for another caller's fn when ctx
+// is done. fn itself runs to completion and should watch ctx on its own
+func (g *flightGroup) doContext(ctx context.Context, k interface{}, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[interface{}]*call)
+	}
+	for {
+		c, ok := g.m[k]
+		if !ok {
+			break
+		}
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err(), true
+		}
+		if !c.locked {
+			return c.val, c.err, true
+		}
+		g.mu.Lock()
+	}
+
+	c := &call{done: make(chan struct{})}
+	g.m[k] = c
+	g.mu.Unlock()
+
+	defer g.finish(k, c)
+
+	c.val, c.err = fn()
+	return c.val, c.err, false
+}
+
+// start runs fn for k in a new goroutine unless a call for k is already
+// running or wait was called, it does not wait for the result
+func (g *flightGroup) start(k interface{}, fn func() (interface{}, error)) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[interface{}]*call)
+	}
+	if _, ok := g.m[k]; ok || g.closed {
+		g.mu.Unlock()
+		return
+	}
+
+	c := &call{done: make(chan struct{})}
+	g.m[k] = c
+	g.bg.Add(1)
+	g.mu.Unlock()
+
+	go func() {
+		defer g.bg.Done()
+		defer g.finish(k, c)
+		c.val, c.err = fn()
+	}()
+}
+
+// wait stops start from running new functions and waits for the ones it
+// already started to return
+func (g *flightGroup) wait() {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+	g.bg.Wait()
+}
+
+// lock waits for the calls for k to finish and holds k until unlock is
+// called, calls for k made meanwhile wait for unlock and then run their own
+// fn
+func (g *flightGroup) lock(ctx context.Context, k interface{}) (unlock func(), err error) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[interface{}]*call)
+	}
+	for {
+		c, ok := g.m[k]
+		if !ok {
+			break
+		}
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		g.mu.Lock()
+	}
+
+	c := &call{done: make(chan struct{}), locked: true}
+	g.m[k] = c
+	g.mu.Unlock()
+
+	var once sync.Once
+	return func() { once.Do(func() { g.finish(k, c) }) }, nil
+}
+
+func (g *flightGroup) finish(k interface{}, c *call) {
+	g.mu.Lock()
+	delete(g.m, k)
+	g.mu.Unlock()
+	close(c.done)
+}
@@ -0,0 +1,252 @@
+// Package slru implements a thread safe segmented lru cache
+package slru
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// DefaultProtectedRatio is the share of the size given to the protected
+// segment
+const DefaultProtectedRatio = 0.8
+
+// SLRU admits new entries into a probation segment. A second hit promotes an
+// entry to the protected segment, and entries pushed out of protected fall
+// back to the head of probation. Eviction always takes the oldest probation
+// entry first, so entries hit once never push out entries hit twice
+type SLRU struct {
+	lock sync.Mutex
+
+	size          int
+	protectedSize int
+
+	protectedRatio float64
+
+	probation *simplelru.LRU
+	protected *simplelru.LRU
+
+	onEvicted simplelru.EvictCallback
+}
+
+func New(size int, onEvict simplelru.EvictCallback) (*SLRU, error) {
+	return NewWithRatio(size, DefaultProtectedRatio, onEvict)
+}
+
+// NewWithRatio creates a cache giving protectedRatio of size to the
+// protected segment, it must be within [0, 1)
+func NewWithRatio(size int, protectedRatio float64, onEvict simplelru.EvictCallback) (*SLRU, error) {
+	if size <= 0 {
+		return nil, errors.New("slru: size must be positive")
+	}
+	if protectedRatio < 0 || protectedRatio >= 1 {
+		return nil, errors.New("slru: protected ratio must be within [0, 1)")
+	}
+	if onEvict == nil {
+		onEvict = func(k, v interface{}) {}
+	}
+
+	c := &SLRU{
+		protectedRatio: protectedRatio,
+		onEvicted:      onEvict,
+	}
+	c.probation, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.protected, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.setSize(size)
+
+	return c, nil
+}
+
+func (c *SLRU) setSize(size int) {
+	c.size = size
+	c.protectedSize = int(float64(size) * c.protectedRatio)
+}
+
+func (c *SLRU) Set(k, v interface{}) {
+	if k == nil || v == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.protected.Contains(k) {
+		c.protected.Set(k, v)
+		return
+	}
+
+	// updating a probation entry counts as its second hit
+	if c.probation.Contains(k) {
+		c.probation.Remove(k)
+		c.promote(k, v)
+		return
+	}
+
+	c.probation.Set(k, v)
+	c.evict()
+}
+
+// promote adds k to protected, demoting the oldest protected entries to
+// probation while protected is over its share
+func (c *SLRU) promote(k, v interface{}) {
+	c.protected.Set(k, v)
+
+	for c.protected.Len() > c.protectedSize {
+		dk, dv, _ := c.protected.RemoveOldest()
+		c.probation.Set(dk, dv)
+	}
+}
+
+// evict removes entries while the cache is over size, probation first
+func (c *SLRU) evict() int {
+	evicted := 0
+	for c.probation.Len()+c.protected.Len() > c.size {
+		c.removeOldest()
+		evicted++
+	}
+	return evicted
+}
+
+func (c *SLRU) Get(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if v, ok := c.protected.Get(k); ok {
+		return v, true
+	}
+
+	if v, ok := c.probation.Peek(k); ok {
+		c.probation.Remove(k)
+		c.promote(k, v)
+		return v, true
+	}
+
+	return nil, false
+}
+
+func (c *SLRU) Contains(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.protected.Contains(k) || c.probation.Contains(k)
+}
+
+// Peek get a cache without promoting it
+func (c *SLRU) Peek(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if v, ok := c.protected.Peek(k); ok {
+		return v, true
+	}
+	return c.probation.Peek(k)
+}
+
+func (c *SLRU) Remove(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, l := range []*simplelru.LRU{c.protected, c.probation} {
+		if v, ok := l.Peek(k); ok {
+			l.Remove(k)
+			c.onEvicted(k, v)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOldest removes the oldest probation entry, or the oldest protected
+// one if probation is empty
+func (c *SLRU) RemoveOldest() (k, v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.removeOldest()
+}
+
+func (c *SLRU) removeOldest() (k, v interface{}, ok bool) {
+	l := c.probation
+	if l.Len() == 0 {
+		l = c.protected
+	}
+
+	if k, v, ok = l.RemoveOldest(); ok {
+		c.onEvicted(k, v)
+	}
+	return
+}
+
+// RemoveOldestN removes up to n entries in RemoveOldest order
+func (c *SLRU) RemoveOldestN(n int) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	removed := 0
+	for ; removed < n; removed++ {
+		if _, _, ok := c.removeOldest(); !ok {
+			break
+		}
+	}
+	return removed
+}
+
+func (c *SLRU) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.probation.Len() + c.protected.Len()
+}
+
+// IsFull reports whether the cache holds as many entries as its size allows
+func (c *SLRU) IsFull() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.probation.Len()+c.protected.Len() >= c.size
+}
+
+// Cap returns the size limit of the cache
+func (c *SLRU) Cap() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.size
+}
+
+// Keys returns keys in eviction order, probation then protected, each from
+// oldest to newest
+func (c *SLRU) Keys() []interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.protected.AppendKeys(c.probation.Keys())
+}
+
+func (c *SLRU) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, l := range []*simplelru.LRU{c.probation, c.protected} {
+		for _, k := range l.Keys() {
+			v, _ := l.Peek(k)
+			c.onEvicted(k, v)
+		}
+		l.Purge()
+	}
+}
+
+// Resize changes the size limit and the protected share derived from it, it
+// returns how many entries were evicted. Non positive sizes are ignored
+func (c *SLRU) Resize(size int) int {
+	if size <= 0 {
+		return 0
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.setSize(size)
+	for c.protected.Len() > c.protectedSize {
+		dk, dv, _ := c.protected.RemoveOldest()
+		c.probation.Set(dk, dv)
+	}
+	return c.evict()
+}
+
+var _ simplelru.LRUCache = (*SLRU)(nil)
@@ -0,0 +1,47 @@
+package lrucache
+
+import (
+	"io"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Snapshot writes the live entries to w with simplelru.GobCodec from oldest
+// to newest, so Restore rebuilds them in the same order
+func (c *Cache) Snapshot(w io.Writer) error {
+	return c.SnapshotWith(w, simplelru.GobCodec)
+}
+
+// SnapshotWith works like Snapshot with the given codec. The read lock is
+// held while writing to w
+func (c *Cache) SnapshotWith(w io.Writer, codec simplelru.SnapshotCodec) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.SnapshotWith(w, codec)
+}
+
+// Restore adds the entries of a snapshot written by Snapshot, it returns
+// how many were added. Entries are not written to a store
+func (c *Cache) Restore(r io.Reader) (int, error) {
+	return c.RestoreWith(r, simplelru.GobCodec)
+}
+
+// RestoreWith works like Restore with the given codec
+func (c *Cache) RestoreWith(r io.Reader, codec simplelru.SnapshotCodec) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed.Load() {
+		return 0, ErrClosed
+	}
+	n, err := c.lru.RestoreWith(r, codec)
+	c.chargeBudget()
+	return n, err
+}
+
+// WithSnapshotOnClose makes Close write a snapshot to w once the cache is
+// drained, see Snapshot. w is not closed
+func WithSnapshotOnClose(w io.Writer) Option {
+	return func(c *Cache) {
+		c.snapshotOnClose = w
+	}
+}
@@ -0,0 +1,81 @@
+package lrucache
+
+import (
+	"context"
+	"time"
+)
+
+// WithStaleWhileRevalidate lets reads that go through a loader return a value
+// up to maxStale after it expired, reloading it in the background. Past
+// maxStale the read waits for a fresh load like a miss. Expired entries are
+// still removed by the janitor and by eviction, which ends their stale life
+// early
+func WithStaleWhileRevalidate(maxStale time.Duration) Option {
+	return func(c *Cache) {
+		c.maxStale = maxStale
+	}
+}
+
+// WithStaleGrace keeps entries for grace after their ttl ran out before they
+// are reclaimed, so GetAllowStale can serve them while the source behind the
+// cache is down. Get and the other reads miss them as soon as they expire,
+// see simplelru.LRU.SetStaleGrace
+func WithStaleGrace(grace time.Duration) Option {
+	return func(c *Cache) {
+		c.lru.SetStaleGrace(grace)
+	}
+}
+
+// GetAllowStale works like Get, and on a miss also returns a value that
+// expired less than the stale grace ago with stale set. Serving a stale
+// value does not move it to head and counts as a miss
+func (c *Cache) GetAllowStale(k interface{}) (v interface{}, stale, ok bool) {
+	if v, ok := c.get(k); ok && !IsNegative(v) {
+		return v, false, true
+	}
+
+	c.lock.RLock()
+	v, staleFor, ok := c.lru.PeekStale(k)
+	grace := c.lru.StaleGrace()
+	c.lock.RUnlock()
+	if !ok || staleFor <= 0 || staleFor > grace || IsNegative(v) {
+		return nil, false, false
+	}
+	return v, true, true
+}
+
+// getOrRevalidate works like get, and also returns a value that is stale by
+// at most maxStale after starting its reload with load
+func (c *Cache) getOrRevalidate(k interface{}, load ContextLoaderFunc) (interface{}, bool) {
+	if c.maxStale <= 0 {
+		return c.get(k)
+	}
+
+	c.lock.Lock()
+	v, staleFor, ok := c.lru.PeekStale(k)
+	if ok && staleFor > 0 && staleFor <= c.maxStale {
+		c.lock.Unlock()
+		c.revalidate(k, load)
+		return v, true
+	}
+	v, ok = c.lru.Get(k)
+	c.lock.Unlock()
+	c.countNegative(v)
+	return v, ok
+}
+
+// revalidate reloads k in the background unless a load of k is running, a
+// failed reload keeps the stale value
+func (c *Cache) revalidate(k interface{}, load ContextLoaderFunc) {
+	if c.closed.Load() {
+		return
+	}
+	c.loads.start(k, func() (interface{}, error) {
+		v, err := c.loadLimited(context.Background(), k, load)
+		if err != nil {
+			return c.loadNegative(k, err)
+		}
+		c.fill(k, v)
+		return v, nil
+	})
+}
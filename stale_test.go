@@ -0,0 +1,48 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+func TestStaleGrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		// get is what Get finds, stale and kept what GetAllowStale finds
+		// and whether the entry survives EvictExpired
+		get, stale, allow, kept bool
+	}{
+		{name: "fresh", elapsed: 30 * time.Second, get: true, allow: true, kept: true},
+		{name: "within grace", elapsed: 90 * time.Second, stale: true, allow: true, kept: true},
+		{name: "grace end", elapsed: 2 * time.Minute, stale: true, allow: true, kept: true},
+		{name: "beyond grace", elapsed: 3 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			c, err := New(WithSize(10), WithClock(clk), WithTTL(time.Minute), WithStaleGrace(time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Set("a", 1)
+			clk.Advance(tt.elapsed)
+
+			v, stale, ok := c.GetAllowStale("a")
+			if ok != tt.allow || stale != tt.stale || ok && v != 1 {
+				t.Errorf("GetAllowStale() = %v, %v, %v, want stale %v ok %v", v, stale, ok, tt.stale, tt.allow)
+			}
+			if _, ok := c.Get("a"); ok != tt.get {
+				t.Errorf("Get() found it %v, want %v", ok, tt.get)
+			}
+
+			c.EvictExpired()
+			if _, _, ok := c.GetAllowStale("a"); ok != tt.kept {
+				t.Errorf("after EvictExpired GetAllowStale() found it %v, want %v", ok, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package lrucache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+// Store is the backing store of a write-through or write-behind cache
+type Store interface {
+	Put(k, v interface{}) error
+	Delete(k interface{}) error
+}
+
+// WithWriteThrough writes every Set and Remove to store before the cache is
+// updated, a Set the store rejects leaves the cache untouched. Writes hold
+// the cache lock so the store sees them in the order the cache does
+func WithWriteThrough(store Store) Option {
+	return func(c *Cache) {
+		c.store = store
+	}
+}
+
+// WithWriteBehind queues Sets and Removes and writes them to store every
+// interval, keeping only the latest write per key. A queue reaching
+// maxPending keys is written by the caller that filled it. Evicting an entry
+// with a queued write writes it first, so a later load sees it. Write errors
+// are passed to onError, which may be nil. Close writes what is left
+func WithWriteBehind(store Store, interval time.Duration, maxPending int, onError func(k interface{}, err error)) Option {
+	return func(c *Cache) {
+		c.behind = &writeBehind{
+			store:      store,
+			interval:   interval,
+			maxPending: maxPending,
+			onError:    onError,
+			pending:    make(map[interface{}]pendingWrite),
+		}
+	}
+}
+
+// Put works like Set and returns the error of a write-through store, the
+// cache is only updated if the store accepted the value. It returns
+// ErrAllPinned for a new key when the cache is full of pinned entries
+func (c *Cache) Put(k, v interface{}) error {
+	return c.write(k, v, func() { c.lru.Set(k, v) })
+}
+
+// Delete works like Remove and returns the error of a write-through store,
+// the entry is removed from the cache either way
+func (c *Cache) Delete(k interface{}) error {
+	_, err := c.remove(k)
+	return err
+}
+
+// Sync writes the queued writes of a write-behind cache to its store now
+func (c *Cache) Sync() {
+	if c.behind != nil {
+		c.behind.flush()
+	}
+}
+
+// write runs set under the lock once v is accepted by the store
+func (c *Cache) write(k, v interface{}, set func()) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.writeLocked(k, v, set)
+}
+
+func (c *Cache) writeLocked(k, v interface{}, set func()) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	if k == nil || v == nil {
+		return nil
+	}
+	if !c.lru.CanAdd(k) {
+		return ErrAllPinned
+	}
+
+	if c.store != nil {
+		if err := c.store.Put(k, v); err != nil {
+			return err
+		}
+	}
+
+	set()
+	c.chargeBudget()
+
+	if c.behind != nil {
+		c.behind.add(k, pendingWrite{value: v})
+	}
+	if c.onSet != nil {
+		c.onSet(c.lru, k, v)
+	}
+	return nil
+}
+
+func (c *Cache) remove(k interface{}) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed.Load() {
+		return false, ErrClosed
+	}
+	var err error
+	if c.store != nil {
+		err = c.store.Delete(k)
+	}
+
+	// queued before removing so the eviction hook does not write k back
+	if c.behind != nil {
+		c.behind.add(k, pendingWrite{deleted: true})
+	}
+
+	return c.lru.Remove(k), err
+}
+
+// evicted is the eviction callback of the underlying lru
+func (c *Cache) evicted(k, v interface{}) {
+	if c.behind != nil {
+		c.behind.flushKey(k)
+	}
+	if c.evictPool != nil && c.evictPool.dispatch(k, v) {
+		return
+	}
+	c.onEvicted(k, v)
+}
+
+type pendingWrite struct {
+	value   interface{}
+	deleted bool
+}
+
+type writeBehind struct {
+	store      Store
+	interval   time.Duration
+	maxPending int
+	onError    func(k interface{}, err error)
+
+	// mu guards pending, flushMu serializes writes to the store so an older
+	// write never lands after a newer one
+	mu      sync.Mutex
+	pending map[interface{}]pendingWrite
+	flushMu sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (w *writeBehind) add(k interface{}, pw pendingWrite) {
+	w.mu.Lock()
+	w.pending[k] = pw
+	full := w.maxPending > 0 && len(w.pending) >= w.maxPending
+	w.mu.Unlock()
+
+	if full {
+		w.flush()
+	}
+}
+
+func (w *writeBehind) flush() {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = make(map[interface{}]pendingWrite, len(batch))
+	w.mu.Unlock()
+
+	for k, pw := range batch {
+		w.apply(k, pw)
+	}
+}
+
+// flushKey writes the queued value of k, if any
+func (w *writeBehind) flushKey(k interface{}) {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	pw, ok := w.pending[k]
+	if ok && !pw.deleted {
+		delete(w.pending, k)
+	}
+	w.mu.Unlock()
+
+	if ok && !pw.deleted {
+		w.apply(k, pw)
+	}
+}
+
+func (w *writeBehind) apply(k interface{}, pw pendingWrite) {
+	var err error
+	if pw.deleted {
+		err = w.store.Delete(k)
+	} else {
+		err = w.store.Put(k, pw.value)
+	}
+	if err != nil && w.onError != nil {
+		w.onError(k, err)
+	}
+}
+
+func (w *writeBehind) start(clk clock.Clock) {
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	var ticker clock.Ticker
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker = clk.NewTicker(w.interval)
+		tick = ticker.C()
+	}
+
+	go func() {
+		defer close(w.done)
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
+		for {
+			select {
+			case <-tick:
+				w.flush()
+			case <-w.stop:
+				w.flush()
+				return
+			}
+		}
+	}()
+}
+
+func (w *writeBehind) close() {
+	close(w.stop)
+	<-w.done
+}
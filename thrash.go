@@ -0,0 +1,61 @@
+package lrucache
+
+import "time"
+
+// WithThrashDetector checks every window how many entries were evicted for
+// room per Set in that window, and calls onThrash with the ratio when it is
+// above threshold. A ratio close to 1 means nearly every write pushes out
+// another entry: the working set does not fit and the cache should grow.
+// onThrash runs on a goroutine of its own, which is stopped by Close
+func WithThrashDetector(window time.Duration, threshold float64, onThrash func(rate float64)) Option {
+	return func(c *Cache) {
+		if window <= 0 || onThrash == nil {
+			return
+		}
+		c.thrash = &thrashDetector{
+			window:    window,
+			threshold: threshold,
+			onThrash:  onThrash,
+		}
+	}
+}
+
+type thrashDetector struct {
+	window    time.Duration
+	threshold float64
+	onThrash  func(rate float64)
+}
+
+func (c *Cache) startThrashDetector() {
+	d := c.thrash
+	if d == nil {
+		return
+	}
+
+	ticker := c.clock.NewTicker(d.window)
+	if c.stop == nil {
+		c.stop = make(chan struct{})
+	}
+	stop := c.stop
+	last := c.Stats()
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				st := c.Stats()
+				diff := st.Sub(last)
+				last = st
+				if diff.Sets == 0 {
+					continue
+				}
+				if rate := float64(diff.Evictions) / float64(diff.Sets); rate > d.threshold {
+					d.onThrash(rate)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
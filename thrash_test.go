@@ -0,0 +1,112 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+func TestThrashDetector(t *testing.T) {
+	tests := []struct {
+		name string
+		// write runs on a cache of 10 entries during one window
+		write func(c *Cache)
+		fire  bool
+	}{
+		{
+			name: "working set fits",
+			write: func(c *Cache) {
+				for i := 0; i < 100; i++ {
+					c.Set(i%10, i)
+				}
+			},
+		},
+		{
+			name: "working set twice the size",
+			write: func(c *Cache) {
+				for i := 0; i < 100; i++ {
+					c.Set(i%20, i)
+				}
+			},
+			fire: true,
+		},
+		{
+			name: "a few evictions under the threshold",
+			write: func(c *Cache) {
+				for i := 0; i < 100; i++ {
+					c.Set(i%12, i)
+				}
+				// the ratio is 90 over 190
+				for i := 0; i < 90; i++ {
+					c.Set(11, i)
+				}
+			},
+		},
+		{name: "no writes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			rates := make(chan float64, 1)
+			c, err := New(WithSize(10), WithClock(clk), WithThrashDetector(time.Minute, 0.5, func(rate float64) {
+				rates <- rate
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close(t.Context())
+
+			if tt.write != nil {
+				tt.write(c)
+			}
+			clk.Advance(time.Minute)
+
+			select {
+			case rate := <-rates:
+				if !tt.fire {
+					t.Errorf("onThrash(%v) on a cache that does not thrash", rate)
+				} else if rate <= 0.5 || rate > 1 {
+					t.Errorf("onThrash(%v), want a rate above the threshold", rate)
+				}
+			case <-time.After(50 * time.Millisecond):
+				if tt.fire {
+					t.Error("onThrash not called on a thrashing cache")
+				}
+			}
+		})
+	}
+}
+
+func TestThrashDetectorWindow(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	rates := make(chan float64, 1)
+	c, err := New(WithSize(10), WithClock(clk), WithThrashDetector(time.Minute, 0.5, func(rate float64) {
+		rates <- rate
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(t.Context())
+
+	// a thrashing window is not counted again in the calm one after it
+	for i := 0; i < 100; i++ {
+		c.Set(i, i)
+	}
+	clk.Advance(time.Minute)
+	select {
+	case <-rates:
+	case <-time.After(time.Second):
+		t.Fatal("onThrash not called on a thrashing window")
+	}
+	for i := 0; i < 100; i++ {
+		c.Set(99-i%10, i)
+	}
+	clk.Advance(time.Minute)
+	select {
+	case rate := <-rates:
+		t.Errorf("onThrash(%v) for a window without evictions", rate)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,99 @@
+package tieredcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Invalidation tells the caches sharing a bus that Key changed, Origin
+// names the cache that changed it
+type Invalidation struct {
+	Origin string
+	Key    string
+}
+
+// Bus carries invalidations between the local tiers of several processes.
+// A bus may deliver an invalidation back to the cache that published it,
+// receivers drop those by Origin
+type Bus interface {
+	Publish(ctx context.Context, inv Invalidation) error
+	// Subscribe calls fn for every invalidation published from now on until
+	// cancel is called, fn must not call cancel
+	Subscribe(fn func(Invalidation)) (cancel func(), err error)
+}
+
+// LocalBus is a Bus within one process, for caches of the same process or
+// tests. Publish calls the subscribers before it returns
+type LocalBus struct {
+	mu   sync.RWMutex
+	subs map[int]func(Invalidation)
+	next int
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{subs: make(map[int]func(Invalidation))}
+}
+
+func (b *LocalBus) Publish(ctx context.Context, inv Invalidation) error {
+	b.mu.RLock()
+	fns := make([]func(Invalidation), 0, len(b.subs))
+	for _, fn := range b.subs {
+		fns = append(fns, fn)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(inv)
+	}
+	return nil
+}
+
+func (b *LocalBus) Subscribe(fn func(Invalidation)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.subs[id] = fn
+
+	return func() {
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+	}, nil
+}
+
+var _ Bus = (*LocalBus)(nil)
+
+// newOrigin returns a random origin for a cache given none
+func newOrigin() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+var errBadInvalidation = errors.New("tieredcache: malformed invalidation")
+
+// encode writes inv as "<len(origin)>:<origin><key>" so neither part needs
+// escaping
+func (inv Invalidation) encode() string {
+	return strconv.Itoa(len(inv.Origin)) + ":" + inv.Origin + inv.Key
+}
+
+func decodeInvalidation(s string) (Invalidation, error) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return Invalidation{}, errBadInvalidation
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil || n < 0 || n > len(s)-i-1 {
+		return Invalidation{}, errBadInvalidation
+	}
+	s = s[i+1:]
+	return Invalidation{Origin: s[:n], Key: s[n:]}, nil
+}
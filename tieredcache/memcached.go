@@ -0,0 +1,147 @@
+package tieredcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Memcached is a RemoteCache speaking the memcached text protocol to one
+// server. Keys must follow memcached rules: at most 250 bytes without
+// spaces or control characters. It is safe for concurrent use
+type Memcached struct {
+	pool *connPool
+}
+
+// NewMemcached creates a client for the server at addr keeping up to maxIdle
+// idle connections
+func NewMemcached(addr string, maxIdle int, dialTimeout time.Duration) *Memcached {
+	return &Memcached{pool: newConnPool(addr, maxIdle, dialTimeout)}
+}
+
+// maxRelativeExpiry is the largest expiry memcached reads as relative, see
+// its protocol description
+const maxRelativeExpiry = 30 * 24 * time.Hour
+
+var errBadKey = errors.New("tieredcache: memcached: invalid key")
+
+func (m *Memcached) Get(ctx context.Context, key string) (value []byte, err error) {
+	if !validMemcachedKey(key) {
+		return nil, errBadKey
+	}
+	cn, err := m.pool.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { m.pool.put(cn, err != nil && err != ErrMiss) }()
+
+	fmt.Fprintf(cn.w, "get %s\r\n", key)
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	line, err := readLine(cn)
+	if err != nil {
+		return nil, err
+	}
+	if line == "END" {
+		return nil, ErrMiss
+	}
+
+	// VALUE <key> <flags> <bytes>
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[0] != "VALUE" {
+		return nil, fmt.Errorf("tieredcache: memcached: unexpected reply %q", line)
+	}
+	n, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("tieredcache: memcached: bad length %q", line)
+	}
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(cn.r, buf); err != nil {
+		return nil, err
+	}
+	if line, err := readLine(cn); err != nil || line != "END" {
+		if err == nil {
+			err = fmt.Errorf("tieredcache: memcached: unexpected reply %q", line)
+		}
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func (m *Memcached) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	exp := int64(0)
+	if ttl > 0 {
+		exp = int64((ttl + time.Second - 1) / time.Second)
+		if ttl > maxRelativeExpiry {
+			exp = time.Now().Add(ttl).Unix()
+		}
+	}
+	return m.command(ctx, key, fmt.Sprintf("set %s 0 %d %d\r\n%s\r\n", key, exp, len(value), value), "STORED")
+}
+
+func (m *Memcached) Del(ctx context.Context, key string) error {
+	return m.command(ctx, key, fmt.Sprintf("delete %s\r\n", key), "DELETED", "NOT_FOUND")
+}
+
+// Close closes the idle connections
+func (m *Memcached) Close() {
+	m.pool.close()
+}
+
+// command sends req and expects one of the single line replies ok
+func (m *Memcached) command(ctx context.Context, key, req string, ok ...string) (err error) {
+	if !validMemcachedKey(key) {
+		return errBadKey
+	}
+	cn, err := m.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { m.pool.put(cn, err != nil) }()
+
+	if _, err := cn.w.WriteString(req); err != nil {
+		return err
+	}
+	if err := cn.w.Flush(); err != nil {
+		return err
+	}
+
+	line, err := readLine(cn)
+	if err != nil {
+		return err
+	}
+	for _, o := range ok {
+		if line == o {
+			return nil
+		}
+	}
+	return fmt.Errorf("tieredcache: memcached: %s", line)
+}
+
+func readLine(cn *conn) (string, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func validMemcachedKey(key string) bool {
+	if key == "" || len(key) > 250 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+var _ RemoteCache = (*Memcached)(nil)
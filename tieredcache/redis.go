@@ -0,0 +1,118 @@
+package tieredcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redis is a RemoteCache speaking the redis protocol to one server, with
+// GET, SET with PX and DEL. It is safe for concurrent use
+type Redis struct {
+	pool *connPool
+}
+
+// NewRedis creates a client for the server at addr keeping up to maxIdle
+// idle connections
+func NewRedis(addr string, maxIdle int, dialTimeout time.Duration) *Redis {
+	return &Redis{pool: newConnPool(addr, maxIdle, dialTimeout)}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrMiss
+	}
+	return reply, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		ms := ttl.Milliseconds()
+		if ms == 0 {
+			ms = 1
+		}
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+func (r *Redis) Del(ctx context.Context, key string) error {
+	_, err := r.do(ctx, "DEL", key)
+	return err
+}
+
+// Close closes the idle connections
+func (r *Redis) Close() {
+	r.pool.close()
+}
+
+// do sends one command and returns its reply, nil for a nil bulk string
+func (r *Redis) do(ctx context.Context, args ...string) (reply []byte, err error) {
+	cn, err := r.pool.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		var replyErr redisError
+		r.pool.put(cn, err != nil && !errors.As(err, &replyErr))
+	}()
+
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(cn.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return readRESP(cn)
+}
+
+// redisError is an error reply, the connection stays usable after it
+type redisError string
+
+func (e redisError) Error() string { return "tieredcache: redis: " + string(e) }
+
+func readRESP(cn *conn) ([]byte, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("tieredcache: redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("tieredcache: redis: bad bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+	return nil, fmt.Errorf("tieredcache: redis: unexpected reply %q", line)
+}
+
+var _ RemoteCache = (*Redis)(nil)
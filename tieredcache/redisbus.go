@@ -0,0 +1,175 @@
+package tieredcache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisResubscribeDelay is the wait between attempts to restore a lost
+// subscription
+const redisResubscribeDelay = time.Second
+
+// RedisBus is a Bus over a redis pub/sub channel. Every subscription holds
+// its own connection and reconnects when it is lost, invalidations published
+// while it is down are missed, so the local ttl bounds how stale a value can
+// get. It is safe for concurrent use
+type RedisBus struct {
+	client  *Redis
+	channel string
+}
+
+// NewRedisBus creates a bus on channel of the server at addr
+func NewRedisBus(addr, channel string, dialTimeout time.Duration) *RedisBus {
+	return &RedisBus{client: NewRedis(addr, 1, dialTimeout), channel: channel}
+}
+
+func (b *RedisBus) Publish(ctx context.Context, inv Invalidation) error {
+	_, err := b.client.do(ctx, "PUBLISH", b.channel, inv.encode())
+	return err
+}
+
+// Subscribe fails if the first connection cannot be made, later ones are
+// retried until cancel
+func (b *RedisBus) Subscribe(fn func(Invalidation)) (func(), error) {
+	cn, err := b.subscribe()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &redisSub{cn: cn, stop: make(chan struct{}), done: make(chan struct{})}
+	go s.run(b, fn)
+	return s.cancel, nil
+}
+
+// Close closes the idle publishing connections
+func (b *RedisBus) Close() {
+	b.client.Close()
+}
+
+// subscribe dials a connection and subscribes it to the channel
+func (b *RedisBus) subscribe() (*conn, error) {
+	ctx := context.Background()
+	if b.client.pool.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.client.pool.dialTimeout)
+		defer cancel()
+	}
+
+	cn, err := b.client.pool.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(cn.w, "*2\r\n$9\r\nSUBSCRIBE\r\n$%d\r\n%s\r\n", len(b.channel), b.channel)
+	if err := cn.w.Flush(); err != nil {
+		cn.Close()
+		return nil, err
+	}
+	if _, err := readPush(cn); err != nil {
+		cn.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+type redisSub struct {
+	// mu guards cn and closed, cancel closes cn to end a blocked read
+	mu     sync.Mutex
+	cn     *conn
+	closed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (s *redisSub) run(b *RedisBus, fn func(Invalidation)) {
+	defer close(s.done)
+
+	for {
+		s.receive(fn)
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(redisResubscribeDelay):
+			}
+			if cn, err := b.subscribe(); err == nil {
+				s.mu.Lock()
+				if s.closed {
+					s.mu.Unlock()
+					cn.Close()
+					return
+				}
+				s.cn = cn
+				s.mu.Unlock()
+				break
+			}
+		}
+	}
+}
+
+// receive passes messages to fn until the connection fails
+func (s *redisSub) receive(fn func(Invalidation)) {
+	s.mu.Lock()
+	cn := s.cn
+	s.mu.Unlock()
+	defer cn.Close()
+
+	for {
+		push, err := readPush(cn)
+		if err != nil {
+			return
+		}
+		// message <channel> <payload>
+		if len(push) != 3 || push[0] != "message" {
+			continue
+		}
+		if inv, err := decodeInvalidation(push[2]); err == nil {
+			fn(inv)
+		}
+	}
+}
+
+func (s *redisSub) cancel() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.cn.Close()
+		close(s.stop)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+// readPush reads a pub/sub reply, an array of bulk strings
+func readPush(cn *conn) ([]string, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("tieredcache: redis: unexpected reply %q", line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("tieredcache: redis: bad array length %q", line)
+	}
+
+	push := make([]string, n)
+	for i := range push {
+		b, err := readRESP(cn)
+		if err != nil {
+			return nil, err
+		}
+		push[i] = string(b)
+	}
+	return push, nil
+}
+
+var _ Bus = (*RedisBus)(nil)
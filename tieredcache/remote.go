@@ -0,0 +1,99 @@
+package tieredcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrMiss is returned by RemoteCache.Get for a key it does not hold
+var ErrMiss = errors.New("tieredcache: remote miss")
+
+// RemoteCache is the second tier, a cache shared by several processes
+type RemoteCache interface {
+	// Get returns the value of key or ErrMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key for ttl, 0 means no expiry
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Del removes key, removing a missing key is not an error
+	Del(ctx context.Context, key string) error
+}
+
+// connPool keeps idle connections to one server, callers hold a connection
+// for one request at a time
+type connPool struct {
+	addr        string
+	dialTimeout time.Duration
+	idle        chan *conn
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func newConnPool(addr string, maxIdle int, dialTimeout time.Duration) *connPool {
+	if maxIdle <= 0 {
+		maxIdle = 1
+	}
+	return &connPool{addr: addr, dialTimeout: dialTimeout, idle: make(chan *conn, maxIdle)}
+}
+
+// get returns an idle connection or dials a new one, with the deadline of
+// ctx set on it
+func (p *connPool) get(ctx context.Context) (*conn, error) {
+	var cn *conn
+	select {
+	case cn = <-p.idle:
+	default:
+		var err error
+		if cn, err = p.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := cn.SetDeadline(deadline); err != nil {
+		cn.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+// dial opens a new connection that is not taken from the pool
+func (p *connPool) dial(ctx context.Context) (*conn, error) {
+	d := net.Dialer{Timeout: p.dialTimeout}
+	nc, err := d.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}, nil
+}
+
+// put returns cn to the pool, a broken connection is closed instead as it
+// may hold half a reply
+func (p *connPool) put(cn *conn, broken bool) {
+	if broken {
+		cn.Close()
+		return
+	}
+	select {
+	case p.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+func (p *connPool) close() {
+	for {
+		select {
+		case cn := <-p.idle:
+			cn.Close()
+		default:
+			return
+		}
+	}
+}
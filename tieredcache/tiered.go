@@ -0,0 +1,249 @@
+// Package tieredcache puts a lrucache.Cache in front of a remote cache
+// shared by several processes, such as redis or memcached
+package tieredcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jingke11235/lrucache"
+)
+
+// Codec turns values into the bytes stored in the remote cache
+type Codec = lrucache.Codec
+
+// GobCodec stores values with encoding/gob, concrete value types must be
+// registered with gob.Register
+var GobCodec = lrucache.GobCodec
+
+// Cache looks keys up in the local cache first and in the remote one on a
+// miss, remote hits are copied into the local cache
+type Cache struct {
+	local  *lrucache.Cache
+	remote RemoteCache
+
+	codec Codec
+	ttl   time.Duration
+
+	// async writes to remote run on at most cap(sem) goroutines
+	sem     chan struct{}
+	onError func(key string, err error)
+	writes  sync.WaitGroup
+
+	bus         Bus
+	origin      string
+	unsubscribe func()
+
+	// closed is guarded by closeLock so no async write starts once Close
+	// waits for them
+	closeLock sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Option configures a Cache
+type Option func(*Cache)
+
+// WithCodec replaces GobCodec
+func WithCodec(codec Codec) Option {
+	return func(c *Cache) {
+		c.codec = codec
+	}
+}
+
+// WithRemoteTTL sets the ttl of values written to the remote cache, they do
+// not expire by default
+func WithRemoteTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithAsyncWrites makes Set and Remove return once the local cache is
+// updated, the remote write runs on one of up to concurrency goroutines.
+// Its errors go to onError, which may be nil. Close waits for them
+func WithAsyncWrites(concurrency int, onError func(key string, err error)) Option {
+	return func(c *Cache) {
+		if concurrency <= 0 {
+			concurrency = 1
+		}
+		c.sem = make(chan struct{}, concurrency)
+		c.onError = onError
+	}
+}
+
+// WithInvalidation publishes every Set and Remove on bus once the remote
+// tier is written, and drops the local copies of keys other caches on bus
+// publish, so peers reload them from the remote tier. origin names this
+// cache on bus and must be unique across it, a random one is used if it is
+// empty. Invalidations carrying our own origin are ignored
+func WithInvalidation(bus Bus, origin string) Option {
+	return func(c *Cache) {
+		c.bus = bus
+		c.origin = origin
+	}
+}
+
+// New creates a Cache in front of remote, it fails if the bus given by
+// WithInvalidation cannot be subscribed to
+func New(local *lrucache.Cache, remote RemoteCache, opts ...Option) (*Cache, error) {
+	c := &Cache{local: local, remote: remote, codec: GobCodec}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.bus != nil {
+		if c.origin == "" {
+			c.origin = newOrigin()
+		}
+		unsubscribe, err := c.bus.Subscribe(c.invalidated)
+		if err != nil {
+			return nil, err
+		}
+		c.unsubscribe = unsubscribe
+	}
+	return c, nil
+}
+
+// Get returns the value of k from the local cache, or from the remote one
+// which then fills the local cache. It returns lrucache.ErrNotFound if
+// neither has it, and lrucache.ErrClosed once the cache is closed
+func (c *Cache) Get(ctx context.Context, k string) (interface{}, error) {
+	if c.isClosed() {
+		return nil, lrucache.ErrClosed
+	}
+	if v, ok := c.local.Get(k); ok {
+		return v, nil
+	}
+
+	b, err := c.remote.Get(ctx, k)
+	if err == ErrMiss {
+		return nil, lrucache.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.codec.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	c.local.Set(k, v)
+	return v, nil
+}
+
+// Set stores v in both tiers
+func (c *Cache) Set(ctx context.Context, k string, v interface{}) error {
+	if c.isClosed() {
+		return lrucache.ErrClosed
+	}
+	b, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.local.Set(k, v)
+	return c.toRemote(ctx, k, func(ctx context.Context) error {
+		return c.publish(ctx, k, c.remote.Set(ctx, k, b, c.ttl))
+	})
+}
+
+// Remove removes k from both tiers
+func (c *Cache) Remove(ctx context.Context, k string) error {
+	if c.isClosed() {
+		return lrucache.ErrClosed
+	}
+	c.local.Remove(k)
+	return c.toRemote(ctx, k, func(ctx context.Context) error {
+		return c.publish(ctx, k, c.remote.Del(ctx, k))
+	})
+}
+
+// Local returns the local cache
+func (c *Cache) Local() *lrucache.Cache {
+	return c.local
+}
+
+// Origin returns the name of the cache on its invalidation bus
+func (c *Cache) Origin() string {
+	return c.origin
+}
+
+// Close leaves the invalidation bus and waits for the async remote writes,
+// it returns ctx.Err() if ctx is done first. Get, Set and Remove return
+// lrucache.ErrClosed afterwards. The local cache belongs to the caller and
+// is not closed. Later calls return the result of the first one
+func (c *Cache) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		c.closeLock.Lock()
+		c.closed = true
+		c.closeLock.Unlock()
+
+		if c.unsubscribe != nil {
+			c.unsubscribe()
+		}
+		written := make(chan struct{})
+		go func() {
+			c.writes.Wait()
+			close(written)
+		}()
+		select {
+		case <-written:
+		case <-ctx.Done():
+			c.closeErr = ctx.Err()
+		}
+	})
+	return c.closeErr
+}
+
+func (c *Cache) isClosed() bool {
+	c.closeLock.RLock()
+	defer c.closeLock.RUnlock()
+	return c.closed
+}
+
+// publish tells the peers k changed. It runs even if the remote write
+// failed, a peer dropping its copy only costs it a reload
+func (c *Cache) publish(ctx context.Context, k string, err error) error {
+	if c.bus == nil {
+		return err
+	}
+	return errors.Join(err, c.bus.Publish(ctx, Invalidation{Origin: c.origin, Key: k}))
+}
+
+// invalidated drops the local copy of a key a peer changed
+func (c *Cache) invalidated(inv Invalidation) {
+	if inv.Origin != c.origin {
+		c.local.Remove(inv.Key)
+	}
+}
+
+// toRemote runs write now, or on a goroutine with async writes. An async
+// write is detached from the cancellation of ctx
+func (c *Cache) toRemote(ctx context.Context, k string, write func(ctx context.Context) error) error {
+	if c.sem == nil {
+		return write(ctx)
+	}
+
+	c.closeLock.RLock()
+	if c.closed {
+		c.closeLock.RUnlock()
+		return lrucache.ErrClosed
+	}
+	c.writes.Add(1)
+	c.closeLock.RUnlock()
+	c.sem <- struct{}{}
+	go func() {
+		defer func() {
+			<-c.sem
+			c.writes.Done()
+		}()
+		if err := write(context.WithoutCancel(ctx)); err != nil && c.onError != nil {
+			c.onError(k, err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,123 @@
+package tinylfu
+
+import "math/bits"
+
+// maxCount is the saturation value of a sketch counter, counters are kept
+// in a byte but never go beyond 4 bits worth like in the TinyLFU paper
+const maxCount = 15
+
+// sketch is a count-min sketch estimating how often a key hash was seen.
+// Counters are halved every resetAt increments so old popularity fades
+type sketch struct {
+	rows [4][]uint8
+	mask uint64
+
+	additions int
+	resetAt   int
+}
+
+func newSketch(width int) *sketch {
+	if width < 16 {
+		width = 16
+	}
+	width = 1 << bits.Len(uint(width-1))
+
+	s := &sketch{
+		mask:    uint64(width - 1),
+		resetAt: 10 * width,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index spreads h over the row i with a per row multiplier
+func (s *sketch) index(h uint64, i int) uint64 {
+	h ^= h >> 33
+	h *= rowSeeds[i]
+	h ^= h >> 29
+	return h & s.mask
+}
+
+var rowSeeds = [4]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+// increment counts h once, it returns true when the counters were aged
+func (s *sketch) increment(h uint64) bool {
+	for i := range s.rows {
+		if idx := s.index(h, i); s.rows[i][idx] < maxCount {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions < s.resetAt {
+		return false
+	}
+
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+	return true
+}
+
+func (s *sketch) estimate(h uint64) int {
+	n := maxCount
+	for i := range s.rows {
+		n = min(n, int(s.rows[i][s.index(h, i)]))
+	}
+	return n
+}
+
+func (s *sketch) clear() {
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+	s.additions = 0
+}
+
+// doorkeeper is a bloom filter holding hashes seen once since the last
+// aging, so one-hit wonders never reach the sketch
+type doorkeeper struct {
+	bits []uint64
+	mask uint64
+}
+
+func newDoorkeeper(width int) *doorkeeper {
+	if width < 64 {
+		width = 64
+	}
+	width = 1 << bits.Len(uint(width-1))
+
+	return &doorkeeper{
+		bits: make([]uint64, width/64),
+		mask: uint64(width - 1),
+	}
+}
+
+func (d *doorkeeper) positions(h uint64) (uint64, uint64) {
+	return h & d.mask, (h >> 32) & d.mask
+}
+
+func (d *doorkeeper) contains(h uint64) bool {
+	a, b := d.positions(h)
+	return d.bits[a/64]&(1<<(a%64)) != 0 && d.bits[b/64]&(1<<(b%64)) != 0
+}
+
+// add records h and reports whether it was already there
+func (d *doorkeeper) add(h uint64) bool {
+	if d.contains(h) {
+		return true
+	}
+	a, b := d.positions(h)
+	d.bits[a/64] |= 1 << (a % 64)
+	d.bits[b/64] |= 1 << (b % 64)
+	return false
+}
+
+func (d *doorkeeper) clear() {
+	clear(d.bits)
+}
@@ -0,0 +1,298 @@
+// Package tinylfu implements a thread safe W-TinyLFU cache: a small LRU
+// window in front of a main LRU guarded by a TinyLFU admission policy
+package tinylfu
+
+import (
+	"errors"
+	"hash/maphash"
+	"sync"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// DefaultWindowRatio is the share of the size given to the window LRU
+const DefaultWindowRatio = 0.01
+
+// minSketchWidth keeps the default sketch of small caches from saturating
+// as soon as more distinct keys than the size go through
+const minSketchWidth = 1024
+
+// TinyLFU admits new entries into the window LRU unconditionally. An entry
+// pushed out of the window only enters the main LRU if its estimated
+// frequency beats the entry main would have to evict, so cold one-shot keys
+// pass through the window without displacing hot entries
+type TinyLFU struct {
+	lock sync.Mutex
+
+	size        int
+	windowSize  int
+	windowRatio float64
+
+	window *simplelru.LRU
+	main   *simplelru.LRU
+
+	seed   maphash.Seed
+	sketch *sketch
+	door   *doorkeeper
+
+	onEvicted simplelru.EvictCallback
+}
+
+func New(size int, onEvict simplelru.EvictCallback) (*TinyLFU, error) {
+	return NewWithParams(size, DefaultWindowRatio, 0, onEvict)
+}
+
+// NewWithParams creates a cache with the given window share of size and
+// sketch width, the number of counters per sketch row. The width should be
+// around the number of distinct keys in use, 0 sizes it after the cache with
+// a floor of 1024
+func NewWithParams(size int, windowRatio float64, sketchWidth int, onEvict simplelru.EvictCallback) (*TinyLFU, error) {
+	if size <= 0 {
+		return nil, errors.New("tinylfu: size must be positive")
+	}
+	if windowRatio < 0 || windowRatio >= 1 {
+		return nil, errors.New("tinylfu: window ratio must be within [0, 1)")
+	}
+	if sketchWidth <= 0 {
+		sketchWidth = max(size, minSketchWidth)
+	}
+	if onEvict == nil {
+		onEvict = func(k, v interface{}) {}
+	}
+
+	c := &TinyLFU{
+		windowRatio: windowRatio,
+		seed:        maphash.MakeSeed(),
+		sketch:      newSketch(sketchWidth),
+		door:        newDoorkeeper(sketchWidth),
+		onEvicted:   onEvict,
+	}
+	c.window, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.main, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.setSize(size)
+
+	return c, nil
+}
+
+func (c *TinyLFU) setSize(size int) {
+	c.size = size
+	c.windowSize = int(float64(size) * c.windowRatio)
+	if c.windowSize < 1 && size > 1 {
+		c.windowSize = 1
+	}
+}
+
+func (c *TinyLFU) mainSize() int {
+	return c.size - c.windowSize
+}
+
+// record counts an access to k, keys go through the doorkeeper first
+func (c *TinyLFU) record(k interface{}) {
+	h := maphash.Comparable(c.seed, k)
+	if !c.door.add(h) {
+		return
+	}
+	if c.sketch.increment(h) {
+		c.door.clear()
+	}
+}
+
+func (c *TinyLFU) frequency(k interface{}) int {
+	h := maphash.Comparable(c.seed, k)
+	n := c.sketch.estimate(h)
+	if c.door.contains(h) {
+		n++
+	}
+	return n
+}
+
+func (c *TinyLFU) Set(k, v interface{}) {
+	if k == nil || v == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.record(k)
+
+	if c.main.Contains(k) {
+		c.main.Set(k, v)
+		return
+	}
+
+	c.window.Set(k, v)
+	c.shrinkWindow()
+}
+
+// shrinkWindow moves entries out of the window until it fits, offering each
+// of them to main
+func (c *TinyLFU) shrinkWindow() {
+	for c.window.Len() > c.windowSize {
+		k, v, _ := c.window.RemoveOldest()
+		c.admit(k, v)
+	}
+}
+
+// admit adds a candidate leaving the window to main, or drops it if main is
+// full and its victim is at least as popular
+func (c *TinyLFU) admit(k, v interface{}) {
+	if c.main.Len() < c.mainSize() {
+		c.main.Set(k, v)
+		return
+	}
+
+	vk, vv, ok := c.main.GetOldest()
+	if !ok || c.frequency(k) <= c.frequency(vk) {
+		c.onEvicted(k, v)
+		return
+	}
+
+	c.main.Remove(vk)
+	c.onEvicted(vk, vv)
+	c.main.Set(k, v)
+}
+
+func (c *TinyLFU) Get(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.record(k)
+
+	if v, ok := c.window.Get(k); ok {
+		return v, true
+	}
+	return c.main.Get(k)
+}
+
+func (c *TinyLFU) Contains(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.window.Contains(k) || c.main.Contains(k)
+}
+
+// Peek get a cache without recording an access
+func (c *TinyLFU) Peek(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if v, ok := c.window.Peek(k); ok {
+		return v, true
+	}
+	return c.main.Peek(k)
+}
+
+func (c *TinyLFU) Remove(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, l := range []*simplelru.LRU{c.window, c.main} {
+		if v, ok := l.Peek(k); ok {
+			l.Remove(k)
+			c.onEvicted(k, v)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOldest removes the oldest entry of main, or of the window if main is
+// empty
+func (c *TinyLFU) RemoveOldest() (k, v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.removeOldest()
+}
+
+func (c *TinyLFU) removeOldest() (k, v interface{}, ok bool) {
+	l := c.main
+	if l.Len() == 0 {
+		l = c.window
+	}
+
+	if k, v, ok = l.RemoveOldest(); ok {
+		c.onEvicted(k, v)
+	}
+	return
+}
+
+// RemoveOldestN removes up to n entries in RemoveOldest order
+func (c *TinyLFU) RemoveOldestN(n int) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	removed := 0
+	for ; removed < n; removed++ {
+		if _, _, ok := c.removeOldest(); !ok {
+			break
+		}
+	}
+	return removed
+}
+
+func (c *TinyLFU) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.window.Len() + c.main.Len()
+}
+
+// IsFull reports whether the cache holds as many entries as its size allows
+func (c *TinyLFU) IsFull() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.window.Len()+c.main.Len() >= c.size
+}
+
+// Cap returns the size limit of the cache
+func (c *TinyLFU) Cap() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.size
+}
+
+// Keys returns the keys of main then of the window, each from oldest to
+// newest
+func (c *TinyLFU) Keys() []interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.window.AppendKeys(c.main.Keys())
+}
+
+// Purge removes all entries and forgets the recorded frequencies
+func (c *TinyLFU) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, l := range []*simplelru.LRU{c.window, c.main} {
+		for _, k := range l.Keys() {
+			v, _ := l.Peek(k)
+			c.onEvicted(k, v)
+		}
+		l.Purge()
+	}
+	c.sketch.clear()
+	c.door.clear()
+}
+
+// Resize changes the size limit, it returns how many entries were evicted.
+// Non positive sizes are ignored
+func (c *TinyLFU) Resize(size int) int {
+	if size <= 0 {
+		return 0
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	before := c.window.Len() + c.main.Len()
+
+	c.setSize(size)
+	for c.main.Len() > c.mainSize() {
+		c.removeOldest()
+	}
+	c.shrinkWindow()
+
+	return before - c.window.Len() - c.main.Len()
+}
+
+var _ simplelru.LRUCache = (*TinyLFU)(nil)
@@ -0,0 +1,281 @@
+// Package twoqueue implements a thread safe 2Q cache
+package twoqueue
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+const (
+	// DefaultRecentRatio is the share of the size given to recently added
+	// entries (Kin)
+	DefaultRecentRatio = 0.25
+
+	// DefaultGhostRatio is the share of the size used to remember keys
+	// evicted from the recent queue (Kout)
+	DefaultGhostRatio = 0.50
+)
+
+// TwoQueue keeps new entries in a recent FIFO-like queue (Kin). Keys pushed
+// out of it are remembered in a ghost queue (Kout) and are promoted to the
+// frequent queue (Km) when added again, so a one-time scan only churns Kin
+type TwoQueue struct {
+	lock sync.Mutex
+
+	size       int
+	recentSize int
+
+	recentRatio float64
+	ghostRatio  float64
+
+	recent      *simplelru.LRU
+	frequent    *simplelru.LRU
+	recentEvict *simplelru.LRU
+
+	onEvicted simplelru.EvictCallback
+}
+
+// ghost is stored as the value of keys in the ghost queue
+var ghost = struct{}{}
+
+func New(size int, onEvict simplelru.EvictCallback) (*TwoQueue, error) {
+	return NewWithParams(size, DefaultRecentRatio, DefaultGhostRatio, onEvict)
+}
+
+// NewWithParams creates a cache with the given shares of size for the recent
+// and ghost queues, both must be within [0, 1]
+func NewWithParams(size int, recentRatio, ghostRatio float64, onEvict simplelru.EvictCallback) (*TwoQueue, error) {
+	if size <= 0 {
+		return nil, errors.New("twoqueue: size must be positive")
+	}
+	if recentRatio < 0 || recentRatio > 1 {
+		return nil, errors.New("twoqueue: recent ratio must be within [0, 1]")
+	}
+	if ghostRatio < 0 || ghostRatio > 1 {
+		return nil, errors.New("twoqueue: ghost ratio must be within [0, 1]")
+	}
+	if onEvict == nil {
+		onEvict = func(k, v interface{}) {}
+	}
+
+	c := &TwoQueue{
+		recentRatio: recentRatio,
+		ghostRatio:  ghostRatio,
+		onEvicted:   onEvict,
+	}
+	c.recent, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.frequent, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.recentEvict, _ = simplelru.NewLRU(simplelru.NoLimitSize, simplelru.NoLimitTTL, nil)
+	c.setSize(size)
+
+	return c, nil
+}
+
+func (c *TwoQueue) setSize(size int) {
+	c.size = size
+	c.recentSize = int(float64(size) * c.recentRatio)
+	c.recentEvict.Resize(max(int(float64(size)*c.ghostRatio), 1))
+}
+
+func (c *TwoQueue) Set(k, v interface{}) {
+	if k == nil || v == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.frequent.Contains(k) {
+		c.frequent.Set(k, v)
+		return
+	}
+
+	// seen once before, it is now frequent
+	if c.recent.Contains(k) {
+		c.recent.Remove(k)
+		c.frequent.Set(k, v)
+		return
+	}
+
+	// pushed out of the recent queue not long ago
+	if c.recentEvict.Contains(k) {
+		c.ensureSpace(true)
+		c.recentEvict.Remove(k)
+		c.frequent.Set(k, v)
+		return
+	}
+
+	c.ensureSpace(false)
+	c.recent.Set(k, v)
+}
+
+// ensureSpace evicts one entry if the cache is full, from the recent queue
+// when it is over its share and from the frequent one otherwise
+func (c *TwoQueue) ensureSpace(recentEvict bool) {
+	recentLen, frequentLen := c.recent.Len(), c.frequent.Len()
+	if recentLen+frequentLen < c.size {
+		return
+	}
+
+	if recentLen > 0 && (recentLen > c.recentSize || (recentLen == c.recentSize && !recentEvict)) {
+		if k, v, ok := c.recent.RemoveOldest(); ok {
+			c.recentEvict.Set(k, ghost)
+			c.onEvicted(k, v)
+		}
+		return
+	}
+
+	if k, v, ok := c.frequent.RemoveOldest(); ok {
+		c.onEvicted(k, v)
+	}
+}
+
+func (c *TwoQueue) Get(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if v, ok := c.frequent.Get(k); ok {
+		return v, true
+	}
+
+	if v, ok := c.recent.Peek(k); ok {
+		c.recent.Remove(k)
+		c.frequent.Set(k, v)
+		return v, true
+	}
+
+	return nil, false
+}
+
+func (c *TwoQueue) Contains(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.frequent.Contains(k) || c.recent.Contains(k)
+}
+
+// Peek get a cache without promoting it
+func (c *TwoQueue) Peek(k interface{}) (v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if v, ok := c.frequent.Peek(k); ok {
+		return v, true
+	}
+	return c.recent.Peek(k)
+}
+
+func (c *TwoQueue) Remove(k interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.recentEvict.Remove(k)
+
+	for _, l := range []*simplelru.LRU{c.frequent, c.recent} {
+		if v, ok := l.Peek(k); ok {
+			l.Remove(k)
+			c.onEvicted(k, v)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOldest removes the oldest recent entry, or the oldest frequent one
+// if the recent queue is empty
+func (c *TwoQueue) RemoveOldest() (k, v interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.removeOldest()
+}
+
+func (c *TwoQueue) removeOldest() (k, v interface{}, ok bool) {
+	l := c.recent
+	if l.Len() == 0 {
+		l = c.frequent
+	}
+
+	if k, v, ok = l.RemoveOldest(); ok {
+		c.onEvicted(k, v)
+	}
+	return
+}
+
+// RemoveOldestN removes up to n entries in RemoveOldest order
+func (c *TwoQueue) RemoveOldestN(n int) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	removed := 0
+	for ; removed < n; removed++ {
+		if _, _, ok := c.removeOldest(); !ok {
+			break
+		}
+	}
+	return removed
+}
+
+func (c *TwoQueue) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.recent.Len() + c.frequent.Len()
+}
+
+// IsFull reports whether the cache holds as many entries as its size allows
+func (c *TwoQueue) IsFull() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.recent.Len()+c.frequent.Len() >= c.size
+}
+
+// Cap returns the size limit of the cache
+func (c *TwoQueue) Cap() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.size
+}
+
+// Keys returns the frequent keys then the recent ones, each from oldest to
+// newest
+func (c *TwoQueue) Keys() []interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.recent.AppendKeys(c.frequent.Keys())
+}
+
+func (c *TwoQueue) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, l := range []*simplelru.LRU{c.recent, c.frequent} {
+		for _, k := range l.Keys() {
+			v, _ := l.Peek(k)
+			c.onEvicted(k, v)
+		}
+		l.Purge()
+	}
+	c.recentEvict.Purge()
+}
+
+// Resize changes the size limit and the queue shares derived from it, it
+// returns how many entries were evicted. Non positive sizes are ignored
+func (c *TwoQueue) Resize(size int) int {
+	if size <= 0 {
+		return 0
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.setSize(size)
+
+	evicted := 0
+	for c.recent.Len()+c.frequent.Len() > size {
+		c.ensureSpace(false)
+		evicted++
+	}
+	return evicted
+}
+
+var _ simplelru.LRUCache = (*TwoQueue)(nil)
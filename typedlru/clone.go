@@ -0,0 +1,64 @@
+package typedlru
+
+import "maps"
+
+// Clone returns an independent copy of the cache: its entries with their
+// recency, ttl, pins, priorities and tags, its settings and its counters.
+// Values are copied as is, so the two caches share what they point to. The
+// callbacks and the event hook are shared too, replace them on the clone
+// when they belong to the original. The context watch is not, see
+// SetContextWatch
+func (c *LRU[K, V]) Clone() *LRU[K, V] {
+	n := *c
+
+	n.cache = maps.Clone(c.cache)
+	n.evictList.nodes = append([]node[entry[K, V]](nil), c.evictList.nodes...)
+	for i := range n.evictList.nodes {
+		e := &n.evictList.nodes[i].e
+		if e.refs != nil {
+			e.refs = append(make([]int64, 0, cap(e.refs)), e.refs...)
+		}
+		// the context watches stay with c
+		e.stopWatch = nil
+	}
+	n.ctxWatch = nil
+	n.bands = maps.Clone(c.bands)
+	if c.tagIndex != nil {
+		n.tagIndex = make(map[string]map[K]struct{}, len(c.tagIndex))
+		for tag, keys := range c.tagIndex {
+			n.tagIndex[tag] = maps.Clone(keys)
+		}
+	}
+	if c.tagStats != nil {
+		n.tagStats = make(map[string]*Stats, len(c.tagStats))
+		for tag, st := range c.tagStats {
+			cp := *st
+			n.tagStats[tag] = &cp
+		}
+	}
+	n.kheap = append([]int32(nil), c.kheap...)
+	if c.expiry != nil {
+		n.expiry = &expiryQueue[K]{
+			items:   append([]expiryItem[K](nil), c.expiry.items...),
+			ctxKeys: maps.Clone(c.expiry.ctxKeys),
+		}
+	}
+	if c.ghost != nil {
+		n.ghost = &ghostList[K]{
+			ring: append([]K(nil), c.ghost.ring...),
+			seq:  c.ghost.seq,
+			keys: maps.Clone(c.ghost.keys),
+			hits: append([]uint64(nil), c.ghost.hits...),
+		}
+	}
+	return &n
+}
+
+// SetEvictCallback replaces the eviction callback given to NewLRU, a nil fn
+// removes it
+func (c *LRU[K, V]) SetEvictCallback(fn EvictCallback[K, V]) {
+	if fn == nil {
+		fn = func(K, V) {}
+	}
+	c.onEvicted = fn
+}
@@ -0,0 +1,64 @@
+package typedlru
+
+// NoLimitCost disables the cost limit
+const NoLimitCost = 0
+
+// SetMaxCost bounds the cache by the total cost of its entries in addition to
+// its size, evicting the oldest entries until the total fits. cost weighs an
+// entry when it is set, a nil cost counts every entry as 1. Entries already in
+// the cache keep their cost. It returns how many entries were evicted
+func (c *LRU[K, V]) SetMaxCost(maxCost int64, cost func(k K, v V) int64) int {
+	if maxCost <= NoLimitCost {
+		maxCost = NoLimitCost
+	}
+	c.maxCost = maxCost
+	c.cost = cost
+	c.costIsBytes = false
+
+	evicted := 0
+	for c.maxCost != NoLimitCost && c.totalCost > c.maxCost && c.removeOldest() {
+		evicted++
+	}
+	return evicted
+}
+
+// SetWithCost adds an entry with the given cost instead of the one computed
+// by the cost function, a cost of 0 falls back to the cost function. An entry
+// costing more than the limit evicts everything, itself included
+func (c *LRU[K, V]) SetWithCost(k K, v V, cost int64) {
+	c.set(&entry[K, V]{key: k, value: v, cost: cost})
+}
+
+// ShedCost removes entries in the order capacity eviction takes them,
+// reporting EvictReasonPressure, until their cost adds up to at least cost.
+// It returns how many were removed, pinned entries are kept
+func (c *LRU[K, V]) ShedCost(cost int64) int {
+	target := c.totalCost - cost
+	removed := 0
+	for c.totalCost > target {
+		item := c.victim()
+		if item == 0 {
+			break
+		}
+		c.removeElement(item, EvictReasonPressure)
+		removed++
+	}
+	return removed
+}
+
+// Cost returns the total cost of the entries in the cache
+func (c *LRU[K, V]) Cost() int64 {
+	return c.totalCost
+}
+
+// MaxCost returns the cost limit of the cache, NoLimitCost if unbounded
+func (c *LRU[K, V]) MaxCost() int64 {
+	return c.maxCost
+}
+
+func (c *LRU[K, V]) entryCost(k K, v V) int64 {
+	if c.cost == nil {
+		return 1
+	}
+	return c.cost(k, v)
+}
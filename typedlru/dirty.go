@@ -0,0 +1,45 @@
+package typedlru
+
+// SetDirtyTracking makes every Set mark its entry dirty until MarkClean,
+// for caches written back to a store by the caller, see RangeDirty. Turning
+// it off leaves the entries marked so far dirty
+func (c *LRU[K, V]) SetDirtyTracking(on bool) {
+	c.trackDirty = on
+}
+
+// RangeDirty calls fn for each dirty entry, expired ones included, least
+// recently used first, until fn returns false. version identifies the Set
+// that made the entry dirty, to pass to MarkClean. fn must not change the
+// cache
+func (c *LRU[K, V]) RangeDirty(fn func(k K, v V, version uint64) bool) {
+	for item := c.evictList.back(); item != 0; item = c.evictList.prev(item) {
+		e := c.evictList.at(item)
+		if e.dirty != 0 && !fn(e.key, e.value, e.dirty) {
+			return
+		}
+	}
+}
+
+// MarkClean clears the dirty mark of k if it was made by the Set version
+// identifies, so an entry set again since stays dirty, a version of 0
+// clears it whatever Set made it. It reports whether k was marked clean
+func (c *LRU[K, V]) MarkClean(k K, version uint64) bool {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok {
+		return false
+	}
+	e := c.evictList.at(item)
+	if e.dirty == 0 || (version != 0 && e.dirty != version) {
+		return false
+	}
+	e.dirty = 0
+	return true
+}
+
+// IsDirty reports whether k is in the cache, expired or not, and dirty
+func (c *LRU[K, V]) IsDirty(k K) bool {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	return ok && c.evictList.at(item).dirty != 0
+}
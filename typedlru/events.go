@@ -0,0 +1,55 @@
+package typedlru
+
+// EventKind tells what an Event reports
+type EventKind int
+
+const (
+	// EventSet is an accepted Set, insert or update
+	EventSet EventKind = iota
+	// EventHit is a read that found a live entry
+	EventHit
+	// EventMiss is a read that found no live entry
+	EventMiss
+	// EventEvict is an entry removed for any reason but expiry
+	EventEvict
+	// EventExpire is an expired entry removed from the cache
+	EventExpire
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventSet:
+		return "set"
+	case EventHit:
+		return "hit"
+	case EventMiss:
+		return "miss"
+	case EventEvict:
+		return "evict"
+	case EventExpire:
+		return "expire"
+	}
+	return "unknown"
+}
+
+// Event is one step of cache activity. Value is the zero value for hits
+// and misses, Reason is only meaningful for EventEvict and EventExpire
+type Event[K comparable, V any] struct {
+	Kind   EventKind
+	Key    K
+	Value  V
+	Reason EvictReason
+}
+
+// SetEventHook sets a function called for every Event, in the goroutine
+// that caused it. It must not block nor change the cache, a nil fn removes
+// it
+func (c *LRU[K, V]) SetEventHook(fn func(Event[K, V])) {
+	c.eventHook = fn
+}
+
+func (c *LRU[K, V]) emit(kind EventKind, k K, v V, reason EvictReason) {
+	if c.eventHook != nil {
+		c.eventHook(Event[K, V]{Kind: kind, Key: k, Value: v, Reason: reason})
+	}
+}
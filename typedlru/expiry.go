@@ -0,0 +1,184 @@
+package typedlru
+
+// expiryQueue orders entries by expiry so EvictExpired visits the due ones
+// only, see SetExpiryHeap. The heap is lazy: an entry is queued at its
+// earliest known expiry and checked again when that time comes, so reads
+// that push the expiry back, sliding ones included, cost nothing. Items of
+// removed entries are dropped as they come up
+type expiryQueue[K comparable] struct {
+	items []expiryItem[K]
+
+	// ctxKeys holds the entries scoped by a context, which may be done at
+	// any time and are checked on every EvictExpired
+	ctxKeys map[K]struct{}
+}
+
+type expiryItem[K comparable] struct {
+	at  int64
+	key K
+}
+
+// SetExpiryHeap makes the cache track expiry times in a min-heap, so
+// EvictExpired removes the due entries in O(expired log n) instead of
+// walking every entry. It costs a heap push per Set and about 8 bytes
+// besides the key per entry, which pays off for large caches with a ttl
+// reaped by a janitor
+func (c *LRU[K, V]) SetExpiryHeap(enabled bool) {
+	if !enabled {
+		c.expiry = nil
+		return
+	}
+	c.expiry = &expiryQueue[K]{}
+	c.rebuildExpiry()
+}
+
+// rebuildExpiry queues every entry again, after the cache ttl or max
+// lifetime changed or when stale items make up most of the heap
+func (c *LRU[K, V]) rebuildExpiry() {
+	q := c.expiry
+	q.items = q.items[:0]
+	q.ctxKeys = nil
+	for k, item := range c.cache {
+		e := c.evictList.at(item)
+		e.queued = 0
+		c.trackContext(e)
+		if at, ok := c.queueAt(e); ok {
+			e.queued = at
+			q.items = append(q.items, expiryItem[K]{at: at, key: k})
+		}
+	}
+	for i := len(q.items)/2 - 1; i >= 0; i-- {
+		q.down(i)
+	}
+}
+
+// queueAt returns when e should be checked for expiry, ok is false for
+// entries that only a context or Unpin can expire
+func (c *LRU[K, V]) queueAt(e *entry[K, V]) (at int64, ok bool) {
+	if e.pinned {
+		return 0, false
+	}
+	t, ok := c.expiresAt(e)
+	if !ok {
+		return 0, false
+	}
+	return t.Add(c.grace).UnixNano(), true
+}
+
+// queueExpiry queues the entry of slot item unless it is already queued at
+// an earlier time
+func (c *LRU[K, V]) queueExpiry(item int32) {
+	q := c.expiry
+	if q == nil {
+		return
+	}
+	e := c.evictList.at(item)
+	at, ok := c.queueAt(e)
+	if !ok || (e.queued != 0 && e.queued <= at) {
+		return
+	}
+	e.queued = at
+	q.push(expiryItem[K]{at: at, key: e.key})
+
+	if len(q.items) > 2*len(c.cache)+64 {
+		c.rebuildExpiry()
+	}
+}
+
+// trackContext adds or removes e from the context scoped entries
+func (c *LRU[K, V]) trackContext(e *entry[K, V]) {
+	q := c.expiry
+	if q == nil {
+		return
+	}
+	if e.ctx == nil {
+		delete(q.ctxKeys, e.key)
+		return
+	}
+	if q.ctxKeys == nil {
+		q.ctxKeys = make(map[K]struct{})
+	}
+	q.ctxKeys[e.key] = struct{}{}
+}
+
+// evictQueued is EvictExpired with an expiry heap
+func (c *LRU[K, V]) evictQueued() int {
+	q := c.expiry
+	now := c.clock.Now().UnixNano()
+	n := 0
+
+	for len(q.items) > 0 && q.items[0].at < now {
+		it := q.pop()
+		item, ok := c.cache[it.key]
+		if !ok {
+			continue
+		}
+		e := c.evictList.at(item)
+		if e.queued != it.at {
+			// queued again at an earlier time, that item was handled
+			continue
+		}
+		e.queued = 0
+		if c.reclaimable(it.key) {
+			c.removeElement(item, EvictReasonExpired)
+			n++
+			continue
+		}
+		// the expiry moved past it.at
+		c.queueExpiry(item)
+	}
+
+	for k := range q.ctxKeys {
+		if c.reclaimable(k) {
+			c.removeElement(c.cache[k], EvictReasonExpired)
+			n++
+		}
+	}
+	return n
+}
+
+func (q *expiryQueue[K]) push(it expiryItem[K]) {
+	q.items = append(q.items, it)
+	q.up(len(q.items) - 1)
+}
+
+func (q *expiryQueue[K]) pop() expiryItem[K] {
+	it := q.items[0]
+	last := len(q.items) - 1
+	q.items[0] = q.items[last]
+	q.items[last] = expiryItem[K]{}
+	q.items = q.items[:last]
+	if last > 0 {
+		q.down(0)
+	}
+	return it
+}
+
+func (q *expiryQueue[K]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if q.items[parent].at <= q.items[i].at {
+			return
+		}
+		q.items[parent], q.items[i] = q.items[i], q.items[parent]
+		i = parent
+	}
+}
+
+func (q *expiryQueue[K]) down(i int) {
+	n := len(q.items)
+	for {
+		least := i
+		if l := 2*i + 1; l < n && q.items[l].at < q.items[least].at {
+			least = l
+		}
+		if r := 2*i + 2; r < n && q.items[r].at < q.items[least].at {
+			least = r
+		}
+		if least == i {
+			return
+		}
+		q.items[i], q.items[least] = q.items[least], q.items[i]
+		i = least
+	}
+}
@@ -0,0 +1,29 @@
+package typedlru
+
+// NewGeneration invalidates every entry in the cache at once without
+// touching them: entries set before the call, pinned ones included, are
+// treated as expired from then on. They keep their room until a Set replaces
+// them, EvictExpired or EvictOldGenerations removes them or they are evicted.
+// It returns how many entries it invalidated
+func (c *LRU[K, V]) NewGeneration() int {
+	c.gen++
+	c.stale = c.evictList.length()
+	return c.stale
+}
+
+// EvictOldGenerations removes up to max of the entries set before the last
+// NewGeneration, least recently used first, reporting EvictReasonPurged to
+// the eviction reason callback. It returns how many were removed, fewer than
+// max once none is left, so it can be called in batches between other work
+func (c *LRU[K, V]) EvictOldGenerations(max int) int {
+	n := 0
+	for item := c.evictList.back(); item != 0 && n < max && c.stale > 0; {
+		prev := c.evictList.prev(item)
+		if c.evictList.at(item).gen != c.gen {
+			c.removeElement(item, EvictReasonPurged)
+			n++
+		}
+		item = prev
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+package typedlru
+
+// ghostList remembers the keys last evicted for capacity, so misses on them
+// tell how much larger the cache would have had to be to hit, see
+// SetGhostSize
+type ghostList[K comparable] struct {
+	// ring holds the keys in eviction order, seq counts the evictions and
+	// keys maps each key to the eviction that put it in the ring
+	ring []K
+	seq  uint64
+	keys map[K]uint64
+
+	// hits counts the misses on a key by how many evictions ago it left,
+	// hits[d] for d+1
+	hits []uint64
+}
+
+// SetGhostSize keeps the last n keys evicted for capacity, without their
+// values, and counts misses on them in Stats.GhostHits and WouldHit. A key
+// set again leaves the ghost list. A n of 0 or less drops the list
+func (c *LRU[K, V]) SetGhostSize(n int) {
+	if n <= 0 {
+		c.ghost = nil
+		return
+	}
+	c.ghost = &ghostList[K]{
+		ring: make([]K, n),
+		keys: make(map[K]uint64, n),
+		hits: make([]uint64, n),
+	}
+}
+
+// WouldHit returns how many misses a cache holding extra more entries would
+// have hit, counted since SetGhostSize or ResetStats. Extra beyond the ghost
+// size counts as the ghost size. It is an estimate on the low side, a larger
+// cache would also skip the evictions that followed those misses
+func (c *LRU[K, V]) WouldHit(extra int) uint64 {
+	if c.ghost == nil {
+		return 0
+	}
+	n := uint64(0)
+	for _, h := range c.ghost.hits[:min(max(extra, 0), len(c.ghost.hits))] {
+		n += h
+	}
+	return n
+}
+
+func (c *LRU[K, V]) ghostEvicted(k K) {
+	g := c.ghost
+	if g == nil {
+		return
+	}
+	i := g.seq % uint64(len(g.ring))
+	if g.seq >= uint64(len(g.ring)) {
+		old := g.ring[i]
+		if seq, ok := g.keys[old]; ok && seq == g.seq-uint64(len(g.ring)) {
+			delete(g.keys, old)
+		}
+	}
+	g.ring[i] = k
+	g.keys[k] = g.seq
+	g.seq++
+}
+
+func (c *LRU[K, V]) ghostMissed(k K) {
+	g := c.ghost
+	if g == nil {
+		return
+	}
+	if seq, ok := g.keys[k]; ok {
+		c.stats.GhostHits++
+		g.hits[g.seq-seq-1]++
+	}
+}
+
+func (c *LRU[K, V]) ghostAdded(k K) {
+	if g := c.ghost; g != nil {
+		delete(g.keys, k)
+	}
+}
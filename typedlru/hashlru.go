@@ -0,0 +1,317 @@
+package typedlru
+
+import (
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+// Hasher hashes and compares keys that cannot be map keys, such as slices or
+// structs holding slices. Keys that are Equal must have the same Hash
+type Hasher[K any] interface {
+	Hash(k K) uint64
+	Equal(a, b K) bool
+}
+
+// HasherFuncs adapts a pair of functions to the Hasher interface
+type HasherFuncs[K any] struct {
+	HashFunc  func(k K) uint64
+	EqualFunc func(a, b K) bool
+}
+
+func (h HasherFuncs[K]) Hash(k K) uint64   { return h.HashFunc(k) }
+func (h HasherFuncs[K]) Equal(a, b K) bool { return h.EqualFunc(a, b) }
+
+const (
+	slotEmpty uint8 = iota
+	slotUsed
+	slotDeleted
+)
+
+// minSlots is the size of the table of an empty HashLRU
+const minSlots = 8
+
+// slot points at the entry of its key in the list, 0 when not used
+type slot struct {
+	state uint8
+	hash  uint64
+	elem  int32
+}
+
+type hashEntry[K, V any] struct {
+	key       K
+	value     V
+	hash      uint64
+	updatedAt time.Time
+}
+
+// HashLRU is a lru whose keys are looked up by a Hasher in an open
+// addressing table instead of a map, so K need not be comparable. It has
+// the size, ttl and eviction callback of LRU, not its other features
+type HashLRU[K, V any] struct {
+	size int
+	ttl  time.Duration
+
+	hasher Hasher[K]
+
+	// slots is probed linearly from hash & (len(slots)-1), deleted slots
+	// keep probe chains intact until the next rehash
+	slots   []slot
+	used    int
+	deleted int
+
+	evictList entryList[hashEntry[K, V]]
+	onEvicted func(k K, v V)
+
+	clock clock.Clock
+}
+
+func NewHashLRU[K, V any](size int, ttl time.Duration, hasher Hasher[K], onEvict func(k K, v V)) (*HashLRU[K, V], error) {
+	if size <= NoLimitSize {
+		size = NoLimitSize
+	}
+	if ttl <= NoLimitTTL {
+		ttl = NoLimitTTL
+	}
+	if onEvict == nil {
+		onEvict = func(K, V) {}
+	}
+
+	c := &HashLRU[K, V]{
+		size:      size,
+		ttl:       ttl,
+		hasher:    hasher,
+		slots:     make([]slot, minSlots),
+		onEvicted: onEvict,
+		clock:     clock.Real,
+	}
+	c.evictList.init()
+	return c, nil
+}
+
+// SetClock replaces the clock used for ttl expiry, for tests
+func (c *HashLRU[K, V]) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// mix spreads user hashes that only vary in a few bits over the table
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// find returns the slot holding k, or -1 and the slot a new k goes to
+func (c *HashLRU[K, V]) find(k K, h uint64) (found, free int) {
+	mask := uint64(len(c.slots) - 1)
+	free = -1
+	for i := h & mask; ; i = (i + 1) & mask {
+		s := &c.slots[i]
+		switch s.state {
+		case slotEmpty:
+			if free < 0 {
+				free = int(i)
+			}
+			return -1, free
+		case slotDeleted:
+			if free < 0 {
+				free = int(i)
+			}
+		case slotUsed:
+			if s.hash == h && c.hasher.Equal(c.evictList.at(s.elem).key, k) {
+				return int(i), free
+			}
+		}
+	}
+}
+
+// grow rehashes into a table twice as big, or the same size when deleted
+// slots make up most of the load
+func (c *HashLRU[K, V]) grow() {
+	n := len(c.slots)
+	if c.used*8 >= n*3 {
+		n *= 2
+	}
+
+	old := c.slots
+	c.slots = make([]slot, n)
+	c.deleted = 0
+	mask := uint64(n - 1)
+	for _, s := range old {
+		if s.state != slotUsed {
+			continue
+		}
+		i := s.hash & mask
+		for c.slots[i].state == slotUsed {
+			i = (i + 1) & mask
+		}
+		c.slots[i] = s
+	}
+}
+
+func (c *HashLRU[K, V]) Set(k K, v V) {
+	h := mix(c.hasher.Hash(k))
+	now := c.clock.Now()
+
+	if i, _ := c.find(k, h); i >= 0 {
+		item := c.slots[i].elem
+		e := c.evictList.at(item)
+		e.value = v
+		e.updatedAt = now
+		c.evictList.moveToFront(item)
+		return
+	}
+
+	// keep the load including deleted slots under 3/4
+	if (c.used+c.deleted+1)*4 > len(c.slots)*3 {
+		c.grow()
+	}
+	_, free := c.find(k, h)
+	if c.slots[free].state == slotDeleted {
+		c.deleted--
+	}
+	item := c.evictList.pushFront(&hashEntry[K, V]{key: k, value: v, hash: h, updatedAt: now})
+	c.slots[free] = slot{state: slotUsed, hash: h, elem: item}
+	c.used++
+
+	if c.size != NoLimitSize && c.used > c.size {
+		c.RemoveOldest()
+	}
+}
+
+func (c *HashLRU[K, V]) Get(k K) (v V, ok bool) {
+	if item := c.live(k); item != 0 {
+		c.evictList.moveToFront(item)
+		return c.evictList.at(item).value, true
+	}
+	return
+}
+
+// Peek get a cache without move it to head
+func (c *HashLRU[K, V]) Peek(k K) (v V, ok bool) {
+	if item := c.live(k); item != 0 {
+		return c.evictList.at(item).value, true
+	}
+	return
+}
+
+func (c *HashLRU[K, V]) Contains(k K) bool {
+	return c.live(k) != 0
+}
+
+// live returns the list slot of k if it is not expired, 0 otherwise
+func (c *HashLRU[K, V]) live(k K) int32 {
+	i, _ := c.find(k, mix(c.hasher.Hash(k)))
+	if i < 0 {
+		return 0
+	}
+	item := c.slots[i].elem
+	if c.expired(c.evictList.at(item)) {
+		return 0
+	}
+	return item
+}
+
+func (c *HashLRU[K, V]) expired(e *hashEntry[K, V]) bool {
+	return c.ttl != NoLimitTTL && c.clock.Now().After(e.updatedAt.Add(c.ttl))
+}
+
+func (c *HashLRU[K, V]) Remove(k K) bool {
+	i, _ := c.find(k, mix(c.hasher.Hash(k)))
+	if i < 0 {
+		return false
+	}
+	c.removeSlot(i)
+	return true
+}
+
+func (c *HashLRU[K, V]) RemoveOldest() (k K, v V, ok bool) {
+	item := c.evictList.back()
+	if item == 0 {
+		return
+	}
+	e := c.evictList.at(item)
+	k, v = e.key, e.value
+	i, _ := c.find(k, e.hash)
+	c.removeSlot(i)
+	return k, v, true
+}
+
+func (c *HashLRU[K, V]) removeSlot(i int) {
+	s := &c.slots[i]
+	e := c.evictList.at(s.elem)
+
+	// the list clears the entry on remove
+	k, v := e.key, e.value
+	c.evictList.remove(s.elem)
+
+	*s = slot{state: slotDeleted}
+	c.used--
+	c.deleted++
+
+	c.onEvicted(k, v)
+}
+
+// EvictExpired removes all expired entries, it returns how many were removed
+func (c *HashLRU[K, V]) EvictExpired() int {
+	n := 0
+	for item := c.evictList.back(); item != 0; {
+		prev := c.evictList.prev(item)
+		if e := c.evictList.at(item); c.expired(e) {
+			i, _ := c.find(e.key, e.hash)
+			c.removeSlot(i)
+			n++
+		}
+		item = prev
+	}
+	return n
+}
+
+func (c *HashLRU[K, V]) Len() int {
+	return c.used
+}
+
+func (c *HashLRU[K, V]) Cap() int {
+	return c.size
+}
+
+// Keys returns the keys that are not expired from oldest to newest
+func (c *HashLRU[K, V]) Keys() []K {
+	keys := make([]K, 0, c.used)
+	for item := c.evictList.back(); item != 0; item = c.evictList.prev(item) {
+		if e := c.evictList.at(item); !c.expired(e) {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+func (c *HashLRU[K, V]) Purge() {
+	for item := c.evictList.front(); item != 0; item = c.evictList.next(item) {
+		e := c.evictList.at(item)
+		c.onEvicted(e.key, e.value)
+	}
+	c.evictList.init()
+	c.slots = make([]slot, minSlots)
+	c.used = 0
+	c.deleted = 0
+}
+
+// Resize changes the size limit, it returns the number of entries evicted
+func (c *HashLRU[K, V]) Resize(size int) int {
+	if size <= NoLimitSize {
+		c.size = NoLimitSize
+		return 0
+	}
+	c.size = size
+
+	evicted := 0
+	for c.used > size {
+		c.RemoveOldest()
+		evicted++
+	}
+	return evicted
+}
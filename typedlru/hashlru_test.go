@@ -0,0 +1,151 @@
+package typedlru
+
+import (
+	"hash/maphash"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+var sliceHasher = HasherFuncs[[]int]{
+	HashFunc: func(k []int) uint64 {
+		var h maphash.Hash
+		h.SetSeed(seed)
+		for _, n := range k {
+			maphash.WriteComparable(&h, n)
+		}
+		return h.Sum64()
+	},
+	EqualFunc: slices.Equal[[]int],
+}
+
+var seed = maphash.MakeSeed()
+
+func TestHashLRU(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(c *HashLRU[[]int, int], clk *clock.Fake)
+		// keys are the live keys from oldest to newest, evicted the keys
+		// passed to the eviction callback in order
+		keys    [][]int
+		evicted [][]int
+	}{
+		{
+			name: "set and overwrite",
+			ops: func(c *HashLRU[[]int, int], _ *clock.Fake) {
+				c.Set([]int{1}, 1)
+				c.Set([]int{2}, 2)
+				c.Set([]int{1}, 3)
+			},
+			keys: [][]int{{2}, {1}},
+		},
+		{
+			name: "evicts the oldest",
+			ops: func(c *HashLRU[[]int, int], _ *clock.Fake) {
+				c.Set([]int{1}, 1)
+				c.Set([]int{2}, 2)
+				c.Get([]int{1})
+				c.Set([]int{3}, 3)
+				c.Set([]int{4}, 4)
+			},
+			keys:    [][]int{{1}, {3}, {4}},
+			evicted: [][]int{{2}},
+		},
+		{
+			name: "remove",
+			ops: func(c *HashLRU[[]int, int], _ *clock.Fake) {
+				c.Set([]int{1}, 1)
+				c.Set([]int{1, 2}, 2)
+				c.Remove([]int{1})
+				c.Remove([]int{3})
+			},
+			keys:    [][]int{{1, 2}},
+			evicted: [][]int{{1}},
+		},
+		{
+			name: "expiry",
+			ops: func(c *HashLRU[[]int, int], clk *clock.Fake) {
+				c.Set([]int{1}, 1)
+				clk.Advance(2 * time.Minute)
+				c.Set([]int{2}, 2)
+				c.EvictExpired()
+			},
+			keys:    [][]int{{2}},
+			evicted: [][]int{{1}},
+		},
+		{
+			name: "purge then reuse",
+			ops: func(c *HashLRU[[]int, int], _ *clock.Fake) {
+				c.Set([]int{1}, 1)
+				c.Purge()
+				c.Set([]int{2}, 2)
+			},
+			keys:    [][]int{{2}},
+			evicted: [][]int{{1}},
+		},
+		{
+			name: "many removals keep the table consistent",
+			ops: func(c *HashLRU[[]int, int], _ *clock.Fake) {
+				for i := 0; i < 100; i++ {
+					c.Set([]int{i}, i)
+				}
+			},
+			keys: [][]int{{97}, {98}, {99}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			var evicted [][]int
+			c, err := NewHashLRU[[]int, int](3, time.Minute, sliceHasher, func(k []int, _ int) {
+				evicted = append(evicted, k)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+
+			tt.ops(c, clk)
+			if got := c.Keys(); !slices.EqualFunc(got, tt.keys, slices.Equal[[]int]) {
+				t.Errorf("Keys() = %v, want %v", got, tt.keys)
+			}
+			if c.Len() != len(tt.keys) {
+				t.Errorf("Len() = %d, want %d", c.Len(), len(tt.keys))
+			}
+			for _, k := range tt.keys {
+				if !c.Contains(k) {
+					t.Errorf("Contains(%v) = false", k)
+				}
+			}
+			if tt.evicted != nil && !slices.EqualFunc(evicted, tt.evicted, slices.Equal[[]int]) {
+				t.Errorf("evicted %v, want %v", evicted, tt.evicted)
+			}
+		})
+	}
+}
+
+func TestHashLRUSetAllocs(t *testing.T) {
+	c, err := NewHashLRU[int, int](64, NoLimitTTL, HasherFuncs[int]{
+		HashFunc:  func(k int) uint64 { return uint64(k) },
+		EqualFunc: func(a, b int) bool { return a == b },
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 128; i++ {
+		c.Set(i, i)
+	}
+
+	i := 128
+	// a full cache recycles the slab slot of the entry it evicts
+	allocs := testing.AllocsPerRun(100, func() {
+		c.Set(i, i)
+		i++
+	})
+	if allocs > 0 {
+		t.Errorf("Set on a full cache allocates %v times", allocs)
+	}
+}
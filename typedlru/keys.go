@@ -0,0 +1,74 @@
+package typedlru
+
+// Order is the direction KeysN and RangeOrder walk the cache in
+type Order int
+
+const (
+	// OldestFirst walks from the least to the most recently used entry
+	OldestFirst Order = iota
+	// NewestFirst walks from the most to the least recently used entry
+	NewestFirst
+)
+
+// KeysN returns up to limit keys that are not expired, skipping the first
+// offset of them in the given order, so a large cache can be listed page by
+// page. A limit of 0 or less returns all keys after offset
+func (c *LRU[K, V]) KeysN(offset, limit int, order Order) []K {
+	n := max(len(c.cache)-max(offset, 0), 0)
+	if limit > 0 {
+		n = min(n, limit)
+	}
+	keys := make([]K, 0, n)
+
+	skipped := 0
+	c.RangeOrder(order, func(k K, v V) bool {
+		if skipped < offset {
+			skipped++
+			return true
+		}
+		keys = append(keys, k)
+		return limit <= 0 || len(keys) < limit
+	})
+	return keys
+}
+
+// RangeOrder works like Range in the given order, it lets the entries of a
+// large cache be streamed without copying them
+func (c *LRU[K, V]) RangeOrder(order Order, fn func(k K, v V) bool) {
+	next, item := c.evictList.prev, c.evictList.back()
+	if order == NewestFirst {
+		next, item = c.evictList.next, c.evictList.front()
+	}
+
+	for ; item != 0; item = next(item) {
+		e := c.evictList.at(item)
+		if c.expired(e.key) {
+			continue
+		}
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Values returns the values that are not expired from oldest to newest,
+// without changing recency
+func (c *LRU[K, V]) Values() []V {
+	values := make([]V, 0, len(c.cache))
+	c.RangeOrder(OldestFirst, func(k K, v V) bool {
+		values = append(values, v)
+		return true
+	})
+	return values
+}
+
+// Items returns a copy of the entries that are not expired, without
+// changing recency
+func (c *LRU[K, V]) Items() map[K]V {
+	items := make(map[K]V, len(c.cache))
+	c.RangeOrder(OldestFirst, func(k K, v V) bool {
+		items[k] = v
+		return true
+	})
+	return items
+}
@@ -0,0 +1,1255 @@
+// Package typedlru implements a not thread safe generic lru cache
+package typedlru
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+const (
+	NoLimitSize = 0
+	NoLimitTTL  = 0
+)
+
+type EvictCallback[K comparable, V any] func(k K, v V)
+
+// EvictReasonCallback is an eviction callback that is also told why the
+// entry left the cache, see SetEvictReasonCallback
+type EvictReasonCallback[K comparable, V any] func(k K, v V, reason EvictReason)
+
+// EvictReason tells why an entry left the cache
+type EvictReason int
+
+const (
+	// EvictReasonCapacity is an entry removed as the oldest one, to make
+	// room or on explicit shedding
+	EvictReasonCapacity EvictReason = iota
+	// EvictReasonRemoved is an entry removed by key
+	EvictReasonRemoved
+	// EvictReasonPurged is an entry removed by Purge or
+	// EvictOldGenerations
+	EvictReasonPurged
+	// EvictReasonExpired is an entry removed after its ttl elapsed
+	EvictReasonExpired
+	// EvictReasonReplaced is a value overwritten by a Set of its key, it is
+	// only reported to an EvictReasonCallback
+	EvictReasonReplaced
+	// EvictReasonPressure is an entry shed under memory pressure, see Shed
+	EvictReasonPressure
+	// EvictReasonTaken is an entry handed over to the caller by Pop, it is
+	// only reported to the event hook
+	EvictReasonTaken
+)
+
+// GetResult tells how GetDetailed resolved a key
+type GetResult int
+
+const (
+	// Miss means the key was not in the cache, or expired and kept for its
+	// stale grace, see SetStaleGrace
+	Miss GetResult = iota
+	// Hit means a live entry was found
+	Hit
+	// ExpiredReclaimed means the key was found expired and removed
+	ExpiredReclaimed
+)
+
+type LRU[K comparable, V any] struct {
+	size int
+
+	ttl time.Duration
+
+	cache map[K]int32
+
+	evictList entryList[entry[K, V]]
+
+	onEvicted       EvictCallback[K, V]
+	onEvictedReason EvictReasonCallback[K, V]
+
+	// normalizeKey maps keys to the key they are stored under, see
+	// SetKeyNormalizer
+	normalizeKey func(k K) K
+
+	// onAdd and onUpdate follow inserts and in-place updates, see SetOnAdd
+	onAdd    func(k K, v V)
+	onUpdate func(k K, oldValue, newValue V)
+
+	// ctxWatch is called once the context of an entry is done, see
+	// SetContextWatch
+	ctxWatch func(k K)
+
+	eventHook func(Event[K, V])
+
+	clock clock.Clock
+
+	// cost weighs entries against maxCost, see SetMaxCost
+	cost      func(k K, v V) int64
+	maxCost   int64
+	totalCost int64
+
+	// trackBytes keeps totalBytes, the estimated memory of the entries, see
+	// SetByteTracking. costIsBytes tells that cost estimates it as well
+	trackBytes  bool
+	costIsBytes bool
+	totalBytes  int64
+
+	// bands counts entries per priority, it stays empty while every entry
+	// has the default priority
+	bands map[int]int
+
+	// tagIndex holds the keys carrying each tag
+	tagIndex map[string]map[K]struct{}
+
+	// sliding restarts the ttl of entries on read hits
+	sliding bool
+
+	// fifo keeps read hits from moving entries to head
+	fifo bool
+
+	// policy picks capacity victims, setting is the slot of the entry being
+	// set while set evicts, which PolicyMRU spares
+	policy  EvictionPolicy
+	setting int32
+
+	// gen is the current generation and stale counts the entries set in an
+	// earlier one, see NewGeneration
+	gen   uint64
+	stale int
+
+	// trackDirty marks set entries dirty, writes numbers the sets so a
+	// flush can tell an entry set again since, see SetDirtyTracking
+	trackDirty bool
+	writes     uint64
+
+	// k is the history depth of PolicyLRUK, kheap orders the slots of all
+	// entries by backward k-distance under it
+	k     int
+	kheap []int32
+
+	// maxLifetime bounds entries since creation, whatever their ttl
+	maxLifetime time.Duration
+
+	// jitter is the largest fraction the ttl of an entry is randomly
+	// lengthened or shortened by, see SetTTLJitter
+	jitter float64
+
+	// grace keeps expired entries around to be served stale, see
+	// SetStaleGrace
+	grace time.Duration
+
+	// expiry orders entries by expiry time, see SetExpiryHeap
+	expiry *expiryQueue[K]
+
+	// ghost holds keys recently evicted for capacity, see SetGhostSize
+	ghost *ghostList[K]
+
+	stats Stats
+
+	tagStats map[string]*Stats
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	updatedAt time.Time
+
+	// createdAt is when the key was first set, see SetMaxLifetime
+	createdAt time.Time
+
+	// accesses counts the read hits since the entry was set
+	accesses uint64
+
+	// pinned entries are skipped by capacity eviction and ttl expiry
+	pinned bool
+
+	// priority orders capacity eviction, lower first, see SetWithPriority.
+	// hasPriority tells a SetWithPriority entry from one keeping the
+	// priority of the value it replaces
+	priority    int
+	hasPriority bool
+
+	// tags group entries for InvalidateTag, hasTags tells a SetWithTags
+	// entry from one keeping the tags of the value it replaces
+	tags    []string
+	hasTags bool
+
+	// ttl overrides the cache ttl for this entry when set
+	ttl time.Duration
+
+	// jitter scales the ttl of this entry by 1+jitter, see SetTTLJitter
+	jitter float64
+
+	// ctx scopes the entry, it is treated as expired once ctx is done.
+	// stopWatch cancels the context.AfterFunc of SetContextWatch
+	ctx       context.Context
+	stopWatch func() bool
+
+	cost int64
+
+	// bytes is the estimated memory of the entry, see SetByteTracking
+	bytes int64
+
+	// refs holds the last references newest first under PolicyLRUK, kpos
+	// is the position in the history heap plus one, 0 if not in it
+	refs []int64
+	kpos int32
+
+	// queued is the expiry time in unix nanoseconds the entry is queued at
+	// in the expiry heap, 0 if it is not
+	queued int64
+
+	// gen is the generation the entry was set in
+	gen uint64
+
+	// dirty is the number of the set that made the entry dirty, 0 if it is
+	// clean
+	dirty uint64
+}
+
+func NewLRU[K comparable, V any](size int, ttl time.Duration, onEvict EvictCallback[K, V]) (*LRU[K, V], error) {
+
+	if size <= NoLimitSize {
+		size = NoLimitSize
+	}
+	if ttl <= NoLimitTTL {
+		ttl = NoLimitTTL
+	}
+	if onEvict == nil {
+		onEvict = func(K, V) {}
+	}
+
+	c := &LRU[K, V]{
+		size:      size,
+		ttl:       ttl,
+		cache:     make(map[K]int32),
+		onEvicted: onEvict,
+		clock:     clock.Real,
+	}
+	c.evictList.init()
+	return c, nil
+}
+
+// Add if not exit - if exited update
+func (c *LRU[K, V]) Set(k K, v V) {
+	c.SetX(k, v)
+}
+
+// SetX works like Set and returns the entry evicted to make room, if any.
+// When several entries are evicted to fit a cost limit the oldest is returned
+func (c *LRU[K, V]) SetX(k K, v V) (evictedKey K, evictedValue V, evicted bool) {
+	return c.set(&entry[K, V]{key: k, value: v})
+}
+
+// Add works like Set and reports whether an entry was evicted to make room
+func (c *LRU[K, V]) Add(k K, v V) (evicted bool) {
+	_, _, evicted = c.SetX(k, v)
+	return
+}
+
+// SetWithTTL adds an entry that expires after ttl instead of the cache ttl,
+// a ttl of NoLimitTTL falls back to the cache ttl
+func (c *LRU[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
+	c.set(&entry[K, V]{key: k, value: v, ttl: ttl})
+}
+
+// SetWithContext adds an entry that lives no longer than ctx, once ctx is
+// done the entry is treated as expired. It is removed then only if
+// SetContextWatch was given a function, the cache is not thread safe so it
+// does not watch ctx on its own
+func (c *LRU[K, V]) SetWithContext(ctx context.Context, k K, v V) {
+	c.set(&entry[K, V]{key: k, value: v, ctx: ctx})
+}
+
+// SetContextWatch makes entries set by SetWithContext register fn with
+// context.AfterFunc, so fn(k) runs in a goroutine of its own once their
+// context is done. fn should take the lock guarding the cache and call
+// ExpireContext. The registration is stopped when the entry is replaced or
+// removed, so no goroutine outlives it. It applies to entries set
+// afterwards, a nil fn registers nothing
+func (c *LRU[K, V]) SetContextWatch(fn func(k K)) {
+	c.ctxWatch = fn
+}
+
+// ExpireContext removes the entry of k if its context is done, reporting
+// EvictReasonExpired. It returns whether it was removed
+func (c *LRU[K, V]) ExpireContext(k K) bool {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok {
+		return false
+	}
+	if e := c.evictList.at(item); e.ctx == nil || e.ctx.Err() == nil {
+		return false
+	}
+	c.removeElement(item, EvictReasonExpired)
+	return true
+}
+
+// set adds e, filled with the caller's key, value and per entry settings
+func (c *LRU[K, V]) set(e *entry[K, V]) (evictedKey K, evictedValue V, evicted bool) {
+
+	// nil interfaces are rejected, this matters when K or V is an interface
+	if any(e.key) == nil || any(e.value) == nil {
+		return
+	}
+	e.key = c.normalize(e.key)
+	if !c.CanAdd(e.key) {
+		return
+	}
+
+	c.stats.Sets++
+
+	// restored and replaced entries come with their own update time and
+	// keep the expiry they had, so only new writes get jitter
+	if e.updatedAt.IsZero() {
+		e.updatedAt = c.clock.Now()
+		if e.jitter == 0 && c.jitter > 0 {
+			e.jitter = (rand.Float64()*2 - 1) * c.jitter
+		}
+	}
+	if c.trackBytes {
+		e.bytes = entrySize(e.key, e.value)
+	}
+	if e.cost == 0 {
+		if c.costIsBytes {
+			e.cost = e.bytes
+		} else {
+			e.cost = c.entryCost(e.key, e.value)
+		}
+	}
+	e.gen = c.gen
+	if c.trackDirty {
+		c.writes++
+		e.dirty = c.writes
+	}
+	c.totalCost += e.cost
+	c.totalBytes += e.bytes
+
+	if item, ok := c.cache[e.key]; ok {
+		old := c.evictList.at(item)
+		if e.createdAt.IsZero() && !c.expired(e.key) {
+			e.createdAt = old.createdAt
+		}
+		if e.createdAt.IsZero() {
+			e.createdAt = e.updatedAt
+		}
+		e.pinned = old.pinned
+		if !e.hasPriority {
+			e.priority = old.priority
+		}
+		if !e.hasTags {
+			e.tags = old.tags
+		}
+		e.refs = old.refs
+		c.unindex(old)
+		c.index(e)
+		c.totalCost -= old.cost
+		c.totalBytes -= old.bytes
+		oldKey, oldValue := old.key, old.value
+		*old = *e
+		c.evictList.moveToFront(item)
+		c.fireEvict(oldKey, oldValue, EvictReasonReplaced)
+		if c.onUpdate != nil {
+			c.onUpdate(e.key, oldValue, e.value)
+		}
+	} else {
+		if e.createdAt.IsZero() {
+			e.createdAt = e.updatedAt
+		}
+		// indexed before the slab copies e, so the copy keeps the context
+		// watch
+		c.index(e)
+		c.cache[e.key] = c.evictList.pushFront(e)
+		c.ghostAdded(e.key)
+		if c.onAdd != nil {
+			c.onAdd(e.key, e.value)
+		}
+	}
+	c.queueExpiry(c.cache[e.key])
+	c.reference(c.cache[e.key])
+	c.emit(EventSet, e.key, e.value, 0)
+
+	c.setting = c.cache[e.key]
+
+	if c.size != NoLimitSize && c.evictList.length() > c.size {
+		evictedKey, evictedValue, evicted = c.RemoveOldest()
+	}
+
+	for c.maxCost != NoLimitCost && c.totalCost > c.maxCost {
+		k, v, ok := c.RemoveOldest()
+		if !ok {
+			break
+		}
+		if !evicted {
+			evictedKey, evictedValue, evicted = k, v, true
+		}
+	}
+	c.setting = 0
+
+	return
+}
+
+func (c *LRU[K, V]) Get(k K) (v V, ok bool) {
+	k = c.normalize(k)
+	if item, ok := c.cache[k]; ok && !c.expired(k) {
+		c.hit(item)
+		return c.evictList.at(item).value, true
+	}
+	c.recordAccess(k, false)
+	return
+}
+
+// ReplayRead applies a read of k the caller already counted as a hit or a
+// miss, for callers recording reads to apply them later in a batch. A hit
+// on a key still live moves it to front like Get, a miss feeds the ghost
+// list, and the event hook sees either. The stats are left alone
+func (c *LRU[K, V]) ReplayRead(k K, hit bool) {
+	k = c.normalize(k)
+	if item, ok := c.cache[k]; hit && ok && !c.expired(k) {
+		c.promote(item)
+	}
+	c.noteAccess(k, hit)
+}
+
+// GetTagged works like Get and attributes the hit or miss to callerTag,
+// see TagStats
+func (c *LRU[K, V]) GetTagged(k K, callerTag string) (V, bool) {
+	v, ok := c.Get(k)
+
+	if c.tagStats == nil {
+		c.tagStats = make(map[string]*Stats)
+	}
+	st, found := c.tagStats[callerTag]
+	if !found {
+		st = &Stats{}
+		c.tagStats[callerTag] = st
+	}
+	if ok {
+		st.Hits++
+	} else {
+		st.Misses++
+	}
+
+	return v, ok
+}
+
+// TagStats returns a copy of the counters recorded by GetTagged per tag
+func (c *LRU[K, V]) TagStats() map[string]Stats {
+	m := make(map[string]Stats, len(c.tagStats))
+	for tag, st := range c.tagStats {
+		m[tag] = *st
+	}
+	return m
+}
+
+// GetBytes works like Get for []byte values and returns a copy, so callers
+// may modify it without corrupting the cache. Values of any other type are
+// reported as not found
+func (c *LRU[K, V]) GetBytes(k K) ([]byte, bool) {
+	k = c.normalize(k)
+	if item, ok := c.cache[k]; ok && !c.expired(k) {
+		if b, ok := any(c.evictList.at(item).value).([]byte); ok {
+			c.hit(item)
+			return append(make([]byte, 0, len(b)), b...), true
+		}
+	}
+	c.recordAccess(k, false)
+	return nil, false
+}
+
+// GetWithTTL works like Get and also returns the remaining ttl of the entry,
+// NoLimitTTL if the cache has no ttl. The entry is moved to head
+func (c *LRU[K, V]) GetWithTTL(k K) (v V, ttl time.Duration, ok bool) {
+	k = c.normalize(k)
+	if item, ok := c.cache[k]; ok && !c.expired(k) {
+		c.hit(item)
+		e := c.evictList.at(item)
+		return e.value, c.remainingTTL(e), true
+	}
+	c.recordAccess(k, false)
+	return
+}
+
+// GetWithExpiration works like Get and also returns when the entry expires,
+// the zero time if it does not
+func (c *LRU[K, V]) GetWithExpiration(k K) (v V, expiresAt time.Time, ok bool) {
+	k = c.normalize(k)
+	if item, ok := c.cache[k]; ok && !c.expired(k) {
+		c.hit(item)
+		e := c.evictList.at(item)
+		expiresAt, _ = c.expiresAt(e)
+		return e.value, expiresAt, true
+	}
+	c.recordAccess(k, false)
+	return
+}
+
+// EntryInfo describes a live entry, see LRU.EntryInfo
+type EntryInfo struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Accesses counts the read hits since the entry was last set
+	Accesses uint64
+
+	// TTL is the time left before expiry, NoLimitTTL if it never expires,
+	// ExpiresAt is then the zero time
+	TTL       time.Duration
+	ExpiresAt time.Time
+}
+
+// EntryInfo returns the metadata of a live entry without touching it
+func (c *LRU[K, V]) EntryInfo(k K) (info EntryInfo, ok bool) {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok || c.expired(k) {
+		return info, false
+	}
+
+	e := c.evictList.at(item)
+	info = EntryInfo{
+		CreatedAt: e.createdAt,
+		UpdatedAt: e.updatedAt,
+		Accesses:  e.accesses,
+		TTL:       c.remainingTTL(e),
+	}
+	info.ExpiresAt, _ = c.expiresAt(e)
+	return info, true
+}
+
+// PeekWithTTL works like GetWithTTL without moving the entry to head
+func (c *LRU[K, V]) PeekWithTTL(k K) (v V, ttl time.Duration, ok bool) {
+	k = c.normalize(k)
+	if item, ok := c.cache[k]; ok && !c.expired(k) {
+		e := c.evictList.at(item)
+		return e.value, c.remainingTTL(e), true
+	}
+	return
+}
+
+// PeekStale works like Peek and also returns entries that expired by ttl,
+// staleFor tells how long ago they did and is 0 for live entries. Entries
+// whose context is done are not returned
+func (c *LRU[K, V]) PeekStale(k K) (v V, staleFor time.Duration, ok bool) {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok {
+		return
+	}
+
+	e := c.evictList.at(item)
+	if e.ctx != nil && e.ctx.Err() != nil {
+		return v, 0, false
+	}
+	if at, ok := c.expiresAt(e); ok {
+		if since := c.clock.Now().Sub(at); since > 0 {
+			staleFor = since
+		}
+	}
+	return e.value, staleFor, true
+}
+
+// Replace changes the value of a live entry and moves it to head like Set,
+// but keeps its ttl, context and update time, so it expires as it would
+// have. It returns false if k is absent
+func (c *LRU[K, V]) Replace(k K, v V) bool {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok || c.expired(k) {
+		return false
+	}
+
+	old := c.evictList.at(item)
+	c.set(&entry[K, V]{
+		key:       k,
+		value:     v,
+		updatedAt: old.updatedAt,
+		createdAt: old.createdAt,
+		ttl:       old.ttl,
+		jitter:    old.jitter,
+		ctx:       old.ctx,
+		queued:    old.queued,
+	})
+	return true
+}
+
+// SetTTLForKey gives a live entry its own ttl counted from now, the entry
+// is touched but its value is kept. It returns false if k is absent
+func (c *LRU[K, V]) SetTTLForKey(k K, ttl time.Duration) bool {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok || c.expired(k) {
+		return false
+	}
+
+	e := c.evictList.at(item)
+	e.ttl = ttl
+	e.updatedAt = c.clock.Now()
+	c.evictList.moveToFront(item)
+	c.queueExpiry(item)
+
+	return true
+}
+
+// Touch resets the ttl of a live entry and moves it to head, keeping its
+// value. It returns false if k is absent
+func (c *LRU[K, V]) Touch(k K) bool {
+	return c.RefreshMany([]K{k}) == 1
+}
+
+// UpdateTTL changes the ttl of a live entry, still counted from its last
+// update, without touching it. A ttl of NoLimitTTL falls back to the cache
+// ttl. It returns false if k is absent
+func (c *LRU[K, V]) UpdateTTL(k K, ttl time.Duration) bool {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok || c.expired(k) {
+		return false
+	}
+
+	c.evictList.at(item).ttl = ttl
+	c.queueExpiry(item)
+	return true
+}
+
+// NextToExpire returns the live entry with the earliest expiry time,
+// entries without ttl are never returned
+func (c *LRU[K, V]) NextToExpire() (k K, v V, at time.Time, ok bool) {
+	for item := c.evictList.back(); item != 0; item = c.evictList.prev(item) {
+		e := c.evictList.at(item)
+		t, expires := c.expiresAt(e)
+		if !expires || c.expired(e.key) {
+			continue
+		}
+		if !ok || t.Before(at) {
+			k, v, at, ok = e.key, e.value, t, true
+		}
+	}
+	return
+}
+
+// RefreshMany resets the ttl of the given keys that are present and not
+// expired and moves them to head, it returns how many were refreshed
+func (c *LRU[K, V]) RefreshMany(keys []K) int {
+	now := c.clock.Now()
+	n := 0
+
+	for _, k := range keys {
+		k = c.normalize(k)
+		if item, ok := c.cache[k]; ok && !c.expired(k) {
+			c.evictList.at(item).updatedAt = now
+			c.evictList.moveToFront(item)
+			n++
+		}
+	}
+
+	return n
+}
+
+// GetDetailed works like Get and removes the entry if it is found expired,
+// result tells which of the cases happened
+func (c *LRU[K, V]) GetDetailed(k K) (v V, result GetResult) {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok {
+		c.recordAccess(k, false)
+		return v, Miss
+	}
+
+	if c.expired(k) {
+		c.recordAccess(k, false)
+		if !c.reclaimable(k) {
+			return v, Miss
+		}
+		c.removeElement(item, EvictReasonExpired)
+		return v, ExpiredReclaimed
+	}
+
+	c.hit(item)
+	return c.evictList.at(item).value, Hit
+}
+
+func (c *LRU[K, V]) Contains(k K) bool {
+	k = c.normalize(k)
+	_, ok := c.cache[k]
+	return ok && !c.expired(k)
+}
+
+// Peek get a cache without move it to head
+func (c *LRU[K, V]) Peek(k K) (v V, ok bool) {
+	k = c.normalize(k)
+	if item, ok := c.cache[k]; ok && !c.expired(k) {
+		return c.evictList.at(item).value, true
+	}
+	return
+}
+
+func (c *LRU[K, V]) Remove(k K) bool {
+	k = c.normalize(k)
+	if item, ok := c.cache[k]; ok {
+		c.removeElement(item, EvictReasonRemoved)
+		return true
+	}
+	return false
+}
+
+// RemoveFunc removes every entry, expired or not, for which pred returns
+// true and calls the eviction callback for each. It returns how many were
+// removed. pred must not change the cache
+func (c *LRU[K, V]) RemoveFunc(pred func(k K, v V) bool) int {
+	n := 0
+	for item := c.evictList.back(); item != 0; {
+		prev := c.evictList.prev(item)
+		if e := c.evictList.at(item); pred(e.key, e.value) {
+			c.removeElement(item, EvictReasonRemoved)
+			n++
+		}
+		item = prev
+	}
+	return n
+}
+
+// TakeOrCreate removes and returns the live value of k, handing it over to
+// the caller without calling the eviction callback. On a miss it returns
+// the result of create, which is not added to the cache
+func (c *LRU[K, V]) TakeOrCreate(k K, create func() V) V {
+	if v, ok := c.Pop(k); ok {
+		return v
+	}
+	return create()
+}
+
+// Pop removes and returns the live value of k, handing it over to the
+// caller without calling the eviction callbacks. The event hook still sees
+// an EventEvict with EvictReasonTaken
+func (c *LRU[K, V]) Pop(k K) (v V, ok bool) {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok || c.expired(k) {
+		return v, false
+	}
+
+	v = c.evictList.at(item).value
+	c.removeElement(item, EvictReasonTaken)
+	return v, true
+}
+
+// RemoveOldest removes the oldest entry that is not pinned, from the lowest
+// priority band. Under an eviction policy other than PolicyLRU it removes
+// the entry the policy picks instead
+func (c *LRU[K, V]) RemoveOldest() (k K, v V, ok bool) {
+	item := c.victim()
+	if item != 0 {
+		kv := c.evictList.at(item)
+		k, v = kv.key, kv.value
+		c.removeElement(item, EvictReasonCapacity)
+		return k, v, true
+	}
+	return
+}
+
+// GetOldest returns the oldest entry without removing it or changing
+// its position
+func (c *LRU[K, V]) GetOldest() (k K, v V, ok bool) {
+	if item := c.evictList.back(); item != 0 {
+		kv := c.evictList.at(item)
+		return kv.key, kv.value, true
+	}
+	return
+}
+
+// GetNewest returns the newest entry without removing it or changing
+// its position
+func (c *LRU[K, V]) GetNewest() (k K, v V, ok bool) {
+	if item := c.evictList.front(); item != 0 {
+		kv := c.evictList.at(item)
+		return kv.key, kv.value, true
+	}
+	return
+}
+
+// RemoveOldestN removes up to n oldest entries that are not pinned without
+// changing the size limit, it returns how many were removed
+func (c *LRU[K, V]) RemoveOldestN(n int) int {
+	removed := 0
+	for ; removed < n; removed++ {
+		if !c.removeOldest() {
+			break
+		}
+	}
+	return removed
+}
+
+// Shed removes the given fraction of the entries, rounded up, in the order
+// capacity eviction takes them and reporting EvictReasonPressure. It returns
+// how many were removed, pinned entries are kept
+func (c *LRU[K, V]) Shed(fraction float64) int {
+	n := int(math.Ceil(float64(c.Len()) * min(max(fraction, 0), 1)))
+	removed := 0
+	for ; removed < n; removed++ {
+		item := c.victim()
+		if item == 0 {
+			break
+		}
+		c.removeElement(item, EvictReasonPressure)
+	}
+	return removed
+}
+
+// Len returns the number of entries, expired ones that were not removed yet
+// included, see LenValid
+func (c *LRU[K, V]) Len() int {
+	return c.evictList.length()
+}
+
+// LenValid returns the number of entries that are not expired, it walks the
+// whole cache
+func (c *LRU[K, V]) LenValid() int {
+	n := 0
+	for k := range c.cache {
+		if !c.expired(k) {
+			n++
+		}
+	}
+	return n
+}
+
+// IsFull reports whether the cache holds as many entries as its size allows,
+// it is always false for a cache without size limit
+func (c *LRU[K, V]) IsFull() bool {
+	return c.size != NoLimitSize && c.Len() >= c.size
+}
+
+// Cap returns the size limit of the cache, NoLimitSize if unbounded
+func (c *LRU[K, V]) Cap() int {
+	return c.size
+}
+
+// Keys returns keys that are not expired from oldest to newest
+func (c *LRU[K, V]) Keys() []K {
+	return c.AppendKeys(make([]K, 0, len(c.cache)))
+}
+
+// AppendKeys appends keys that are not expired from oldest to newest to dst
+// and returns the extended slice, it does not allocate if dst has room
+func (c *LRU[K, V]) AppendKeys(dst []K) []K {
+	for item := c.evictList.back(); item != 0; item = c.evictList.prev(item) {
+		if k := c.evictList.at(item).key; !c.expired(k) {
+			dst = append(dst, k)
+		}
+	}
+
+	return dst
+}
+
+// Range calls fn for every entry that is not expired from newest to oldest
+// without changing recency, it stops when fn returns false. fn must not
+// change the cache
+func (c *LRU[K, V]) Range(fn func(k K, v V) bool) {
+	c.RangeOrder(NewestFirst, fn)
+}
+
+// TimestampMap returns the last update time of every key that is not expired
+func (c *LRU[K, V]) TimestampMap() map[K]time.Time {
+	m := make(map[K]time.Time, len(c.cache))
+
+	for k, item := range c.cache {
+		if !c.expired(k) {
+			m[k] = c.evictList.at(item).updatedAt
+		}
+	}
+
+	return m
+}
+
+// EvictExpired removes all expired entries, it returns how many were removed
+func (c *LRU[K, V]) EvictExpired() int {
+	if c.expiry != nil {
+		return c.evictQueued()
+	}
+
+	n := 0
+	for item := c.evictList.back(); item != 0; {
+		prev := c.evictList.prev(item)
+		if c.reclaimable(c.evictList.at(item).key) {
+			c.removeElement(item, EvictReasonExpired)
+			n++
+		}
+		item = prev
+	}
+	return n
+}
+
+func (c *LRU[K, V]) Purge() {
+	for k, v := range c.cache {
+		e := c.evictList.at(v)
+		if e.stopWatch != nil {
+			e.stopWatch()
+		}
+		c.fireEvict(k, e.value, EvictReasonPurged)
+		delete(c.cache, k)
+	}
+
+	c.evictList.init()
+	c.totalCost = 0
+	c.totalBytes = 0
+	c.stale = 0
+	c.bands = nil
+	c.tagIndex = nil
+	c.kheap = c.kheap[:0]
+	if c.expiry != nil {
+		c.expiry = &expiryQueue[K]{}
+	}
+}
+
+func (c *LRU[K, V]) Resize(size int) int {
+	diff := c.Len() - size
+	evicted := 0
+	for ; evicted < diff; evicted++ {
+		if !c.removeOldest() {
+			break
+		}
+	}
+	c.size = size
+	return evicted
+}
+
+func (c *LRU[K, V]) removeOldest() bool {
+	item := c.victim()
+
+	if item != 0 {
+		c.removeElement(item, EvictReasonCapacity)
+		return true
+	}
+	return false
+}
+
+// Pin keeps a live entry in the cache until Unpin: capacity eviction skips
+// it and its ttl no longer expires it. Pinned entries still count in Len and
+// Cost, and Remove and Purge still remove them. It returns false if k is
+// absent
+func (c *LRU[K, V]) Pin(k K) bool {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok || c.expired(k) {
+		return false
+	}
+	c.evictList.at(item).pinned = true
+	return true
+}
+
+// Unpin makes a pinned entry evictable again, it returns false if k is not
+// pinned. An entry past its ttl expires right away
+func (c *LRU[K, V]) Unpin(k K) bool {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok || !c.evictList.at(item).pinned {
+		return false
+	}
+	c.evictList.at(item).pinned = false
+	c.queueExpiry(item)
+	return true
+}
+
+// SetIfRoom adds k as the oldest entry if it is not in the cache and fits
+// without evicting anything, it reports whether k was added. Entries added
+// this way never displace the ones already cached, and of several of them
+// the first added is the last evicted
+func (c *LRU[K, V]) SetIfRoom(k K, v V) bool {
+	k = c.normalize(k)
+	// an expired entry of k is replaced in place
+	item, present := c.cache[k]
+	if present && !c.expired(k) {
+		return false
+	}
+	if !present && c.size != NoLimitSize && c.Len() >= c.size {
+		return false
+	}
+	cost, freed := c.entryCost(k, v), int64(0)
+	if present {
+		freed = c.evictList.at(item).cost
+	}
+	if c.maxCost != NoLimitCost && c.totalCost-freed+cost > c.maxCost {
+		return false
+	}
+
+	c.set(&entry[K, V]{key: k, value: v, cost: cost})
+	if item, ok := c.cache[k]; ok {
+		c.evictList.moveToBack(item)
+		return true
+	}
+	return false
+}
+
+// CanAdd reports whether Set would accept k: k is in the cache, the cache is
+// not full or one of its entries can be evicted. Set drops new keys when
+// the cache is full of pinned entries
+func (c *LRU[K, V]) CanAdd(k K) bool {
+	k = c.normalize(k)
+	if _, ok := c.cache[k]; ok {
+		return true
+	}
+	return c.size == NoLimitSize || c.Len() < c.size || c.victim() != 0
+}
+
+// SetTTL changes the cache ttl, entries without their own ttl follow it
+// from their last update
+func (c *LRU[K, V]) SetTTL(ttl time.Duration) {
+	if ttl <= NoLimitTTL {
+		ttl = NoLimitTTL
+	}
+	c.ttl = ttl
+	if c.expiry != nil {
+		c.rebuildExpiry()
+	}
+}
+
+// ResizeTTL works like SetTTL and removes the entries already past the new
+// ttl right away, like Resize does for the size. It returns how many were
+// removed
+func (c *LRU[K, V]) ResizeTTL(ttl time.Duration) int {
+	c.SetTTL(ttl)
+	return c.EvictExpired()
+}
+
+// SetClock makes the cache read the time from clk, which is clock.Real by
+// default
+func (c *LRU[K, V]) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// SetSlidingExpiration makes read hits restart the ttl of the entry, so the
+// ttl becomes an idle timeout. Peeks do not count as reads
+func (c *LRU[K, V]) SetSlidingExpiration(sliding bool) {
+	c.sliding = sliding
+}
+
+// SetFIFO makes read hits leave entries where they are, so capacity evicts
+// the entry set longest ago and the cache becomes FIFO with ttl. Sets of a
+// present key, Touch and RefreshMany still move it to head
+func (c *LRU[K, V]) SetFIFO(fifo bool) {
+	c.fifo = fifo
+}
+
+// SetTTLJitter makes every Set lengthen or shorten the ttl of its entry by a
+// random fraction up to fraction, 0.1 for ±10%, so entries set together
+// expire spread out instead of all at once. It applies to the cache ttl and
+// per entry ttls alike, not to the max lifetime. Entries restored from a
+// snapshot keep the expiry they were saved with. 0 disables it for entries
+// set afterwards
+func (c *LRU[K, V]) SetTTLJitter(fraction float64) {
+	c.jitter = min(max(fraction, 0), 1)
+}
+
+// SetStaleGrace keeps entries expired by ttl for grace more before they are
+// reclaimed, so PeekStale can still serve them while Get and Peek miss.
+// EvictExpired, GetDetailed and the expiry heap leave them alone until the
+// grace is over, eviction for room still removes them. Entries expired by
+// their context or by NewGeneration get no grace
+func (c *LRU[K, V]) SetStaleGrace(grace time.Duration) {
+	c.grace = max(grace, 0)
+}
+
+// StaleGrace returns the grace set by SetStaleGrace
+func (c *LRU[K, V]) StaleGrace() time.Duration {
+	return c.grace
+}
+
+// SetMaxLifetime bounds every entry to maxLifetime since its key was first
+// set, updates and reads do not extend it. It applies on top of the ttl, so
+// with SetSlidingExpiration the ttl is an idle timeout and maxLifetime an
+// absolute one. NoLimitTTL disables it
+func (c *LRU[K, V]) SetMaxLifetime(maxLifetime time.Duration) {
+	if maxLifetime <= NoLimitTTL {
+		maxLifetime = NoLimitTTL
+	}
+	c.maxLifetime = maxLifetime
+	if c.expiry != nil {
+		c.rebuildExpiry()
+	}
+}
+
+// hit moves an entry found live by a read to head and counts the hit
+func (c *LRU[K, V]) hit(item int32) {
+	c.promote(item)
+	c.recordAccess(c.evictList.at(item).key, true)
+}
+
+// promote does what a hit does to the entry, without counting it
+func (c *LRU[K, V]) promote(item int32) {
+	e := c.evictList.at(item)
+	e.accesses++
+	if !c.fifo {
+		c.evictList.moveToFront(item)
+	}
+	c.reference(item)
+	if c.sliding {
+		e.updatedAt = c.clock.Now()
+	}
+}
+
+// index adds e to the priority and tag indexes, unindex removes it
+func (c *LRU[K, V]) index(e *entry[K, V]) {
+	c.moveBand(defaultPriority, e.priority)
+	c.tagAdd(e)
+	c.trackContext(e)
+	if e.ctx != nil && c.ctxWatch != nil {
+		k, watch := e.key, c.ctxWatch
+		e.stopWatch = context.AfterFunc(e.ctx, func() { watch(k) })
+	}
+}
+
+func (c *LRU[K, V]) unindex(e *entry[K, V]) {
+	c.moveBand(e.priority, defaultPriority)
+	c.tagRemove(e)
+	c.forget(e)
+	if e.stopWatch != nil {
+		e.stopWatch()
+		e.stopWatch = nil
+	}
+	if e.gen != c.gen {
+		c.stale--
+	}
+	if c.expiry != nil {
+		delete(c.expiry.ctxKeys, e.key)
+	}
+}
+
+func (c *LRU[K, V]) removeElement(e int32, reason EvictReason) {
+	kv := c.evictList.at(e)
+
+	delete(c.cache, kv.key)
+	c.totalCost -= kv.cost
+	c.totalBytes -= kv.bytes
+	c.unindex(kv)
+
+	// the slot is cleared on remove and may be reused by the callback
+	k, v := kv.key, kv.value
+	c.evictList.remove(e)
+	c.fireEvict(k, v, reason)
+}
+
+// fireEvict is the only place the eviction callbacks are called from, every
+// value leaving the cache goes through it
+func (c *LRU[K, V]) fireEvict(k K, v V, reason EvictReason) {
+	switch reason {
+	case EvictReasonReplaced:
+		// the Set replacing it emits the new value
+		if c.onEvictedReason != nil {
+			c.onEvictedReason(k, v, reason)
+		}
+		return
+	case EvictReasonTaken:
+		c.emit(EventEvict, k, v, reason)
+		return
+	case EvictReasonCapacity:
+		c.stats.Evictions++
+		c.ghostEvicted(k)
+	case EvictReasonExpired:
+		c.stats.Expirations++
+	}
+	c.onEvicted(k, v)
+	if reason == EvictReasonExpired {
+		c.emit(EventExpire, k, v, reason)
+	} else {
+		c.emit(EventEvict, k, v, reason)
+	}
+	if c.onEvictedReason != nil {
+		c.onEvictedReason(k, v, reason)
+	}
+}
+
+// SetOnAdd sets a callback called when a Set adds a key that was not in the
+// cache, before any eviction it causes. A nil fn removes it
+func (c *LRU[K, V]) SetOnAdd(fn func(k K, v V)) {
+	c.onAdd = fn
+}
+
+// SetOnUpdate sets a callback called when a Set replaces the value of a key
+// already in the cache, expired or not. A nil fn removes it
+func (c *LRU[K, V]) SetOnUpdate(fn func(k K, oldValue, newValue V)) {
+	c.onUpdate = fn
+}
+
+// SetEvictReasonCallback sets a callback called with the reason of every
+// eviction, after the one given to NewLRU. Unlike that one it is also called
+// for values replaced by Set. A nil fn removes it
+func (c *LRU[K, V]) SetEvictReasonCallback(fn EvictReasonCallback[K, V]) {
+	c.onEvictedReason = fn
+}
+
+// entryTTL returns the ttl that applies to e
+func (c *LRU[K, V]) entryTTL(e *entry[K, V]) time.Duration {
+	if e.ttl > NoLimitTTL {
+		return e.ttl
+	}
+	return c.ttl
+}
+
+// expiresAt returns when e expires by its ttl or the max lifetime, whichever
+// comes first, ok is false if neither applies
+func (c *LRU[K, V]) expiresAt(e *entry[K, V]) (at time.Time, ok bool) {
+	if ttl := c.entryTTL(e); ttl != NoLimitTTL {
+		if e.jitter != 0 {
+			ttl += time.Duration(float64(ttl) * e.jitter)
+		}
+		at, ok = e.updatedAt.Add(ttl), true
+	}
+	if c.maxLifetime != NoLimitTTL {
+		if end := e.createdAt.Add(c.maxLifetime); !ok || end.Before(at) {
+			at, ok = end, true
+		}
+	}
+	return
+}
+
+func (c *LRU[K, V]) remainingTTL(e *entry[K, V]) time.Duration {
+	at, ok := c.expiresAt(e)
+	if !ok {
+		return NoLimitTTL
+	}
+	return at.Sub(c.clock.Now())
+}
+
+func (c *LRU[K, V]) expired(k K) bool {
+	item, ok := c.cache[k]
+	if !ok {
+		return true
+	}
+
+	e := c.evictList.at(item)
+	if e.gen != c.gen {
+		return true
+	}
+	if e.ctx != nil && e.ctx.Err() != nil {
+		return true
+	}
+	if e.pinned {
+		return false
+	}
+
+	at, ok := c.expiresAt(e)
+	return ok && c.clock.Now().After(at)
+}
+
+// reclaimable reports whether k is expired and past its stale grace, see
+// SetStaleGrace
+func (c *LRU[K, V]) reclaimable(k K) bool {
+	if !c.expired(k) {
+		return false
+	}
+	if c.grace <= 0 {
+		return true
+	}
+
+	e := c.evictList.at(c.cache[k])
+	if e.gen != c.gen || e.ctx != nil && e.ctx.Err() != nil {
+		return true
+	}
+	at, _ := c.expiresAt(e)
+	return c.clock.Now().After(at.Add(c.grace))
+}
@@ -0,0 +1,698 @@
+package typedlru
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+func TestContextWatch(t *testing.T) {
+	tests := []struct {
+		name string
+		// drop takes "a" out of the cache before its context is cancelled,
+		// nil leaves it in
+		drop  func(c *LRU[string, int])
+		calls int32
+	}{
+		{name: "live entry", calls: 1},
+		{name: "removed", drop: func(c *LRU[string, int]) { c.Remove("a") }, calls: 0},
+		{name: "popped", drop: func(c *LRU[string, int]) { c.Pop("a") }, calls: 0},
+		{name: "replaced", drop: func(c *LRU[string, int]) { c.Set("a", 2) }, calls: 0},
+		{name: "evicted", drop: func(c *LRU[string, int]) { c.Set("b", 2); c.Set("c", 3) }, calls: 0},
+		{name: "purged", drop: func(c *LRU[string, int]) { c.Purge() }, calls: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewLRU[string, int](2, NoLimitTTL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var calls atomic.Int32
+			c.SetContextWatch(func(string) { calls.Add(1) })
+
+			ctx, cancel := context.WithCancel(context.Background())
+			c.SetWithContext(ctx, "a", 1)
+			if tt.drop != nil {
+				tt.drop(c)
+			}
+			cancel()
+
+			// AfterFunc runs in its own goroutine
+			time.Sleep(10 * time.Millisecond)
+			if got := calls.Load(); got != tt.calls {
+				t.Errorf("watch called %d times, want %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+// TestFireEvict checks every removal path reports through fireEvict: the
+// eviction callback, the reason callback and the event hook each see it
+// once, or not at all where the reason says so
+func TestFireEvict(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove func(c *LRU[string, int], clk *clock.Fake)
+		reason EvictReason
+		// callback and event tell whether the eviction callback and the
+		// event hook see the removal, the reason callback always does
+		callback, event bool
+	}{
+		{"Remove", func(c *LRU[string, int], _ *clock.Fake) { c.Remove("a") }, EvictReasonRemoved, true, true},
+		{"RemoveFunc", func(c *LRU[string, int], _ *clock.Fake) {
+			c.RemoveFunc(func(k string, _ int) bool { return k == "a" })
+		}, EvictReasonRemoved, true, true},
+		{"InvalidateTag", func(c *LRU[string, int], _ *clock.Fake) { c.InvalidateTag("t") }, EvictReasonRemoved, true, true},
+		{"RemoveOldest", func(c *LRU[string, int], _ *clock.Fake) { c.RemoveOldest() }, EvictReasonCapacity, true, true},
+		{"RemoveOldestN", func(c *LRU[string, int], _ *clock.Fake) { c.RemoveOldestN(1) }, EvictReasonCapacity, true, true},
+		{"Set over size", func(c *LRU[string, int], _ *clock.Fake) { c.Set("b", 2); c.Set("c", 3) }, EvictReasonCapacity, true, true},
+		{"Resize", func(c *LRU[string, int], _ *clock.Fake) { c.Resize(0) }, EvictReasonCapacity, true, true},
+		{"ShedCost", func(c *LRU[string, int], _ *clock.Fake) { c.ShedCost(1) }, EvictReasonPressure, true, true},
+		{"Purge", func(c *LRU[string, int], _ *clock.Fake) { c.Purge() }, EvictReasonPurged, true, true},
+		{"EvictOldGenerations", func(c *LRU[string, int], _ *clock.Fake) {
+			c.NewGeneration()
+			c.EvictOldGenerations(1)
+		}, EvictReasonPurged, true, true},
+		{"EvictExpired", func(c *LRU[string, int], clk *clock.Fake) {
+			clk.Advance(time.Hour)
+			c.EvictExpired()
+		}, EvictReasonExpired, true, true},
+		{"GetDetailed", func(c *LRU[string, int], clk *clock.Fake) {
+			clk.Advance(time.Hour)
+			c.GetDetailed("a")
+		}, EvictReasonExpired, true, true},
+		{"Set replacing", func(c *LRU[string, int], _ *clock.Fake) { c.Set("a", 2) }, EvictReasonReplaced, false, false},
+		{"Pop", func(c *LRU[string, int], _ *clock.Fake) { c.Pop("a") }, EvictReasonTaken, false, true},
+		{"TakeOrCreate", func(c *LRU[string, int], _ *clock.Fake) {
+			c.TakeOrCreate("a", func() int { return 0 })
+		}, EvictReasonTaken, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var callbacks, events int
+			var reasons []EvictReason
+			clk := clock.NewFake(time.Unix(0, 0))
+			c, err := NewLRU[string, int](2, NoLimitTTL, func(string, int) { callbacks++ })
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+			c.SetEvictReasonCallback(func(_ string, _ int, reason EvictReason) { reasons = append(reasons, reason) })
+			c.SetEventHook(func(ev Event[string, int]) {
+				if ev.Kind == EventEvict || ev.Kind == EventExpire {
+					events++
+				}
+			})
+			c.SetWithTags("a", 1, "t")
+			c.UpdateTTL("a", time.Minute)
+
+			tt.remove(c, clk)
+
+			wantCallbacks, wantEvents, wantReasons := 0, 0, 1
+			if tt.callback {
+				wantCallbacks = 1
+			}
+			if tt.event {
+				wantEvents = 1
+			}
+			if tt.reason == EvictReasonTaken {
+				wantReasons = 0
+			}
+			if callbacks != wantCallbacks {
+				t.Errorf("eviction callback called %d times, want %d", callbacks, wantCallbacks)
+			}
+			if events != wantEvents {
+				t.Errorf("%d eviction events, want %d", events, wantEvents)
+			}
+			if len(reasons) != wantReasons || wantReasons == 1 && reasons[0] != tt.reason {
+				t.Errorf("reasons = %v, want %d of %v", reasons, wantReasons, tt.reason)
+			}
+		})
+	}
+}
+
+func TestIsFull(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		sets int
+		want bool
+	}{
+		{name: "empty", size: 3, sets: 0, want: false},
+		{name: "partial", size: 3, sets: 2, want: false},
+		{name: "exactly full", size: 3, sets: 3, want: true},
+		{name: "full after evicting", size: 3, sets: 5, want: true},
+		{name: "unlimited", size: NoLimitSize, sets: 100, want: false},
+		{name: "unlimited empty", size: NoLimitSize, sets: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewLRU[int, int](tt.size, NoLimitTTL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.sets; i++ {
+				c.Set(i, i)
+			}
+			if got := c.IsFull(); got != tt.want {
+				t.Errorf("IsFull() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampMap(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name string
+		ops  func(c *LRU[string, int], clk *clock.Fake)
+		want map[string]time.Time
+	}{
+		{
+			name: "insert times",
+			ops: func(c *LRU[string, int], clk *clock.Fake) {
+				c.Set("a", 1)
+				clk.Advance(time.Second)
+				c.Set("b", 2)
+			},
+			want: map[string]time.Time{"a": start, "b": start.Add(time.Second)},
+		},
+		{
+			name: "update moves the time",
+			ops: func(c *LRU[string, int], clk *clock.Fake) {
+				c.Set("a", 1)
+				clk.Advance(2 * time.Second)
+				c.Set("a", 2)
+			},
+			want: map[string]time.Time{"a": start.Add(2 * time.Second)},
+		},
+		{
+			name: "reads keep the time",
+			ops: func(c *LRU[string, int], clk *clock.Fake) {
+				c.Set("a", 1)
+				clk.Advance(time.Second)
+				c.Get("a")
+			},
+			want: map[string]time.Time{"a": start},
+		},
+		{
+			name: "expired left out",
+			ops: func(c *LRU[string, int], clk *clock.Fake) {
+				c.SetWithTTL("a", 1, time.Second)
+				c.Set("b", 2)
+				clk.Advance(2 * time.Second)
+			},
+			want: map[string]time.Time{"b": start},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(start)
+			c, err := NewLRU[string, int](10, NoLimitTTL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+
+			tt.ops(c, clk)
+			got := c.TimestampMap()
+			if len(got) != len(tt.want) {
+				t.Fatalf("TimestampMap() = %v, want %v", got, tt.want)
+			}
+			for k, want := range tt.want {
+				if !got[k].Equal(want) {
+					t.Errorf("TimestampMap()[%s] = %v, want %v", k, got[k], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetX(t *testing.T) {
+	tests := []struct {
+		name    string
+		sets    []string
+		evicted bool
+		key     string
+		value   int
+	}{
+		{name: "room", sets: []string{"a"}, evicted: false},
+		{name: "update when full", sets: []string{"a", "b", "a"}, evicted: false},
+		{name: "evicts the oldest", sets: []string{"a", "b", "c"}, evicted: true, key: "a", value: 0},
+		{name: "evicts the least recent", sets: []string{"a", "b", "a", "c"}, evicted: true, key: "b", value: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewLRU[string, int](2, NoLimitTTL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var k string
+			var v int
+			var evicted bool
+			for i, key := range tt.sets {
+				k, v, evicted = c.SetX(key, i)
+			}
+			if evicted != tt.evicted || evicted && (k != tt.key || v != tt.value) {
+				t.Errorf("SetX() = %q, %d, %v, want %q, %d, %v", k, v, evicted, tt.key, tt.value, tt.evicted)
+			}
+		})
+	}
+}
+
+func TestRefreshMany(t *testing.T) {
+	tests := []struct {
+		name    string
+		refresh []string
+		n       int
+		// live are the keys left after the ttl of the first sets ran out
+		live []string
+	}{
+		{name: "none", refresh: nil, n: 0, live: []string{"c"}},
+		{name: "some", refresh: []string{"a"}, n: 1, live: []string{"a", "c"}},
+		{name: "absent skipped", refresh: []string{"a", "x", "b"}, n: 2, live: []string{"a", "b", "c"}},
+		{name: "expired skipped", refresh: []string{"old"}, n: 0, live: []string{"c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			c, err := NewLRU[string, int](10, time.Minute, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+			c.Set("old", 0)
+			clk.Advance(time.Minute + time.Second)
+			c.Set("a", 1)
+			c.Set("b", 2)
+			clk.Advance(30 * time.Second)
+			c.Set("c", 3)
+
+			if n := c.RefreshMany(tt.refresh); n != tt.n {
+				t.Errorf("RefreshMany() = %d, want %d", n, tt.n)
+			}
+			clk.Advance(45 * time.Second)
+			if got := c.Keys(); !slices.Equal(slices.Sorted(slices.Values(got)), tt.live) {
+				t.Errorf("live keys %v, want %v", got, tt.live)
+			}
+		})
+	}
+}
+
+func TestGetWithTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(c *LRU[string, int], k string) (int, time.Duration, bool)
+		// oldest is the key evicted by the next Set
+		oldest string
+	}{
+		{name: "GetWithTTL promotes", read: (*LRU[string, int]).GetWithTTL, oldest: "b"},
+		{name: "PeekWithTTL does not", read: (*LRU[string, int]).PeekWithTTL, oldest: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			c, err := NewLRU[string, int](2, time.Minute, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+			c.Set("a", 1)
+			clk.Advance(10 * time.Second)
+			c.SetWithTTL("b", 2, 30*time.Second)
+			clk.Advance(5 * time.Second)
+
+			v, ttl, ok := tt.read(c, "a")
+			if !ok || v != 1 || ttl != 45*time.Second {
+				t.Errorf("read(a) = %d, %v, %v, want 1, 45s, true", v, ttl, ok)
+			}
+			// a read never moves the expiry
+			if _, ttl, _ := c.PeekWithTTL("b"); ttl != 25*time.Second {
+				t.Errorf("ttl of b = %v, want 25s", ttl)
+			}
+			if _, _, ok := tt.read(c, "x"); ok {
+				t.Error("read(x) found an absent key")
+			}
+
+			c.Set("c", 3)
+			if c.Contains(tt.oldest) {
+				t.Errorf("%s outlived the Set, keys %v", tt.oldest, c.Keys())
+			}
+		})
+	}
+}
+
+func TestRemoveOldestN(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		removed int
+		keys    []int
+	}{
+		{name: "negative", n: -1, removed: 0, keys: []int{0, 1, 2, 3}},
+		{name: "zero", n: 0, removed: 0, keys: []int{0, 1, 2, 3}},
+		{name: "within range", n: 2, removed: 2, keys: []int{2, 3}},
+		{name: "all", n: 4, removed: 4, keys: []int{}},
+		{name: "more than Len", n: 10, removed: 4, keys: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var evicted []int
+			c, err := NewLRU[int, int](10, NoLimitTTL, func(k, _ int) { evicted = append(evicted, k) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 4; i++ {
+				c.Set(i, i)
+			}
+
+			if got := c.RemoveOldestN(tt.n); got != tt.removed {
+				t.Errorf("RemoveOldestN(%d) = %d, want %d", tt.n, got, tt.removed)
+			}
+			if got := c.Keys(); !slices.Equal(got, tt.keys) {
+				t.Errorf("Keys() = %v, want %v", got, tt.keys)
+			}
+			if len(evicted) != tt.removed {
+				t.Errorf("eviction callback called for %v, want %d keys", evicted, tt.removed)
+			}
+			if c.Cap() != 10 {
+				t.Errorf("Cap() = %d, RemoveOldestN changed the size", c.Cap())
+			}
+		})
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		ok    bool
+	}{
+		{name: "bytes", value: []byte("hello"), ok: true},
+		{name: "empty bytes", value: []byte{}, ok: true},
+		{name: "string", value: "hello", ok: false},
+		{name: "int", value: 1, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewLRU[string, any](10, NoLimitTTL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Set("k", tt.value)
+
+			b, ok := c.GetBytes("k")
+			if ok != tt.ok {
+				t.Fatalf("GetBytes() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			stored := tt.value.([]byte)
+			if string(b) != string(stored) {
+				t.Fatalf("GetBytes() = %q, want %q", b, stored)
+			}
+			// the copy is independent of the cached slice
+			b = append(b[:0], "HELLO"...)
+			if v, _ := c.Peek("k"); string(v.([]byte)) != string(stored) || string(stored) == "HELLO" {
+				t.Errorf("writing the copy changed the cached value to %q", v)
+			}
+			if again, _ := c.GetBytes("k"); len(again) > 0 && &again[0] == &b[0] {
+				t.Error("GetBytes returned the same buffer twice")
+			}
+		})
+	}
+
+	c, _ := NewLRU[string, any](10, NoLimitTTL, nil)
+	if _, ok := c.GetBytes("absent"); ok {
+		t.Error("GetBytes found an absent key")
+	}
+}
+
+func TestGetTagged(t *testing.T) {
+	tests := []struct {
+		name  string
+		reads []struct{ key, tag string }
+		want  map[string]Stats
+	}{
+		{name: "no reads", want: map[string]Stats{}},
+		{
+			name: "two tags",
+			reads: []struct{ key, tag string }{
+				{"a", "api"}, {"a", "api"}, {"x", "api"},
+				{"b", "batch"}, {"x", "batch"}, {"y", "batch"},
+			},
+			want: map[string]Stats{"api": {Hits: 2, Misses: 1}, "batch": {Hits: 1, Misses: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewLRU[string, int](10, NoLimitTTL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Set("a", 1)
+			c.Set("b", 2)
+
+			for _, r := range tt.reads {
+				c.GetTagged(r.key, r.tag)
+			}
+			got := c.TagStats()
+			if len(got) != len(tt.want) {
+				t.Fatalf("TagStats() = %v, want %v", got, tt.want)
+			}
+			for tag, want := range tt.want {
+				if got[tag].Hits != want.Hits || got[tag].Misses != want.Misses {
+					t.Errorf("TagStats()[%s] = %d hits %d misses, want %d %d", tag, got[tag].Hits, got[tag].Misses, want.Hits, want.Misses)
+				}
+			}
+			// the tagged reads count in the cache totals too
+			if st := c.Stats(); int(st.Hits+st.Misses) != len(tt.reads) {
+				t.Errorf("Stats() counted %d reads, want %d", st.Hits+st.Misses, len(tt.reads))
+			}
+		})
+	}
+}
+
+func TestSetTTLForKey(t *testing.T) {
+	tests := []struct {
+		name string
+		// cacheTTL is the default, ttl the one given to the key 10s in
+		cacheTTL, ttl time.Duration
+		// aliveAt and deadAt are times since the start
+		aliveAt, deadAt time.Duration
+	}{
+		{name: "longer than the default", cacheTTL: time.Minute, ttl: 30 * time.Minute, aliveAt: 20 * time.Minute, deadAt: 41 * time.Minute},
+		{name: "shorter than the default", cacheTTL: time.Hour, ttl: time.Minute, aliveAt: 69 * time.Second, deadAt: 71 * time.Second},
+		{name: "no default", cacheTTL: NoLimitTTL, ttl: time.Minute, aliveAt: 69 * time.Second, deadAt: 71 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Unix(0, 0)
+			clk := clock.NewFake(start)
+			c, err := NewLRU[string, int](10, tt.cacheTTL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+			c.Set("a", 1)
+			c.Set("other", 2)
+			clk.Advance(10 * time.Second)
+
+			if !c.SetTTLForKey("a", tt.ttl) {
+				t.Fatal("SetTTLForKey() = false on a live key")
+			}
+			if v, _ := c.Peek("a"); v != 1 {
+				t.Errorf("value changed to %d", v)
+			}
+			clk.Set(start.Add(tt.aliveAt))
+			if !c.Contains("a") {
+				t.Errorf("a expired before %v", tt.aliveAt)
+			}
+			clk.Set(start.Add(tt.deadAt))
+			if c.Contains("a") {
+				t.Errorf("a still live at %v", tt.deadAt)
+			}
+		})
+	}
+
+	c, _ := NewLRU[string, int](10, NoLimitTTL, nil)
+	if c.SetTTLForKey("absent", time.Minute) {
+		t.Error("SetTTLForKey() = true on an absent key")
+	}
+}
+
+func TestNextToExpire(t *testing.T) {
+	type set struct {
+		key string
+		ttl time.Duration
+	}
+	tests := []struct {
+		name string
+		// noTTL leaves the cache without a ttl, so a NoLimitTTL set never
+		// expires. Otherwise the cache ttl is a minute
+		noTTL bool
+		// sets are one second apart
+		sets  []set
+		after time.Duration
+		want  string
+		ok    bool
+	}{
+		{name: "empty"},
+		{name: "uniform ttl is the oldest", sets: []set{{"a", time.Minute}, {"b", time.Minute}, {"c", time.Minute}}, want: "a", ok: true},
+		{name: "short ttl set last", sets: []set{{"a", time.Hour}, {"b", time.Minute}, {"c", time.Second}}, want: "c", ok: true},
+		{name: "short ttl in the middle", sets: []set{{"a", time.Hour}, {"b", 10 * time.Second}, {"c", time.Hour}}, want: "b", ok: true},
+		{name: "never expires is skipped", noTTL: true, sets: []set{{"a", NoLimitTTL}, {"b", time.Hour}}, want: "b", ok: true},
+		{name: "expired is skipped", sets: []set{{"a", time.Second}, {"b", time.Hour}, {"c", time.Minute}}, after: 30 * time.Second, want: "c", ok: true},
+		{name: "nothing expires", noTTL: true, sets: []set{{"a", NoLimitTTL}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Unix(0, 0)
+			clk := clock.NewFake(start)
+			ttl := time.Minute
+			if tt.noTTL {
+				ttl = NoLimitTTL
+			}
+			c, err := NewLRU[string, int](10, ttl, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+			wantAt := map[string]time.Time{}
+			for i, s := range tt.sets {
+				clk.Set(start.Add(time.Duration(i) * time.Second))
+				c.SetWithTTL(s.key, i, s.ttl)
+				wantAt[s.key] = clk.Now().Add(s.ttl)
+			}
+			clk.Advance(tt.after)
+
+			k, v, at, ok := c.NextToExpire()
+			if ok != tt.ok || k != tt.want {
+				t.Fatalf("NextToExpire() = %q, %v, want %q, %v", k, ok, tt.want, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if got, _ := c.Peek(k); v != got {
+				t.Errorf("NextToExpire() value %d, want %d", v, got)
+			}
+			if !at.Equal(wantAt[k]) {
+				t.Errorf("NextToExpire() at %v, want %v", at, wantAt[k])
+			}
+		})
+	}
+}
+
+func TestTakeOrCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(c *LRU[string, int], clk *clock.Fake)
+		want    int
+		created bool
+		evicted int
+	}{
+		{name: "hit", prepare: func(c *LRU[string, int], clk *clock.Fake) { c.Set("a", 1) }, want: 1},
+		{name: "miss", prepare: func(c *LRU[string, int], clk *clock.Fake) {}, want: 100, created: true},
+		{name: "expired", prepare: func(c *LRU[string, int], clk *clock.Fake) {
+			c.Set("a", 1)
+			clk.Advance(2 * time.Minute)
+		}, want: 100, created: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			evicted := 0
+			c, err := NewLRU[string, int](10, time.Minute, func(k string, v int) { evicted++ })
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+			tt.prepare(c, clk)
+
+			created := false
+			v := c.TakeOrCreate("a", func() int { created = true; return 100 })
+			if v != tt.want || created != tt.created {
+				t.Errorf("TakeOrCreate() = %d, created %v, want %d, %v", v, created, tt.want, tt.created)
+			}
+			if c.Contains("a") {
+				t.Error("a is still cached after TakeOrCreate")
+			}
+			if evicted != 0 {
+				t.Errorf("eviction callback called %d times for a take", evicted)
+			}
+		})
+	}
+}
+
+func TestGetDetailed(t *testing.T) {
+	tests := []struct {
+		name string
+		// after is how long after setting a, with a ttl of a minute, it is
+		// read
+		after  time.Duration
+		grace  time.Duration
+		key    string
+		want   GetResult
+		kept   bool
+		misses uint64
+	}{
+		{name: "hit", after: 30 * time.Second, key: "a", want: Hit, kept: true},
+		{name: "absent", key: "b", want: Miss, kept: true, misses: 1},
+		{name: "expired", after: 2 * time.Minute, key: "a", want: ExpiredReclaimed, misses: 1},
+		{name: "expired within the stale grace", after: 2 * time.Minute, grace: time.Hour, key: "a", want: Miss, kept: true, misses: 1},
+		{name: "expired past the stale grace", after: 2 * time.Minute, grace: 30 * time.Second, key: "a", want: ExpiredReclaimed, misses: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			c, err := NewLRU[string, int](10, time.Minute, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+			c.SetStaleGrace(tt.grace)
+			c.Set("a", 1)
+			clk.Advance(tt.after)
+
+			v, result := c.GetDetailed(tt.key)
+			if result != tt.want {
+				t.Errorf("GetDetailed(%q) result %v, want %v", tt.key, result, tt.want)
+			}
+			if (result == Hit) != (v == 1) {
+				t.Errorf("GetDetailed(%q) = %d with result %v", tt.key, v, result)
+			}
+			if _, kept := c.cache["a"]; kept != tt.kept {
+				t.Errorf("a kept %v, want %v", kept, tt.kept)
+			}
+			if m := c.Stats().Misses; m != tt.misses {
+				t.Errorf("%d misses, want %d", m, tt.misses)
+			}
+
+			// a reclaimed key is an ordinary miss from then on
+			if tt.want == ExpiredReclaimed {
+				if _, result := c.GetDetailed(tt.key); result != Miss {
+					t.Errorf("GetDetailed(%q) again result %v, want Miss", tt.key, result)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package typedlru
+
+// defaultK is the history depth of PolicyLRUK unless SetLRUK says otherwise
+const defaultK = 2
+
+// SetLRUK switches to PolicyLRUK with a history of the last k references
+// per entry, sets and read hits count as references. A k below 1 keeps
+// the current depth, 2 by default
+func (c *LRU[K, V]) SetLRUK(k int) {
+	if k >= 1 {
+		c.k = k
+	}
+	c.SetEvictionPolicy(PolicyLRUK)
+}
+
+// rebuildHistory starts or drops the history of every entry as the policy
+// changes. Entries present when PolicyLRUK starts get one reference at
+// their last update
+func (c *LRU[K, V]) rebuildHistory() {
+	c.kheap = c.kheap[:0]
+	for _, item := range c.cache {
+		e := c.evictList.at(item)
+		e.refs, e.kpos = nil, 0
+		if c.policy != PolicyLRUK {
+			continue
+		}
+		e.refs = append(make([]int64, 0, c.k), e.updatedAt.UnixNano())
+		c.kheap = append(c.kheap, item)
+		e.kpos = int32(len(c.kheap))
+	}
+	for i := len(c.kheap)/2 - 1; i >= 0; i-- {
+		c.kdown(i)
+	}
+	if c.policy != PolicyLRUK {
+		c.kheap = nil
+	}
+}
+
+// reference records a reference to the entry of slot item
+func (c *LRU[K, V]) reference(item int32) {
+	if c.policy != PolicyLRUK {
+		return
+	}
+
+	e := c.evictList.at(item)
+	if e.refs == nil {
+		e.refs = make([]int64, 0, c.k)
+	}
+	if len(e.refs) < c.k {
+		e.refs = append(e.refs, 0)
+	}
+	copy(e.refs[1:], e.refs[:len(e.refs)-1])
+	e.refs[0] = c.clock.Now().UnixNano()
+
+	if e.kpos == 0 {
+		c.kheap = append(c.kheap, item)
+		e.kpos = int32(len(c.kheap))
+		c.kup(len(c.kheap) - 1)
+		return
+	}
+	c.kfix(int(e.kpos - 1))
+}
+
+// forget takes e out of the history heap
+func (c *LRU[K, V]) forget(e *entry[K, V]) {
+	if e.kpos == 0 {
+		return
+	}
+	i, last := int(e.kpos-1), len(c.kheap)-1
+	e.kpos = 0
+	if i != last {
+		c.kheap[i] = c.kheap[last]
+		c.evictList.at(c.kheap[i]).kpos = int32(i + 1)
+	}
+	c.kheap = c.kheap[:last]
+	if i != last {
+		c.kfix(i)
+	}
+}
+
+// kcandidate returns the matching entry of largest backward k-distance:
+// entries referenced fewer than k times first, by last reference, then by
+// their k-th most recent reference. The heap is searched best first so
+// pinned and filtered entries cost only their own rejection
+func (c *LRU[K, V]) kcandidate(in func(e *entry[K, V]) bool) int32 {
+	if len(c.kheap) == 0 {
+		return 0
+	}
+
+	open := []int{0}
+	for len(open) > 0 {
+		best := 0
+		for j := range open[1:] {
+			if c.kless(open[j+1], open[best]) {
+				best = j + 1
+			}
+		}
+		i := open[best]
+		open[best] = open[len(open)-1]
+		open = open[:len(open)-1]
+
+		item := c.kheap[i]
+		if e := c.evictList.at(item); item != c.setting && !e.pinned && in(e) {
+			return item
+		}
+		for _, child := range [2]int{2*i + 1, 2*i + 2} {
+			if child < len(c.kheap) {
+				open = append(open, child)
+			}
+		}
+	}
+
+	// only the entry being set is left
+	if e := c.evictList.at(c.setting); c.setting != 0 && !e.pinned && in(e) {
+		return c.setting
+	}
+	return 0
+}
+
+// kless reports whether heap position i is evicted before position j
+func (c *LRU[K, V]) kless(i, j int) bool {
+	a, b := c.evictList.at(c.kheap[i]), c.evictList.at(c.kheap[j])
+	fullA, fullB := len(a.refs) >= c.k, len(b.refs) >= c.k
+	if fullA != fullB {
+		return fullB
+	}
+	if fullA {
+		return a.refs[c.k-1] < b.refs[c.k-1]
+	}
+	return a.refs[0] < b.refs[0]
+}
+
+func (c *LRU[K, V]) kswap(i, j int) {
+	c.kheap[i], c.kheap[j] = c.kheap[j], c.kheap[i]
+	c.evictList.at(c.kheap[i]).kpos = int32(i + 1)
+	c.evictList.at(c.kheap[j]).kpos = int32(j + 1)
+}
+
+func (c *LRU[K, V]) kfix(i int) {
+	if i > 0 && c.kless(i, (i-1)/2) {
+		c.kup(i)
+		return
+	}
+	c.kdown(i)
+}
+
+func (c *LRU[K, V]) kup(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !c.kless(i, parent) {
+			return
+		}
+		c.kswap(i, parent)
+		i = parent
+	}
+}
+
+func (c *LRU[K, V]) kdown(i int) {
+	n := len(c.kheap)
+	for {
+		least := i
+		if l := 2*i + 1; l < n && c.kless(l, least) {
+			least = l
+		}
+		if r := 2*i + 2; r < n && c.kless(r, least) {
+			least = r
+		}
+		if least == i {
+			return
+		}
+		c.kswap(i, least)
+		i = least
+	}
+}
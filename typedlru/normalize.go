@@ -0,0 +1,19 @@
+package typedlru
+
+// SetKeyNormalizer makes the cache store and look up every key as fn(k), for
+// instance to match keys case insensitively. Every method taking a key
+// applies it, and the keys the cache returns or passes to callbacks are the
+// normalized ones. fn must return the same key when given its own result. It
+// applies to the keys set from then on, set it on an empty cache. A nil fn
+// removes it
+func (c *LRU[K, V]) SetKeyNormalizer(fn func(k K) K) {
+	c.normalizeKey = fn
+}
+
+// normalize returns the key k is stored under
+func (c *LRU[K, V]) normalize(k K) K {
+	if c.normalizeKey == nil {
+		return k
+	}
+	return c.normalizeKey(k)
+}
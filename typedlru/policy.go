@@ -0,0 +1,98 @@
+package typedlru
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// EvictionPolicy tells which entry capacity eviction takes within the
+// lowest priority band, see SetEvictionPolicy
+type EvictionPolicy int
+
+const (
+	// PolicyLRU evicts the least recently used entry
+	PolicyLRU EvictionPolicy = iota
+	// PolicyMRU evicts the most recently used entry other than the one
+	// being set, which suits cyclic scans larger than the cache
+	PolicyMRU
+	// PolicyRandom evicts an entry picked at random, other than the one being
+	// set
+	PolicyRandom
+	// PolicyLRUK evicts the entry whose k-th most recent reference is the
+	// oldest, entries with fewer references first, see SetLRUK. It keeps
+	// entries read repeatedly over ones read once by a scan
+	PolicyLRUK
+)
+
+func (p EvictionPolicy) String() string {
+	switch p {
+	case PolicyLRU:
+		return "lru"
+	case PolicyMRU:
+		return "mru"
+	case PolicyRandom:
+		return "random"
+	case PolicyLRUK:
+		return "lru-k"
+	}
+	return "unknown"
+}
+
+// ParseEvictionPolicy returns the policy named s as printed by String, for
+// policies read from configuration
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	for _, p := range []EvictionPolicy{PolicyLRU, PolicyMRU, PolicyRandom, PolicyLRUK} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return PolicyLRU, fmt.Errorf("typedlru: unknown eviction policy %q", s)
+}
+
+// randomTries is how many slots PolicyRandom samples before falling back
+// to the oldest entry
+const randomTries = 16
+
+// SetEvictionPolicy changes which entry capacity eviction and RemoveOldest
+// take, PolicyLRU by default. Priorities and pins apply under every policy,
+// GetOldest and Keys still follow recency
+func (c *LRU[K, V]) SetEvictionPolicy(p EvictionPolicy) {
+	if c.k == 0 {
+		c.k = defaultK
+	}
+	was := c.policy
+	c.policy = p
+	if was == PolicyLRUK || p == PolicyLRUK {
+		c.rebuildHistory()
+	}
+}
+
+// candidateIn returns the entry matching in that the policy evicts first,
+// 0 if every such entry is pinned
+func (c *LRU[K, V]) candidateIn(in func(e *entry[K, V]) bool) int32 {
+	switch c.policy {
+	case PolicyLRUK:
+		return c.kcandidate(in)
+	case PolicyMRU:
+		for item := c.evictList.front(); item != 0; item = c.evictList.next(item) {
+			if e := c.evictList.at(item); item != c.setting && !e.pinned && in(e) {
+				return item
+			}
+		}
+		if e := c.evictList.at(c.setting); c.setting != 0 && !e.pinned && in(e) {
+			return c.setting
+		}
+		return 0
+	case PolicyRandom:
+		if n := int32(len(c.evictList.nodes)); n > 1 {
+			for range randomTries {
+				// the entry being set is kept, as under PolicyMRU
+				item := 1 + rand.N(n-1)
+				if e := c.evictList.at(item); item != c.setting && c.evictList.inUse(item) && !e.pinned && in(e) {
+					return item
+				}
+			}
+		}
+	}
+	return c.oldestIn(in)
+}
@@ -0,0 +1,69 @@
+package typedlru
+
+import (
+	"sort"
+)
+
+// defaultPriority is the priority of entries set without SetWithPriority
+const defaultPriority = 0
+
+// SetWithPriority adds an entry with the given priority. Capacity eviction
+// takes the oldest entry of the lowest priority present, so an entry is
+// only evicted once every entry of a lower priority is gone. Set keeps the
+// priority of the value it replaces, new keys get priority 0
+func (c *LRU[K, V]) SetWithPriority(k K, v V, priority int) {
+	c.set(&entry[K, V]{key: k, value: v, priority: priority, hasPriority: true})
+}
+
+// moveBand moves one entry from priority from to priority to in the band
+// counts, defaultPriority stands for no entry on either side
+func (c *LRU[K, V]) moveBand(from, to int) {
+	if from == to {
+		return
+	}
+	if from != defaultPriority {
+		if c.bands[from]--; c.bands[from] == 0 {
+			delete(c.bands, from)
+		}
+	}
+	if to != defaultPriority {
+		if c.bands == nil {
+			c.bands = make(map[int]int)
+		}
+		c.bands[to]++
+	}
+}
+
+// victim returns the entry capacity eviction takes next: the unpinned entry
+// of the lowest priority the eviction policy picks. It is 0 if every entry
+// is pinned
+func (c *LRU[K, V]) victim() int32 {
+	if len(c.bands) == 0 {
+		return c.candidateIn(func(*entry[K, V]) bool { return true })
+	}
+
+	// bands does not count the default priority
+	prios := make([]int, 0, len(c.bands)+1)
+	for p := range c.bands {
+		prios = append(prios, p)
+	}
+	prios = append(prios, defaultPriority)
+	sort.Ints(prios)
+
+	for _, p := range prios {
+		if item := c.candidateIn(func(e *entry[K, V]) bool { return e.priority == p }); item != 0 {
+			return item
+		}
+	}
+	return 0
+}
+
+// oldestIn returns the oldest unpinned entry matching in, 0 if none
+func (c *LRU[K, V]) oldestIn(in func(e *entry[K, V]) bool) int32 {
+	for item := c.evictList.back(); item != 0; item = c.evictList.prev(item) {
+		if e := c.evictList.at(item); !e.pinned && in(e) {
+			return item
+		}
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package typedlru
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Sizer is implemented by keys and values that know their memory footprint,
+// EstimateSize trusts it instead of walking the value
+type Sizer interface {
+	Size() int64
+}
+
+// SetMaxBytes bounds the cache by the approximate memory held by its keys
+// and values, as estimated by EstimateSize. It uses the cost limit, so it
+// replaces any cost set by SetMaxCost. It returns how many entries were
+// evicted
+func (c *LRU[K, V]) SetMaxBytes(maxBytes int64) int {
+	c.SetByteTracking(true)
+	evicted := c.SetMaxCost(maxBytes, entrySize[K, V])
+	c.costIsBytes = true
+	return evicted
+}
+
+// SetByteTracking makes Stats.Bytes report the approximate memory held by
+// the keys and values, as estimated by EstimateSize, kept up to date as
+// entries are set and removed. Entries already in the cache are estimated
+// now. It costs an estimate per Set, SetMaxBytes turns it on since it needs
+// the estimate anyway
+func (c *LRU[K, V]) SetByteTracking(on bool) {
+	if c.trackBytes == on {
+		return
+	}
+	c.trackBytes = on
+	c.totalBytes = 0
+	for _, item := range c.cache {
+		e := c.evictList.at(item)
+		e.bytes = 0
+		if on {
+			e.bytes = entrySize(e.key, e.value)
+			c.totalBytes += e.bytes
+		}
+	}
+}
+
+// entrySize estimates the memory of an entry of k and v
+func entrySize[K comparable, V any](k K, v V) int64 {
+	return EstimateSize(k) + EstimateSize(v) + entryOverhead
+}
+
+// entryOverhead approximates the bookkeeping of one entry: the entry, its
+// list element and its map slot
+const entryOverhead = int64(unsafe.Sizeof(entry[struct{}, struct{}]{})) + 48 + 16
+
+// EstimateSize approximates the bytes reachable from v. Pointers are followed
+// once, so shared data is counted once per call. Sizer values report
+// themselves. Maps are estimated from their element sizes and channels and
+// functions count as a pointer
+func EstimateSize(v interface{}) int64 {
+	if v == nil {
+		return 0
+	}
+	if s, ok := v.(Sizer); ok {
+		return s.Size()
+	}
+
+	seen := make(map[uintptr]bool)
+	rv := reflect.ValueOf(v)
+	return int64(rv.Type().Size()) + indirectSize(rv, seen)
+}
+
+// indirectSize returns the bytes v refers to outside of its own Type.Size
+func indirectSize(v reflect.Value, seen map[uintptr]bool) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		if v.CanInterface() {
+			if s, ok := v.Interface().(Sizer); ok {
+				return s.Size()
+			}
+		}
+		return int64(v.Elem().Type().Size()) + indirectSize(v.Elem(), seen)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		e := v.Elem()
+		return int64(e.Type().Size()) + indirectSize(e, seen)
+
+	case reflect.Slice:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		n := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			n += indirectSize(v.Index(i), seen)
+		}
+		return n
+
+	case reflect.Array:
+		var n int64
+		for i := 0; i < v.Len(); i++ {
+			n += indirectSize(v.Index(i), seen)
+		}
+		return n
+
+	case reflect.Struct:
+		var n int64
+		for i := 0; i < v.NumField(); i++ {
+			n += indirectSize(v.Field(i), seen)
+		}
+		return n
+
+	case reflect.Map:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		t := v.Type()
+		n := int64(v.Len()) * int64(t.Key().Size()+t.Elem().Size())
+		iter := v.MapRange()
+		for iter.Next() {
+			n += indirectSize(iter.Key(), seen) + indirectSize(iter.Value(), seen)
+		}
+		return n
+	}
+
+	return 0
+}
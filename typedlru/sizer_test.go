@@ -0,0 +1,82 @@
+package typedlru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jingke11235/lrucache/clock"
+)
+
+// sized reports itself as its own size in bytes
+type sized int64
+
+func (s sized) Size() int64 { return int64(s) }
+
+func TestStatsBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		// op runs on a cache of 3 entries with a ttl of a minute, holding
+		// 1: 100 and 2: 200
+		op func(c *LRU[sized, sized], clk *clock.Fake)
+		// want is the bytes of the keys and values left, without the
+		// overhead of the entries
+		want    int64
+		entries int
+	}{
+		{name: "insert", op: func(c *LRU[sized, sized], clk *clock.Fake) {}, want: 1 + 100 + 2 + 200, entries: 2},
+		{name: "another insert", op: func(c *LRU[sized, sized], clk *clock.Fake) { c.Set(3, 300) }, want: 1 + 100 + 2 + 200 + 3 + 300, entries: 3},
+		{name: "replace", op: func(c *LRU[sized, sized], clk *clock.Fake) { c.Set(1, 1000) }, want: 1 + 1000 + 2 + 200, entries: 2},
+		{name: "remove", op: func(c *LRU[sized, sized], clk *clock.Fake) { c.Remove(1) }, want: 2 + 200, entries: 1},
+		{name: "pop", op: func(c *LRU[sized, sized], clk *clock.Fake) { c.Pop(2) }, want: 1 + 100, entries: 1},
+		{name: "evict", op: func(c *LRU[sized, sized], clk *clock.Fake) {
+			c.Set(3, 300)
+			c.Set(4, 400)
+		}, want: 2 + 200 + 3 + 300 + 4 + 400, entries: 3},
+		{name: "expire on read", op: func(c *LRU[sized, sized], clk *clock.Fake) {
+			clk.Advance(2 * time.Minute)
+			c.GetDetailed(1)
+		}, want: 2 + 200, entries: 1},
+		{name: "expire in a sweep", op: func(c *LRU[sized, sized], clk *clock.Fake) {
+			clk.Advance(2 * time.Minute)
+			c.EvictExpired()
+		}, want: 0, entries: 0},
+		{name: "purge", op: func(c *LRU[sized, sized], clk *clock.Fake) { c.Purge() }, want: 0, entries: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			c, err := NewLRU[sized, sized](3, time.Minute, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetClock(clk)
+			c.SetByteTracking(true)
+			c.Set(1, 100)
+			c.Set(2, 200)
+
+			tt.op(c, clk)
+			if got, want := c.Stats().Bytes, tt.want+int64(tt.entries)*entryOverhead; got != want {
+				t.Errorf("Stats().Bytes = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestByteTrackingToggle(t *testing.T) {
+	c, _ := NewLRU[sized, sized](10, NoLimitTTL, nil)
+	c.Set(1, 100)
+	if b := c.Stats().Bytes; b != 0 {
+		t.Errorf("Stats().Bytes = %d without byte tracking", b)
+	}
+
+	// the entries already cached are counted once tracking is on
+	c.SetByteTracking(true)
+	if got, want := c.Stats().Bytes, 1+100+entryOverhead; got != want {
+		t.Errorf("Stats().Bytes = %d after SetByteTracking, want %d", got, want)
+	}
+	c.SetByteTracking(false)
+	if b := c.Stats().Bytes; b != 0 {
+		t.Errorf("Stats().Bytes = %d after byte tracking was turned off", b)
+	}
+}
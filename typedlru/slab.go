@@ -0,0 +1,113 @@
+package typedlru
+
+// entryList is the recency list of a LRU or HashLRU, holding entries of type
+// T. Entries live in one slice and link to each other by index, so the list
+// holds no per entry allocation and few pointers for the GC to trace. Slots
+// are allocated as the cache grows and recycled once it is full, so a full
+// cache sets and gets without allocating. Slot 0 is the root, its next is
+// the front and its prev the back of the list, index 0 also stands for no
+// entry
+type entryList[T any] struct {
+	nodes []node[T]
+
+	// free heads the slots of removed entries, linked by next
+	free int32
+	len  int
+}
+
+type node[T any] struct {
+	e          T
+	prev, next int32
+}
+
+// init empties the list, keeping the slice to reuse its slots
+func (l *entryList[T]) init() {
+	clear(l.nodes)
+	l.nodes = append(l.nodes[:0], node[T]{})
+	l.free = 0
+	l.len = 0
+}
+
+// at returns the entry of slot i, valid until the next pushFront which may
+// move the slots
+func (l *entryList[T]) at(i int32) *T {
+	return &l.nodes[i].e
+}
+
+func (l *entryList[T]) front() int32 { return l.nodes[0].next }
+func (l *entryList[T]) back() int32  { return l.nodes[0].prev }
+
+// next and prev return 0 past the ends of the list
+func (l *entryList[T]) next(i int32) int32 { return l.nodes[i].next }
+func (l *entryList[T]) prev(i int32) int32 { return l.nodes[i].prev }
+
+func (l *entryList[T]) length() int { return l.len }
+
+// inUse reports whether slot i holds an entry rather than being free
+func (l *entryList[T]) inUse(i int32) bool {
+	return l.nodes[i].prev != 0 || l.nodes[0].next == i
+}
+
+// pushFront copies e into a free slot at the front and returns the slot
+func (l *entryList[T]) pushFront(e *T) int32 {
+	if len(l.nodes) == 0 {
+		l.init()
+	}
+
+	i := l.free
+	if i != 0 {
+		l.free = l.nodes[i].next
+	} else {
+		i = int32(len(l.nodes))
+		l.nodes = append(l.nodes, node[T]{})
+	}
+
+	l.nodes[i].e = *e
+	l.link(i)
+	l.len++
+	return i
+}
+
+// remove unlinks slot i and frees it, dropping the references of its entry
+func (l *entryList[T]) remove(i int32) {
+	l.unlink(i)
+	l.nodes[i] = node[T]{next: l.free}
+	l.free = i
+	l.len--
+}
+
+func (l *entryList[T]) moveToFront(i int32) {
+	if l.nodes[0].next == i {
+		return
+	}
+	l.unlink(i)
+	l.link(i)
+}
+
+// moveToBack makes slot i the oldest entry
+func (l *entryList[T]) moveToBack(i int32) {
+	if l.nodes[0].prev == i {
+		return
+	}
+	l.unlink(i)
+	last := l.nodes[0].prev
+	l.nodes[i].next = 0
+	l.nodes[i].prev = last
+	l.nodes[last].next = i
+	l.nodes[0].prev = i
+}
+
+// link puts slot i at the front
+func (l *entryList[T]) link(i int32) {
+	first := l.nodes[0].next
+	l.nodes[i].prev = 0
+	l.nodes[i].next = first
+	l.nodes[first].prev = i
+	l.nodes[0].next = i
+}
+
+func (l *entryList[T]) unlink(i int32) {
+	n := &l.nodes[i]
+	l.nodes[n.prev].next = n.next
+	l.nodes[n.next].prev = n.prev
+}
@@ -0,0 +1,139 @@
+package typedlru
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// snapshotVersion is bumped when the snapshot layout changes
+const snapshotVersion = 1
+
+// Encoder writes one snapshot record, *gob.Encoder and *json.Encoder fit it
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads one snapshot record, *gob.Decoder and *json.Decoder fit it
+type Decoder interface {
+	Decode(v any) error
+}
+
+// SnapshotCodec creates the encoder and decoder of a snapshot stream
+type SnapshotCodec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// GobCodec is the default snapshot codec. Keys and values stored behind
+// interfaces must have their concrete types registered with gob.Register
+var GobCodec SnapshotCodec = gobCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder { return gob.NewEncoder(w) }
+func (gobCodec) NewDecoder(r io.Reader) Decoder { return gob.NewDecoder(r) }
+
+type snapshotHeader struct {
+	Version int
+	Taken   time.Time
+	Len     int
+}
+
+// snapshotEntry is the stored form of an entry, TTL is what was left of it
+// when the snapshot was taken, NoLimitTTL if the entry does not expire
+type snapshotEntry[K comparable, V any] struct {
+	Key       K
+	Value     V
+	UpdatedAt time.Time
+	CreatedAt time.Time
+	TTL       time.Duration
+}
+
+// Snapshot writes the live entries to w with GobCodec, see SnapshotWith
+func (c *LRU[K, V]) Snapshot(w io.Writer) error {
+	return c.SnapshotWith(w, GobCodec)
+}
+
+// SnapshotWith writes the live entries to w from oldest to newest with
+// codec. Entry contexts and costs are not kept
+func (c *LRU[K, V]) SnapshotWith(w io.Writer, codec SnapshotCodec) error {
+	now := c.clock.Now()
+	records := make([]snapshotEntry[K, V], 0, len(c.cache))
+
+	for item := c.evictList.back(); item != 0; item = c.evictList.prev(item) {
+		e := c.evictList.at(item)
+		ttl := c.remainingTTL(e)
+		if _, expires := c.expiresAt(e); c.expired(e.key) || expires && ttl <= 0 {
+			continue
+		}
+		records = append(records, snapshotEntry[K, V]{
+			Key:       e.key,
+			Value:     e.value,
+			UpdatedAt: e.updatedAt,
+			CreatedAt: e.createdAt,
+			TTL:       ttl,
+		})
+	}
+
+	enc := codec.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion, Taken: now, Len: len(records)}); err != nil {
+		return err
+	}
+	for i := range records {
+		if err := enc.Encode(&records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore reads a snapshot written by Snapshot, see RestoreWith
+func (c *LRU[K, V]) Restore(r io.Reader) (int, error) {
+	return c.RestoreWith(r, GobCodec)
+}
+
+// RestoreWith adds the entries of a snapshot written with codec as the newest
+// entries of the cache, keeping their order and update time. Entries whose
+// ttl ran out since the snapshot was taken are skipped, the size and cost
+// limits apply as for Set. It returns how many entries were added, entries
+// read before an error stay in the cache
+func (c *LRU[K, V]) RestoreWith(r io.Reader, codec SnapshotCodec) (int, error) {
+	dec := codec.NewDecoder(r)
+
+	var h snapshotHeader
+	if err := dec.Decode(&h); err != nil {
+		return 0, err
+	}
+	if h.Version != snapshotVersion {
+		return 0, fmt.Errorf("typedlru: unsupported snapshot version %d", h.Version)
+	}
+
+	n := 0
+	for i := 0; i < h.Len; i++ {
+		var rec snapshotEntry[K, V]
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
+
+		e := &entry[K, V]{key: rec.Key, value: rec.Value, updatedAt: rec.UpdatedAt, createdAt: rec.CreatedAt}
+		if rec.TTL != NoLimitTTL {
+			// the entry keeps the expiry time it had when the snapshot
+			// was taken
+			expiresAt := h.Taken.Add(rec.TTL)
+			if !c.clock.Now().Before(expiresAt) {
+				continue
+			}
+			e.ttl = expiresAt.Sub(rec.UpdatedAt)
+		}
+
+		c.set(e)
+		n++
+	}
+	return n, nil
+}
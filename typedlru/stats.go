@@ -0,0 +1,114 @@
+package typedlru
+
+// Stats holds access counters of a cache
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+
+	// Sets counts accepted Set calls, inserts and updates alike
+	Sets uint64
+
+	// Evictions counts entries removed to respect the size limit
+	Evictions uint64
+
+	// Expirations counts expired entries removed from the cache
+	Expirations uint64
+
+	// GhostHits counts misses on keys recently evicted for capacity, which
+	// a larger cache would have hit, see SetGhostSize
+	GhostHits uint64
+
+	// NegativeHits counts lookups finding a cached not found, left out of
+	// Hits. It is kept by lrucache.Cache, see its SetNegative
+	NegativeHits uint64
+
+	// Len is the number of entries when the stats were taken
+	Len int
+
+	// Cost is the total cost of the entries when the stats were taken
+	Cost int64
+
+	// Bytes is the estimated memory of the entries when the stats were
+	// taken, 0 unless tracked, see SetByteTracking
+	Bytes int64
+}
+
+// HitRatio returns Hits over Hits plus Misses, 0 without any lookup
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Add returns the sum of s and o, used to aggregate several caches
+func (s Stats) Add(o Stats) Stats {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Sets += o.Sets
+	s.Evictions += o.Evictions
+	s.Expirations += o.Expirations
+	s.GhostHits += o.GhostHits
+	s.NegativeHits += o.NegativeHits
+	s.Len += o.Len
+	s.Cost += o.Cost
+	s.Bytes += o.Bytes
+	return s
+}
+
+// Sub returns the counters of s minus those of o, taken earlier from the
+// same cache, and the Len, Cost and Bytes of s. Counters below those of o mean the
+// stats were reset in between, s is then returned as is
+func (s Stats) Sub(o Stats) Stats {
+	if s.Hits < o.Hits || s.Misses < o.Misses || s.Sets < o.Sets ||
+		s.Evictions < o.Evictions || s.Expirations < o.Expirations ||
+		s.GhostHits < o.GhostHits || s.NegativeHits < o.NegativeHits {
+		return s
+	}
+	s.Hits -= o.Hits
+	s.Misses -= o.Misses
+	s.Sets -= o.Sets
+	s.Evictions -= o.Evictions
+	s.Expirations -= o.Expirations
+	s.GhostHits -= o.GhostHits
+	s.NegativeHits -= o.NegativeHits
+	return s
+}
+
+// Stats returns the counters of the cache
+func (c *LRU[K, V]) Stats() Stats {
+	st := c.stats
+	st.Len = c.Len()
+	st.Cost = c.totalCost
+	st.Bytes = c.totalBytes
+	return st
+}
+
+// ResetStats zeroes the counters of the cache, including the tag counters
+func (c *LRU[K, V]) ResetStats() {
+	c.stats = Stats{}
+	c.tagStats = nil
+	if c.ghost != nil {
+		clear(c.ghost.hits)
+	}
+}
+
+func (c *LRU[K, V]) recordAccess(k K, hit bool) {
+	if hit {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	c.noteAccess(k, hit)
+}
+
+// noteAccess does what a read does besides counting it
+func (c *LRU[K, V]) noteAccess(k K, hit bool) {
+	var v V
+	if hit {
+		c.emit(EventHit, k, v, 0)
+	} else {
+		c.ghostMissed(k)
+		c.emit(EventMiss, k, v, 0)
+	}
+}
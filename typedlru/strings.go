@@ -0,0 +1,56 @@
+package typedlru
+
+import "time"
+
+// LRUString is a lru keyed by strings whose Bytes lookups take the key as a
+// byte slice, they do not allocate a string for it on hits
+type LRUString[V any] struct {
+	*LRU[string, V]
+}
+
+func NewLRUString[V any](size int, ttl time.Duration, onEvict EvictCallback[string, V]) (*LRUString[V], error) {
+	lru, err := NewLRU[string, V](size, ttl, onEvict)
+	if err != nil {
+		return nil, err
+	}
+	return &LRUString[V]{LRU: lru}, nil
+}
+
+// key returns the key stored for k, so callers go on with a string that is
+// already allocated. The map index converts k without allocating
+func (c *LRUString[V]) key(k []byte) (string, bool) {
+	if item, ok := c.cache[string(k)]; ok {
+		return c.evictList.at(item).key, true
+	}
+	return "", false
+}
+
+// BytesGet works like Get for the key string(k)
+func (c *LRUString[V]) BytesGet(k []byte) (v V, ok bool) {
+	if key, ok := c.key(k); ok {
+		return c.Get(key)
+	}
+	// a miss goes through Get as well, the ghost list and the event hook
+	// need the key as a string
+	return c.Get(string(k))
+}
+
+// BytesPeek works like Peek for the key string(k)
+func (c *LRUString[V]) BytesPeek(k []byte) (v V, ok bool) {
+	if key, ok := c.key(k); ok {
+		return c.Peek(key)
+	}
+	return v, false
+}
+
+// BytesContains works like Contains for the key string(k)
+func (c *LRUString[V]) BytesContains(k []byte) bool {
+	key, ok := c.key(k)
+	return ok && c.Contains(key)
+}
+
+// BytesRemove works like Remove for the key string(k)
+func (c *LRUString[V]) BytesRemove(k []byte) bool {
+	key, ok := c.key(k)
+	return ok && c.Remove(key)
+}
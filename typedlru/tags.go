@@ -0,0 +1,55 @@
+package typedlru
+
+// SetWithTags adds an entry carrying tags, replacing the tags of the value
+// it replaces. Set keeps the tags of the value it replaces
+func (c *LRU[K, V]) SetWithTags(k K, v V, tags ...string) {
+	c.set(&entry[K, V]{key: k, value: v, tags: append([]string(nil), tags...), hasTags: true})
+}
+
+// InvalidateTag removes every entry carrying tag, expired or not, and calls
+// the eviction callback for each. It returns how many were removed
+func (c *LRU[K, V]) InvalidateTag(tag string) int {
+	keys := c.tagIndex[tag]
+	n := 0
+	for k := range keys {
+		if item, ok := c.cache[k]; ok {
+			c.removeElement(item, EvictReasonRemoved)
+			n++
+		}
+	}
+	return n
+}
+
+// Tags returns the tags of a live entry
+func (c *LRU[K, V]) Tags(k K) []string {
+	k = c.normalize(k)
+	item, ok := c.cache[k]
+	if !ok || c.expired(k) {
+		return nil
+	}
+	return append([]string(nil), c.evictList.at(item).tags...)
+}
+
+func (c *LRU[K, V]) tagAdd(e *entry[K, V]) {
+	for _, tag := range e.tags {
+		if c.tagIndex == nil {
+			c.tagIndex = make(map[string]map[K]struct{})
+		}
+		keys := c.tagIndex[tag]
+		if keys == nil {
+			keys = make(map[K]struct{})
+			c.tagIndex[tag] = keys
+		}
+		keys[e.key] = struct{}{}
+	}
+}
+
+func (c *LRU[K, V]) tagRemove(e *entry[K, V]) {
+	for _, tag := range e.tags {
+		keys := c.tagIndex[tag]
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.tagIndex, tag)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// Warm loads the keys missing from the cache with loader, concurrency loads
+// at a time, and adds them as the coldest entries as long as they fit
+// without evicting anything, see simplelru.LRU.SetIfRoom. Entries already
+// cached are neither reloaded nor displaced, and keys given first are kept
+// longest, so give them hottest first. Warm stops once the cache is full or
+// ctx is done, it returns how many keys were added and the failed loads
+// joined, or ctx.Err()
+func (c *Cache) Warm(ctx context.Context, keys []interface{}, loader Loader, concurrency int) (int, error) {
+	if c.closed.Load() {
+		return 0, ErrClosed
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  []error
+		added atomic.Int64
+		full  atomic.Bool
+	)
+	next := make(chan interface{})
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range next {
+				ok, err := c.warmKey(ctx, k, loader)
+				switch {
+				case ok:
+					added.Add(1)
+				case errors.Is(err, errWarmFull):
+					full.Store(true)
+					cancel()
+				case err != nil && ctx.Err() == nil:
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, k := range keys {
+		select {
+		case next <- k:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if err := parent.Err(); err != nil && !full.Load() {
+		return int(added.Load()), err
+	}
+	return int(added.Load()), errors.Join(errs...)
+}
+
+var errWarmFull = errors.New("lrucache: cache full")
+
+// warmKey loads and adds k unless it is cached, sharing the load with
+// concurrent misses on k. It returns errWarmFull when there is no room left
+func (c *Cache) warmKey(ctx context.Context, k interface{}, loader Loader) (bool, error) {
+	c.lock.RLock()
+	cached := c.lru.Contains(k)
+	room := c.lru.Cap() == simplelru.NoLimitSize || c.lru.Len() < c.lru.Cap()
+	c.lock.RUnlock()
+	if cached {
+		return false, nil
+	}
+	if !room {
+		return false, errWarmFull
+	}
+
+	// set by fn, which concurrent Gets of k may share
+	added, full := false, false
+	_, err, _ := c.loads.doContext(ctx, k, func() (interface{}, error) {
+		if v, ok := c.Peek(k); ok {
+			return v, nil
+		}
+		v, err := loader.Load(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		c.lock.Lock()
+		if added = c.lru.SetIfRoom(k, v); added {
+			c.lru.MarkClean(k, 0)
+			c.chargeBudget()
+		}
+		full = !added && !c.lru.Contains(k)
+		c.lock.Unlock()
+		return v, nil
+	})
+	if full {
+		return false, errWarmFull
+	}
+	return added, err
+}
@@ -0,0 +1,113 @@
+package lrucache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jingke11235/lrucache/simplelru"
+)
+
+// WithStatsWindow keeps the stats of the last buckets intervals of bucket,
+// for WindowStats to tell the recent hit ratio. A goroutine records them
+// every bucket and is stopped by Close
+func WithStatsWindow(bucket time.Duration, buckets int) Option {
+	return func(c *Cache) {
+		if bucket <= 0 || buckets <= 0 {
+			return
+		}
+		c.window = &statsWindow{
+			bucket: bucket,
+			ring:   make([]statsSnapshot, 0, buckets+1),
+		}
+	}
+}
+
+type statsWindow struct {
+	bucket time.Duration
+
+	mu sync.Mutex
+	// ring holds the stats at the end of each bucket, next is where the
+	// next one goes once it is full
+	ring []statsSnapshot
+	next int
+}
+
+type statsSnapshot struct {
+	at time.Time
+	st simplelru.Stats
+}
+
+func (w *statsWindow) record(at time.Time, st simplelru.Stats) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.ring) < cap(w.ring) {
+		w.ring = append(w.ring, statsSnapshot{at: at, st: st})
+		return
+	}
+	w.ring[w.next] = statsSnapshot{at: at, st: st}
+	w.next = (w.next + 1) % len(w.ring)
+}
+
+// since returns the latest snapshot taken at or before t, or the oldest
+// one if none was
+func (w *statsWindow) since(t time.Time) (statsSnapshot, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.ring) == 0 {
+		return statsSnapshot{}, false
+	}
+	// from the newest snapshot back, the oldest one sits at next
+	best := w.ring[w.next%len(w.ring)]
+	for i := range w.ring {
+		s := w.ring[(w.next+len(w.ring)-1-i)%len(w.ring)]
+		if !s.at.After(t) {
+			return s, true
+		}
+		best = s
+	}
+	return best, true
+}
+
+func (c *Cache) startStatsWindow() {
+	w := c.window
+	if w == nil {
+		return
+	}
+
+	ticker := c.clock.NewTicker(w.bucket)
+	if c.stop == nil {
+		c.stop = make(chan struct{})
+	}
+	stop := c.stop
+	w.record(c.clock.Now(), c.Stats())
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				w.record(c.clock.Now(), c.Stats())
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// WindowStats returns the counters of the last d, see Stats.HitRatio for
+// the hit ratio over it, and the current Len and Cost. d is counted in
+// whole buckets of WithStatsWindow and capped to the buckets kept, without
+// WithStatsWindow the counters are those since the cache was created
+func (c *Cache) WindowStats(d time.Duration) simplelru.Stats {
+	st := c.Stats()
+	if c.window == nil {
+		return st
+	}
+	s, ok := c.window.since(c.clock.Now().Add(-d))
+	if !ok {
+		return st
+	}
+	return st.Sub(s.st)
+}